@@ -10,6 +10,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the first token of the node
 }
 
 type Statement interface {
@@ -30,33 +31,63 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 && p.Statements[0] != nil {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{Line: 1, Column: 1}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
+	writeStatements(&out, p.Statements)
+	return out.String()
+}
 
-	for _, s := range p.Statements {
+// writeStatements writes stmts so that they are parsed back as they are,
+// separating an expression statement from the next one by a semicolon.
+func writeStatements(out *bytes.Buffer, stmts []Statement) {
+	for i, s := range stmts {
+		if i > 0 {
+			out.WriteString(" ")
+		}
 		out.WriteString(s.String())
+		if _, ok := s.(*ExpressionStatement); ok && i < len(stmts)-1 {
+			out.WriteString(";")
+		}
 	}
-
-	return out.String()
 }
 
+// LetStatement is a statement like `let x = value;`, or `let [a, b] = value;`
+// which destructures the value by a pattern. Either Name or Pattern is nil.
+// A const statement like `const x = value;` is also a LetStatement.
 type LetStatement struct {
 	Statement
 
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // the let or const token
+	Const   bool        // whether the bindings cannot be redeclared
+	Name    *Identifier
+	Pattern Expression // an ArrayPattern or a HashPattern
+	Value   Expression
 }
 
 func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
 
+func (ls *LetStatement) Pos() token.Position {
+	return ls.Token.Pos
+}
+
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -72,12 +103,33 @@ type Identifier struct {
 
 	Token token.Token
 	Value string
+	Ref   Ref // filled in by the resolver of the evaluator
+}
+
+// RefKind tells how the variable referred to by an identifier is looked up.
+type RefKind int
+
+const (
+	RefDynamic RefKind = iota // by name through the environments; the identifier is not resolved
+	RefLocal                  // at Slot in the Depth-th outer environment
+	RefGlobal                 // in the top-level environment or the builtins
+)
+
+// Ref locates the variable referred to by an identifier at runtime.
+type Ref struct {
+	Kind  RefKind
+	Depth int
+	Slot  int
 }
 
 func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Identifier) Pos() token.Position {
+	return i.Token.Pos
+}
+
 func (i *Identifier) String() string {
 	return i.Value
 }
@@ -93,6 +145,10 @@ func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
 
+func (rs *ReturnStatement) Pos() token.Position {
+	return rs.Token.Pos
+}
+
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -116,6 +172,10 @@ func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
 
+func (es *ExpressionStatement) Pos() token.Position {
+	return es.Token.Pos
+}
+
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -134,6 +194,10 @@ func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
 
+func (il *IntegerLiteral) Pos() token.Position {
+	return il.Token.Pos
+}
+
 func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
@@ -149,8 +213,75 @@ func (sl *StringLiteral) TokenLiteral() string {
 	return sl.Token.Literal
 }
 
+func (sl *StringLiteral) Pos() token.Position {
+	return sl.Token.Pos
+}
+
 func (sl *StringLiteral) String() string {
-	return sl.Token.Literal
+	return Quote(sl.Value)
+}
+
+// Quote returns a string literal whose value is s.
+func Quote(s string) string {
+	return `"` + escape(s) + `"`
+}
+
+// escape escapes the chars in s which cannot appear as they are in a string
+// literal, including the dollar signs beginning interpolations.
+func escape(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '"' || ch == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(ch)
+		case ch == '\n':
+			out.WriteString(`\n`)
+		case ch == '\t':
+			out.WriteString(`\t`)
+		case ch == '\r':
+			out.WriteString(`\r`)
+		case ch == '$' && i+1 < len(s) && s[i+1] == '{':
+			out.WriteString(`\$`)
+		default:
+			out.WriteByte(ch)
+		}
+	}
+	return out.String()
+}
+
+// InterpolatedString is a string literal with embedded expressions, e.g.
+// "hello ${name}". Parts consist of StringLiterals for the texts and the
+// embedded expressions, in the order of appearance.
+type InterpolatedString struct {
+	Expression
+
+	Token token.Token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *InterpolatedString) Pos() token.Position {
+	return is.Token.Pos
+}
+
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+	out.WriteString(`"`)
+	for _, part := range is.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			out.WriteString(escape(text.Value))
+			continue
+		}
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+	out.WriteString(`"`)
+	return out.String()
 }
 
 type PrefixExpression struct {
@@ -165,6 +296,10 @@ func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
 
+func (pe *PrefixExpression) Pos() token.Position {
+	return pe.Token.Pos
+}
+
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -189,6 +324,10 @@ func (oe *InfixExpression) TokenLiteral() string {
 	return oe.Token.Literal
 }
 
+func (oe *InfixExpression) Pos() token.Position {
+	return oe.Token.Pos
+}
+
 func (oe *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -212,10 +351,32 @@ func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *Boolean) Pos() token.Position {
+	return b.Token.Pos
+}
+
 func (b *Boolean) String() string {
 	return b.Token.Literal
 }
 
+type Null struct {
+	Expression
+
+	Token token.Token
+}
+
+func (n *Null) TokenLiteral() string {
+	return n.Token.Literal
+}
+
+func (n *Null) Pos() token.Position {
+	return n.Token.Pos
+}
+
+func (n *Null) String() string {
+	return n.Token.Literal
+}
+
 type IfExpression struct {
 	Expression
 
@@ -229,16 +390,20 @@ func (ie *IfExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+func (ie *IfExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("if")
+	out.WriteString("if (")
 	out.WriteString(ie.Condition.String())
-	out.WriteString(" ")
+	out.WriteString(") ")
 	out.WriteString(ie.Consequence.String())
 
 	if ie.Alternative != nil {
-		out.WriteString("else ")
+		out.WriteString(" else ")
 		out.WriteString(ie.Alternative.String())
 	}
 
@@ -256,12 +421,20 @@ func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
 
+func (bs *BlockStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
 func (bs *BlockStatement) String() string {
+	if len(bs.Statements) == 0 {
+		return "{}"
+	}
+
 	var out bytes.Buffer
 
-	for _, s := range bs.Statements {
-		out.WriteString(s.String())
-	}
+	out.WriteString("{ ")
+	writeStatements(&out, bs.Statements)
+	out.WriteString(" }")
 
 	return out.String()
 }
@@ -270,23 +443,52 @@ type FunctionLiteral struct {
 	Expression
 
 	Token      token.Token
-	Parameters []*Identifier
+	Name       *Identifier   // nil if the function is anonymous
+	Parameters []*Identifier // an element is nil if the parameter is a pattern
+	Defaults   []Expression  // default values of Parameters; an element is nil if none
+	Patterns   []Expression  // destructuring patterns of Parameters; an element is nil if none
+	Rest       *Identifier   // parameter collecting the remaining arguments, if any
 	Body       *BlockStatement
+	Generator  bool   // whether Body contains yield expressions, not counting nested functions
+	Isolated   bool   // set by the resolver if the function refers to no local variables of the enclosing scopes, and so need not keep them
+	Binding    string // set by the resolver to the name bound to the function by a let or const statement, if any
+}
+
+// Param returns the i-th parameter, which is an identifier or a pattern.
+func (fl *FunctionLiteral) Param(i int) Expression {
+	if i < len(fl.Patterns) && fl.Patterns[i] != nil {
+		return fl.Patterns[i]
+	}
+	return fl.Parameters[i]
 }
 
 func (fl *FunctionLiteral) TokenLiteral() string {
 	return fl.Token.Literal
 }
 
+func (fl *FunctionLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
+
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	var params []string
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	for i := range fl.Parameters {
+		p := fl.Param(i).String()
+		if i < len(fl.Defaults) && fl.Defaults[i] != nil {
+			p += " = " + fl.Defaults[i].String()
+		}
+		params = append(params, p)
+	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.String())
 	}
 
 	out.WriteString(fl.TokenLiteral())
+	if fl.Name != nil {
+		out.WriteString(" " + fl.Name.String())
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
@@ -307,6 +509,10 @@ func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
 
+func (ce *CallExpression) Pos() token.Position {
+	return ce.Token.Pos
+}
+
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -334,6 +540,10 @@ func (al *ArrayLiteral) TokenLiteral() string {
 	return al.Token.Literal
 }
 
+func (al *ArrayLiteral) Pos() token.Position {
+	return al.Token.Pos
+}
+
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -361,6 +571,10 @@ func (ie *IndexExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+func (ie *IndexExpression) Pos() token.Position {
+	return ie.Token.Pos
+}
+
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -372,3 +586,375 @@ func (ie *IndexExpression) String() string {
 
 	return out.String()
 }
+
+// SpreadExpression expands an array into the surrounding argument list,
+// e.g. f(...args).
+type SpreadExpression struct {
+	Expression
+
+	Token token.Token
+	Value Expression
+}
+
+func (se *SpreadExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+func (se *SpreadExpression) Pos() token.Position {
+	return se.Token.Pos
+}
+
+func (se *SpreadExpression) String() string {
+	return "..." + se.Value.String()
+}
+
+type HashLiteral struct {
+	Expression
+
+	Token token.Token
+	Pairs []HashLiteralPair // in the order of appearance
+}
+
+// HashLiteralPair is a key-value pair in a hash literal.
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl *HashLiteral) TokenLiteral() string {
+	return hl.Token.Literal
+}
+
+func (hl *HashLiteral) Pos() token.Position {
+	return hl.Token.Pos
+}
+
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	var pairs []string
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// SliceExpression is an expression like a[low:high]. Low and High are nil
+// if omitted.
+type SliceExpression struct {
+	Expression
+
+	Token token.Token
+	Left  Expression
+	Low   Expression
+	High  Expression
+}
+
+func (se *SliceExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+func (se *SliceExpression) Pos() token.Position {
+	return se.Token.Pos
+}
+
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// MatchExpression is an expression like
+//
+//	match (subject) { pattern => value, pattern if guard => value }
+//
+// Its value is the value of the first arm whose pattern matches the subject
+// and whose guard, if any, is truthy.
+type MatchExpression struct {
+	Expression
+
+	Token   token.Token
+	Subject Expression
+	Arms    []MatchArm
+}
+
+// MatchArm is an arm of a match expression. Guard is nil if omitted.
+//
+// A pattern is one of an integer, string or boolean literal, a negated
+// integer literal, an identifier which binds the matched value (`_` matches
+// anything without binding), an ArrayPattern or a HashPattern.
+type MatchArm struct {
+	Pattern Expression
+	Guard   Expression
+	Value   Expression
+}
+
+func (me *MatchExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MatchExpression) Pos() token.Position {
+	return me.Token.Pos
+}
+
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	var arms []string
+	for _, arm := range me.Arms {
+		s := patternString(arm.Pattern)
+		if arm.Guard != nil {
+			s += " if " + arm.Guard.String()
+		}
+		arms = append(arms, s+" => "+arm.Value.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// patternString returns the string form of a pattern, in which a negative
+// integer is not parenthesized unlike the prefix expression.
+func patternString(pattern Expression) string {
+	if pe, ok := pattern.(*PrefixExpression); ok {
+		return pe.Operator + pe.Right.String()
+	}
+	return pattern.String()
+}
+
+// ArrayPattern is a pattern like [a, b, ...rest], which matches an array.
+// Rest is nil if omitted, and then the length of the array must be the
+// number of the elements.
+type ArrayPattern struct {
+	Expression
+
+	Token    token.Token
+	Elements []Expression
+	Rest     *Identifier
+}
+
+func (ap *ArrayPattern) TokenLiteral() string {
+	return ap.Token.Literal
+}
+
+func (ap *ArrayPattern) Pos() token.Position {
+	return ap.Token.Pos
+}
+
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	var elems []string
+	for _, elem := range ap.Elements {
+		elems = append(elems, patternString(elem))
+	}
+	if ap.Rest != nil {
+		elems = append(elems, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elems, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// HashPattern is a pattern like {x, "y": [a, b], ...rest}, which matches a
+// hash having all the keys. A shorthand x is the same as "x": x. Rest, if
+// not nil, binds a hash of the other pairs.
+type HashPattern struct {
+	Expression
+
+	Token token.Token
+	Pairs []HashLiteralPair // the values are patterns
+	Rest  *Identifier
+}
+
+func (hp *HashPattern) TokenLiteral() string {
+	return hp.Token.Literal
+}
+
+func (hp *HashPattern) Pos() token.Position {
+	return hp.Token.Pos
+}
+
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+
+	var pairs []string
+	for _, pair := range hp.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+patternString(pair.Value))
+	}
+	if hp.Rest != nil {
+		pairs = append(pairs, "..."+hp.Rest.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// StructStatement declares a struct type like
+//
+//	struct Point { x, y, fn norm() { self.x * self.x + self.y * self.y } }
+//
+// and binds Name to its constructor. Methods are named function literals
+// in which `self` refers to the receiver.
+type StructStatement struct {
+	Statement
+
+	Token   token.Token
+	Name    *Identifier
+	Fields  []*Identifier
+	Methods []*FunctionLiteral
+}
+
+func (ss *StructStatement) TokenLiteral() string {
+	return ss.Token.Literal
+}
+
+func (ss *StructStatement) Pos() token.Position {
+	return ss.Token.Pos
+}
+
+func (ss *StructStatement) String() string {
+	var out bytes.Buffer
+
+	var members []string
+	for _, field := range ss.Fields {
+		members = append(members, field.String())
+	}
+	for _, method := range ss.Methods {
+		members = append(members, method.String())
+	}
+
+	out.WriteString(ss.TokenLiteral() + " ")
+	out.WriteString(ss.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(members, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// MemberExpression is an expression like p.x, which refers to a field or a
+// method of a struct.
+type MemberExpression struct {
+	Expression
+
+	Token  token.Token // the dot token
+	Object Expression
+	Member *Identifier
+}
+
+func (me *MemberExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MemberExpression) Pos() token.Position {
+	return me.Token.Pos
+}
+
+func (me *MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Member.String() + ")"
+}
+
+// AssignExpression is an expression like p.x = value, whose value is the
+// assigned value. Target is a MemberExpression.
+type AssignExpression struct {
+	Expression
+
+	Token  token.Token // the assign token
+	Target Expression
+	Value  Expression
+}
+
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+
+func (ae *AssignExpression) Pos() token.Position {
+	return ae.Token.Pos
+}
+
+func (ae *AssignExpression) String() string {
+	return "(" + ae.Target.String() + " = " + ae.Value.String() + ")"
+}
+
+// YieldExpression is an expression like `yield value` in a generator
+// function. It suspends the generator producing the value.
+type YieldExpression struct {
+	Expression
+
+	Token token.Token
+	Value Expression
+}
+
+func (ye *YieldExpression) TokenLiteral() string {
+	return ye.Token.Literal
+}
+
+func (ye *YieldExpression) Pos() token.Position {
+	return ye.Token.Pos
+}
+
+func (ye *YieldExpression) String() string {
+	return "(" + ye.TokenLiteral() + " " + ye.Value.String() + ")"
+}
+
+// ForStatement is a loop like `for (x in iterable) { body }`. Pattern is an
+// identifier, an ArrayPattern or a HashPattern which is bound to each value.
+type ForStatement struct {
+	Statement
+
+	Token    token.Token
+	Pattern  Expression
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+
+func (fs *ForStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
+
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(fs.TokenLiteral())
+	out.WriteString(" (")
+	out.WriteString(fs.Pattern.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
@@ -0,0 +1,201 @@
+package ast
+
+import "fmt"
+
+// Equal reports whether a and b are structurally equal. Tokens are not
+// compared; only the values derived from them, such as the value of an
+// integer literal or the operator of an infix expression, are.
+func Equal(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	switch a := a.(type) {
+	case *Program:
+		b, ok := b.(*Program)
+		return ok && equalStatements(a.Statements, b.Statements)
+	case *LetStatement:
+		b, ok := b.(*LetStatement)
+		return ok && a.Const == b.Const && equalIdentifier(a.Name, b.Name) && equalExpression(a.Pattern, b.Pattern) &&
+			equalExpression(a.Value, b.Value)
+	case *ReturnStatement:
+		b, ok := b.(*ReturnStatement)
+		return ok && equalExpression(a.ReturnValue, b.ReturnValue)
+	case *ExpressionStatement:
+		b, ok := b.(*ExpressionStatement)
+		return ok && equalExpression(a.Expression, b.Expression)
+	case *BlockStatement:
+		b, ok := b.(*BlockStatement)
+		return ok && equalBlock(a, b)
+	case *Identifier:
+		b, ok := b.(*Identifier)
+		return ok && equalIdentifier(a, b)
+	case *IntegerLiteral:
+		b, ok := b.(*IntegerLiteral)
+		return ok && a.Value == b.Value
+	case *StringLiteral:
+		b, ok := b.(*StringLiteral)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	case *PrefixExpression:
+		b, ok := b.(*PrefixExpression)
+		return ok && a.Operator == b.Operator && equalExpression(a.Right, b.Right)
+	case *InfixExpression:
+		b, ok := b.(*InfixExpression)
+		return ok && a.Operator == b.Operator &&
+			equalExpression(a.Left, b.Left) && equalExpression(a.Right, b.Right)
+	case *IfExpression:
+		b, ok := b.(*IfExpression)
+		return ok && equalExpression(a.Condition, b.Condition) &&
+			equalBlock(a.Consequence, b.Consequence) && equalBlock(a.Alternative, b.Alternative)
+	case *FunctionLiteral:
+		b, ok := b.(*FunctionLiteral)
+		if !ok || len(a.Parameters) != len(b.Parameters) || len(a.Defaults) != len(b.Defaults) {
+			return false
+		}
+		for i := range a.Parameters {
+			if !equalIdentifier(a.Parameters[i], b.Parameters[i]) {
+				return false
+			}
+		}
+		return equalExpressions(a.Defaults, b.Defaults) && equalExpressions(a.Patterns, b.Patterns) &&
+			equalIdentifier(a.Name, b.Name) && equalIdentifier(a.Rest, b.Rest) && equalBlock(a.Body, b.Body)
+	case *CallExpression:
+		b, ok := b.(*CallExpression)
+		return ok && equalExpression(a.Function, b.Function) && equalExpressions(a.Arguments, b.Arguments)
+	case *ArrayLiteral:
+		b, ok := b.(*ArrayLiteral)
+		return ok && equalExpressions(a.Elements, b.Elements)
+	case *HashLiteral:
+		b, ok := b.(*HashLiteral)
+		return ok && equalPairs(a.Pairs, b.Pairs)
+	case *IndexExpression:
+		b, ok := b.(*IndexExpression)
+		return ok && equalExpression(a.Left, b.Left) && equalExpression(a.Index, b.Index)
+	case *SliceExpression:
+		b, ok := b.(*SliceExpression)
+		return ok && equalExpression(a.Left, b.Left) &&
+			equalExpression(a.Low, b.Low) && equalExpression(a.High, b.High)
+	case *SpreadExpression:
+		b, ok := b.(*SpreadExpression)
+		return ok && equalExpression(a.Value, b.Value)
+	case *InterpolatedString:
+		b, ok := b.(*InterpolatedString)
+		return ok && equalExpressions(a.Parts, b.Parts)
+	case *StructStatement:
+		b, ok := b.(*StructStatement)
+		if !ok || len(a.Fields) != len(b.Fields) || len(a.Methods) != len(b.Methods) {
+			return false
+		}
+		for i := range a.Fields {
+			if !equalIdentifier(a.Fields[i], b.Fields[i]) {
+				return false
+			}
+		}
+		for i := range a.Methods {
+			if !Equal(a.Methods[i], b.Methods[i]) {
+				return false
+			}
+		}
+		return equalIdentifier(a.Name, b.Name)
+	case *MemberExpression:
+		b, ok := b.(*MemberExpression)
+		return ok && equalExpression(a.Object, b.Object) && equalIdentifier(a.Member, b.Member)
+	case *AssignExpression:
+		b, ok := b.(*AssignExpression)
+		return ok && equalExpression(a.Target, b.Target) && equalExpression(a.Value, b.Value)
+	case *YieldExpression:
+		b, ok := b.(*YieldExpression)
+		return ok && equalExpression(a.Value, b.Value)
+	case *ForStatement:
+		b, ok := b.(*ForStatement)
+		return ok && equalExpression(a.Pattern, b.Pattern) &&
+			equalExpression(a.Iterable, b.Iterable) && equalBlock(a.Body, b.Body)
+	case *MatchExpression:
+		b, ok := b.(*MatchExpression)
+		if !ok || len(a.Arms) != len(b.Arms) {
+			return false
+		}
+		for i := range a.Arms {
+			if !equalExpression(a.Arms[i].Pattern, b.Arms[i].Pattern) ||
+				!equalExpression(a.Arms[i].Guard, b.Arms[i].Guard) ||
+				!equalExpression(a.Arms[i].Value, b.Arms[i].Value) {
+				return false
+			}
+		}
+		return equalExpression(a.Subject, b.Subject)
+	case *ArrayPattern:
+		b, ok := b.(*ArrayPattern)
+		return ok && equalExpressions(a.Elements, b.Elements) && equalIdentifier(a.Rest, b.Rest)
+	case *HashPattern:
+		b, ok := b.(*HashPattern)
+		return ok && equalPairs(a.Pairs, b.Pairs) && equalIdentifier(a.Rest, b.Rest)
+	default:
+		panic(fmt.Sprintf("ast.Equal: unexpected node type %T", a))
+	}
+}
+
+func equalStatements(a, b []Statement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if (a[i] == nil) != (b[i] == nil) {
+			return false
+		}
+		if a[i] != nil && !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalExpressions(a, b []Expression) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalExpression(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalPairs(a, b []HashLiteralPair) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalExpression(a[i].Key, b[i].Key) || !equalExpression(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalExpression(a, b Expression) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return Equal(a, b)
+}
+
+func equalIdentifier(a, b *Identifier) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Value == b.Value
+}
+
+func equalBlock(a, b *BlockStatement) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return equalStatements(a.Statements, b.Statements)
+}
@@ -0,0 +1,159 @@
+package ast
+
+import "fmt"
+
+// ModifierFunc returns the node which replaces the given one.
+type ModifierFunc func(Node) Node
+
+// Modify rewrites an AST in depth-first order: the children of node are
+// modified first, then node itself is replaced with modifier(node). Nodes
+// are modified in place and the result of modifier(node) is returned.
+//
+// If modifier returns a node that cannot be placed where the original node
+// was, e.g. an expression in place of a statement, the original node is kept.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch n := node.(type) {
+	case *Program:
+		modifyStatements(n.Statements, modifier)
+	case *LetStatement:
+		n.Name = modifyIdentifier(n.Name, modifier)
+		n.Pattern = modifyExpression(n.Pattern, modifier)
+		n.Value = modifyExpression(n.Value, modifier)
+	case *ReturnStatement:
+		n.ReturnValue = modifyExpression(n.ReturnValue, modifier)
+	case *ExpressionStatement:
+		n.Expression = modifyExpression(n.Expression, modifier)
+	case *BlockStatement:
+		modifyStatements(n.Statements, modifier)
+	case *Identifier, *IntegerLiteral, *StringLiteral, *Boolean, *Null:
+		// nothing to do
+	case *PrefixExpression:
+		n.Right = modifyExpression(n.Right, modifier)
+	case *InfixExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Right = modifyExpression(n.Right, modifier)
+	case *IfExpression:
+		n.Condition = modifyExpression(n.Condition, modifier)
+		n.Consequence = modifyBlock(n.Consequence, modifier)
+		n.Alternative = modifyBlock(n.Alternative, modifier)
+	case *FunctionLiteral:
+		n.Name = modifyIdentifier(n.Name, modifier)
+		for i, param := range n.Parameters {
+			n.Parameters[i] = modifyIdentifier(param, modifier)
+		}
+		modifyExpressions(n.Defaults, modifier)
+		modifyExpressions(n.Patterns, modifier)
+		n.Rest = modifyIdentifier(n.Rest, modifier)
+		n.Body = modifyBlock(n.Body, modifier)
+	case *CallExpression:
+		n.Function = modifyExpression(n.Function, modifier)
+		modifyExpressions(n.Arguments, modifier)
+	case *ArrayLiteral:
+		modifyExpressions(n.Elements, modifier)
+	case *HashLiteral:
+		for i, pair := range n.Pairs {
+			n.Pairs[i].Key = modifyExpression(pair.Key, modifier)
+			n.Pairs[i].Value = modifyExpression(pair.Value, modifier)
+		}
+	case *IndexExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Index = modifyExpression(n.Index, modifier)
+	case *SliceExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Low = modifyExpression(n.Low, modifier)
+		n.High = modifyExpression(n.High, modifier)
+	case *SpreadExpression:
+		n.Value = modifyExpression(n.Value, modifier)
+	case *InterpolatedString:
+		modifyExpressions(n.Parts, modifier)
+	case *StructStatement:
+		n.Name = modifyIdentifier(n.Name, modifier)
+		for i, field := range n.Fields {
+			n.Fields[i] = modifyIdentifier(field, modifier)
+		}
+		for i, method := range n.Methods {
+			if modified, ok := Modify(method, modifier).(*FunctionLiteral); ok {
+				n.Methods[i] = modified
+			}
+		}
+	case *MemberExpression:
+		n.Object = modifyExpression(n.Object, modifier)
+		n.Member = modifyIdentifier(n.Member, modifier)
+	case *AssignExpression:
+		n.Target = modifyExpression(n.Target, modifier)
+		n.Value = modifyExpression(n.Value, modifier)
+	case *YieldExpression:
+		n.Value = modifyExpression(n.Value, modifier)
+	case *ForStatement:
+		n.Pattern = modifyExpression(n.Pattern, modifier)
+		n.Iterable = modifyExpression(n.Iterable, modifier)
+		n.Body = modifyBlock(n.Body, modifier)
+	case *MatchExpression:
+		n.Subject = modifyExpression(n.Subject, modifier)
+		for i, arm := range n.Arms {
+			n.Arms[i].Pattern = modifyExpression(arm.Pattern, modifier)
+			n.Arms[i].Guard = modifyExpression(arm.Guard, modifier)
+			n.Arms[i].Value = modifyExpression(arm.Value, modifier)
+		}
+	case *ArrayPattern:
+		modifyExpressions(n.Elements, modifier)
+		n.Rest = modifyIdentifier(n.Rest, modifier)
+	case *HashPattern:
+		for i, pair := range n.Pairs {
+			n.Pairs[i].Key = modifyExpression(pair.Key, modifier)
+			n.Pairs[i].Value = modifyExpression(pair.Value, modifier)
+		}
+		n.Rest = modifyIdentifier(n.Rest, modifier)
+	default:
+		panic(fmt.Sprintf("ast.Modify: unexpected node type %T", n))
+	}
+
+	return modifier(node)
+}
+
+func modifyStatements(stmts []Statement, modifier ModifierFunc) {
+	for i, stmt := range stmts {
+		if stmt == nil {
+			continue
+		}
+		if modified, ok := Modify(stmt, modifier).(Statement); ok {
+			stmts[i] = modified
+		}
+	}
+}
+
+func modifyExpressions(exprs []Expression, modifier ModifierFunc) {
+	for i, expr := range exprs {
+		exprs[i] = modifyExpression(expr, modifier)
+	}
+}
+
+func modifyExpression(expr Expression, modifier ModifierFunc) Expression {
+	if expr == nil {
+		return nil
+	}
+	if modified, ok := Modify(expr, modifier).(Expression); ok {
+		return modified
+	}
+	return expr
+}
+
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	if modified, ok := Modify(ident, modifier).(*Identifier); ok {
+		return modified
+	}
+	return ident
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	if modified, ok := Modify(block, modifier).(*BlockStatement); ok {
+		return modified
+	}
+	return block
+}
@@ -0,0 +1,172 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor
+// w for each of the non-nil children of node, followed by a call of
+// w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+	case *LetStatement:
+		walkIdentifier(v, n.Name)
+		walkExpression(v, n.Pattern)
+		walkExpression(v, n.Value)
+	case *ReturnStatement:
+		walkExpression(v, n.ReturnValue)
+	case *ExpressionStatement:
+		walkExpression(v, n.Expression)
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+	case *Identifier, *IntegerLiteral, *StringLiteral, *Boolean, *Null:
+		// nothing to do
+	case *PrefixExpression:
+		walkExpression(v, n.Right)
+	case *InfixExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Right)
+	case *IfExpression:
+		walkExpression(v, n.Condition)
+		walkBlock(v, n.Consequence)
+		walkBlock(v, n.Alternative)
+	case *FunctionLiteral:
+		walkIdentifier(v, n.Name)
+		for i, param := range n.Parameters {
+			walkIdentifier(v, param)
+			if i < len(n.Patterns) {
+				walkExpression(v, n.Patterns[i])
+			}
+			if i < len(n.Defaults) {
+				walkExpression(v, n.Defaults[i])
+			}
+		}
+		walkIdentifier(v, n.Rest)
+		walkBlock(v, n.Body)
+	case *CallExpression:
+		walkExpression(v, n.Function)
+		walkExpressions(v, n.Arguments)
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+	case *HashLiteral:
+		for _, pair := range n.Pairs {
+			walkExpression(v, pair.Key)
+			walkExpression(v, pair.Value)
+		}
+	case *IndexExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Index)
+	case *SliceExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Low)
+		walkExpression(v, n.High)
+	case *SpreadExpression:
+		walkExpression(v, n.Value)
+	case *InterpolatedString:
+		walkExpressions(v, n.Parts)
+	case *StructStatement:
+		walkIdentifier(v, n.Name)
+		for _, field := range n.Fields {
+			walkIdentifier(v, field)
+		}
+		for _, method := range n.Methods {
+			if method != nil {
+				Walk(v, method)
+			}
+		}
+	case *MemberExpression:
+		walkExpression(v, n.Object)
+		walkIdentifier(v, n.Member)
+	case *AssignExpression:
+		walkExpression(v, n.Target)
+		walkExpression(v, n.Value)
+	case *YieldExpression:
+		walkExpression(v, n.Value)
+	case *ForStatement:
+		walkExpression(v, n.Pattern)
+		walkExpression(v, n.Iterable)
+		walkBlock(v, n.Body)
+	case *MatchExpression:
+		walkExpression(v, n.Subject)
+		for _, arm := range n.Arms {
+			walkExpression(v, arm.Pattern)
+			walkExpression(v, arm.Guard)
+			walkExpression(v, arm.Value)
+		}
+	case *ArrayPattern:
+		walkExpressions(v, n.Elements)
+		walkIdentifier(v, n.Rest)
+	case *HashPattern:
+		for _, pair := range n.Pairs {
+			walkExpression(v, pair.Key)
+			walkExpression(v, pair.Value)
+		}
+		walkIdentifier(v, n.Rest)
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkStatements(v Visitor, stmts []Statement) {
+	for _, stmt := range stmts {
+		if stmt != nil {
+			Walk(v, stmt)
+		}
+	}
+}
+
+func walkExpressions(v Visitor, exprs []Expression) {
+	for _, expr := range exprs {
+		walkExpression(v, expr)
+	}
+}
+
+func walkExpression(v Visitor, expr Expression) {
+	if expr != nil {
+		Walk(v, expr)
+	}
+}
+
+func walkIdentifier(v Visitor, ident *Identifier) {
+	if ident != nil {
+		Walk(v, ident)
+	}
+}
+
+func walkBlock(v Visitor, block *BlockStatement) {
+	if block != nil {
+		Walk(v, block)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,312 @@
+package ast_test
+
+import (
+	"fmt"
+	goast "go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	monkeyparser "github.com/daichimukai/x/syakyo/monkey/parser"
+	"github.com/stretchr/testify/require"
+)
+
+// allNodesInput is a program which contains every type of nodes.
+// Update it when a new node type is added to the package.
+const allNodesInput = `
+let f = fn g(a, [d], b = 1, ...c) { return a; };
+let {e, ...h} = f;
+struct P { x, fn m() { self.x = 1 } }
+for (i in x) { yield i }
+f(...[1], {"k": 2}[true], x[1:2], -y + z, null, if (a) { 1 } else { 2 }, "s${a}");
+match (a) { [1, _, ...r] => r, {k, "l": -1, ...r} if k => r };
+`
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	program, err := monkeyparser.New(lexer.New(input)).ParseProgram()
+	require.NoError(t, err)
+	return program
+}
+
+// declaredNodeTypes returns the names of the node types declared in the package.
+func declaredNodeTypes(t *testing.T) []string {
+	t.Helper()
+
+	filenames, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+
+	var types []string
+	fset := token.NewFileSet()
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filename, nil, 0)
+		require.NoError(t, err)
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*goast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "TokenLiteral" {
+				continue
+			}
+			star := fn.Recv.List[0].Type.(*goast.StarExpr)
+			types = append(types, "*ast."+star.X.(*goast.Ident).Name)
+		}
+	}
+	return types
+}
+
+func nodeTypes(node ast.Node) map[string]bool {
+	types := map[string]bool{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			types[fmt.Sprintf("%T", n)] = true
+		}
+		return true
+	})
+	return types
+}
+
+func TestWalkCoversAllNodeTypes(t *testing.T) {
+	visited := nodeTypes(parse(t, allNodesInput))
+
+	declared := declaredNodeTypes(t)
+	require.NotEmpty(t, declared)
+	for _, typ := range declared {
+		require.Truef(t, visited[typ], "%s is not visited; update allNodesInput", typ)
+	}
+}
+
+func TestPosOfAllNodeTypes(t *testing.T) {
+	ast.Inspect(parse(t, allNodesInput), func(n ast.Node) bool {
+		if n != nil {
+			require.Truef(t, n.Pos().IsValid(), "%T has no position", n)
+		}
+		return true
+	})
+}
+
+type recorder struct {
+	events *[]string
+}
+
+func (r recorder) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		*r.events = append(*r.events, "end")
+	} else {
+		*r.events = append(*r.events, node.String())
+	}
+	return r
+}
+
+func TestWalkOrder(t *testing.T) {
+	var events []string
+	ast.Walk(recorder{events: &events}, parse(t, `-a + 1;`))
+
+	require.Equal(t, []string{
+		"((-a) + 1)", // program
+		"((-a) + 1)", // expression statement
+		"((-a) + 1)",
+		"(-a)",
+		"a",
+		"end",
+		"end",
+		"1",
+		"end",
+		"end",
+		"end",
+		"end",
+	}, events)
+}
+
+func TestInspectPrune(t *testing.T) {
+	var idents []string
+	ast.Inspect(parse(t, `let a = fn(x) { y }; b;`), func(n ast.Node) bool {
+		if _, ok := n.(*ast.FunctionLiteral); ok {
+			return false
+		}
+		if ident, ok := n.(*ast.Identifier); ok {
+			idents = append(idents, ident.Value)
+		}
+		return true
+	})
+
+	require.Equal(t, []string{"a", "b"}, idents)
+}
+
+func TestModify(t *testing.T) {
+	one := func() ast.Expression { return &ast.IntegerLiteral{Value: 1} }
+	two := func() ast.Expression { return &ast.IntegerLiteral{Value: 2} }
+	turnOneIntoTwo := func(node ast.Node) ast.Node {
+		integer, ok := node.(*ast.IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+		return two()
+	}
+
+	testcases := []struct {
+		input  ast.Node
+		expect ast.Node
+	}{
+		{one(), two()},
+		{
+			&ast.Program{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: one()}}},
+			&ast.Program{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: two()}}},
+		},
+		{
+			&ast.InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&ast.InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&ast.PrefixExpression{Operator: "-", Right: one()},
+			&ast.PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&ast.IndexExpression{Left: one(), Index: one()},
+			&ast.IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&ast.SliceExpression{Left: one(), Low: one()},
+			&ast.SliceExpression{Left: two(), Low: two()},
+		},
+		{
+			&ast.IfExpression{
+				Condition:   one(),
+				Consequence: &ast.BlockStatement{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: one()}}},
+				Alternative: &ast.BlockStatement{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: one()}}},
+			},
+			&ast.IfExpression{
+				Condition:   two(),
+				Consequence: &ast.BlockStatement{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: two()}}},
+				Alternative: &ast.BlockStatement{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{
+			&ast.ReturnStatement{ReturnValue: one()},
+			&ast.ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&ast.LetStatement{Name: &ast.Identifier{Value: "x"}, Value: one()},
+			&ast.LetStatement{Name: &ast.Identifier{Value: "x"}, Value: two()},
+		},
+		{
+			&ast.FunctionLiteral{
+				Parameters: []*ast.Identifier{{Value: "x"}},
+				Defaults:   []ast.Expression{one()},
+				Body:       &ast.BlockStatement{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: one()}}},
+			},
+			&ast.FunctionLiteral{
+				Parameters: []*ast.Identifier{{Value: "x"}},
+				Defaults:   []ast.Expression{two()},
+				Body:       &ast.BlockStatement{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{
+			&ast.CallExpression{Function: one(), Arguments: []ast.Expression{one(), &ast.SpreadExpression{Value: one()}}},
+			&ast.CallExpression{Function: two(), Arguments: []ast.Expression{two(), &ast.SpreadExpression{Value: two()}}},
+		},
+		{
+			&ast.ArrayLiteral{Elements: []ast.Expression{one(), one()}},
+			&ast.ArrayLiteral{Elements: []ast.Expression{two(), two()}},
+		},
+		{
+			&ast.HashLiteral{Pairs: []ast.HashLiteralPair{{Key: one(), Value: one()}}},
+			&ast.HashLiteral{Pairs: []ast.HashLiteralPair{{Key: two(), Value: two()}}},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.expect.String(), func(t *testing.T) {
+			modified := ast.Modify(tt.input, turnOneIntoTwo)
+			require.True(t, ast.Equal(tt.expect, modified))
+		})
+	}
+}
+
+func TestModifyCoversAllNodeTypes(t *testing.T) {
+	modified := map[string]bool{}
+	ast.Modify(parse(t, allNodesInput), func(node ast.Node) ast.Node {
+		modified[fmt.Sprintf("%T", node)] = true
+		return node
+	})
+
+	for _, typ := range declaredNodeTypes(t) {
+		require.Truef(t, modified[typ], "%s is not modified", typ)
+	}
+}
+
+func TestModifyKeepsIncompatibleReplacement(t *testing.T) {
+	program := parse(t, `let x = 1;`)
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if _, ok := node.(*ast.Identifier); ok {
+			return &ast.IntegerLiteral{Value: 0}
+		}
+		return node
+	})
+
+	require.Equal(t, "let x = 1;", program.String())
+}
+
+func TestEqual(t *testing.T) {
+	require.True(t, ast.Equal(parse(t, allNodesInput), parse(t, allNodesInput)))
+
+	testcases := []struct {
+		a, b string
+	}{
+		{`1`, `2`},
+		{`"a"`, `"b"`},
+		{`"a${b}"`, `"a${c}"`},
+		{`"a${b}"`, `"${b}"`},
+		{`true`, `false`},
+		{`a`, `b`},
+		{`-a`, `!a`},
+		{`a + b`, `a - b`},
+		{`a + b`, `a + c`},
+		{`let a = 1;`, `let b = 1;`},
+		{`let a = 1;`, `let [a] = 1;`},
+		{`let [a] = 1;`, `let {a} = 1;`},
+		{`fn(a) { a }`, `fn([a]) { a }`},
+		{`return 1;`, `return 2;`},
+		{`if (a) { 1 }`, `if (a) { 1 } else { 2 }`},
+		{`fn(a) { a }`, `fn(b) { a }`},
+		{`fn(a) { a }`, `fn f(a) { a }`},
+		{`fn(a = 1) { a }`, `fn(a = 2) { a }`},
+		{`fn(...a) { a }`, `fn(a) { a }`},
+		{`f(a)`, `f(a, b)`},
+		{`f(a)`, `f(...a)`},
+		{`[1, 2]`, `[1]`},
+		{`{1: 2}`, `{1: 3}`},
+		{`a[1]`, `a[2]`},
+		{`a[1:]`, `a[:1]`},
+		{`match (a) { 1 => 2 }`, `match (a) { 1 => 3 }`},
+		{`match (a) { x => 2 }`, `match (a) { x if x => 2 }`},
+		{`match (a) { [x] => 2 }`, `match (a) { [x, ...y] => 2 }`},
+		{`match (a) { {x} => 2 }`, `match (a) { {"x": y} => 2 }`},
+		{`struct P { x }`, `struct Q { x }`},
+		{`struct P { x }`, `struct P { x, y }`},
+		{`struct P { x, fn f() {} }`, `struct P { x, fn g() {} }`},
+		{`a.x`, `a.y`},
+		{`a.x = 1`, `a.x = 2`},
+		{`yield 1`, `yield 2`},
+		{`for (i in a) { i }`, `for (j in a) { i }`},
+		{`for (i in a) { i }`, `for (i in b) { i }`},
+		{`for (i in a) { i }`, `for ([i] in a) { i }`},
+		{`1; 2`, `1`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, b := parse(t, tt.a), parse(t, tt.b)
+			require.True(t, ast.Equal(a, a))
+			require.False(t, ast.Equal(a, b))
+			require.False(t, ast.Equal(b, a))
+		})
+	}
+}
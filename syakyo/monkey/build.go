@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/parser"
+	"github.com/daichimukai/x/syakyo/monkey/transpile"
+)
+
+// buildMain implements `monkey build [-o dir] file`. It writes a Go main
+// package running the program to main.go in the directory, which is then
+// compiled by `go build` in a module requiring this one.
+func buildMain(args []string) int {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	output := flags.String("o", "", "write the Go package to `dir` (default: the file name without the extension)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey build [-o dir] file")
+		return 2
+	}
+
+	filename := flags.Arg(0)
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	program, err := parser.New(lexer.New(string(src))).ParseProgram()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: parse error: %s\n", filename, err)
+		return 1
+	}
+	code, err := transpile.Program(program, filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", filename, err)
+		return 1
+	}
+
+	dir := *output
+	if dir == "" {
+		dir = strings.TrimSuffix(filename, filepath.Ext(filename))
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), code, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
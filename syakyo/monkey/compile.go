@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/mkc"
+	"github.com/daichimukai/x/syakyo/monkey/parser"
+)
+
+// compiledExt is the extension of the files written by `monkey compile`.
+const compiledExt = ".mkc"
+
+// compileMain implements `monkey compile [-o file] file`. It saves the
+// parsed program so that `monkey run` can skip parsing it.
+func compileMain(args []string) int {
+	flags := flag.NewFlagSet("compile", flag.ContinueOnError)
+	output := flags.String("o", "", "write the compiled program to `file` (default: the file name with the extension .mkc)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey compile [-o file] file")
+		return 2
+	}
+
+	filename := flags.Arg(0)
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	program, err := parseSource(filename, src)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	out := *output
+	if out == "" {
+		out = strings.TrimSuffix(filename, filepath.Ext(filename)) + compiledExt
+	}
+	source, err := relativePath(filepath.Dir(out), filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var buf bytes.Buffer
+	if err := mkc.Encode(&buf, program, source, src); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// relativePath returns the path of target relative to dir.
+func relativePath(dir, target string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	target, err = filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// loadProgram reads the program in filename. A compiled program is used
+// only if it is fresh; the source is parsed instead if it has been changed
+// since or the program was compiled by another version. The compiled
+// program is used as is if the source cannot be read.
+func loadProgram(filename string) (*ast.Program, error) {
+	if filepath.Ext(filename) != compiledExt {
+		return parseFile(filename)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	header, program, err := mkc.Decode(bytes.NewReader(data))
+	if header == nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	source := filepath.Join(filepath.Dir(filename), filepath.FromSlash(header.Source))
+	if src, srcErr := os.ReadFile(source); srcErr == nil {
+		if err != nil || !header.Fresh(src) {
+			return parseSource(source, src)
+		}
+		return program, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return program, nil
+}
+
+func parseFile(filename string) (*ast.Program, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseSource(filename, src)
+}
+
+func parseSource(filename string, src []byte) (*ast.Program, error) {
+	program, err := parser.New(lexer.New(string(src))).ParseProgram()
+	if err != nil {
+		return nil, fmt.Errorf("%s: parse error: %w", filename, err)
+	}
+	return program, nil
+}
@@ -1,21 +1,478 @@
 package eval
 
-import "github.com/daichimukai/x/syakyo/monkey/object"
+import (
+	"sort"
+
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
 
 var builtins = map[string]*object.Builtin{
 	"len": {
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(_ object.ApplyFunc, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return object.NewError("wrong number of arguments: got=%d, want=1", len(args))
 			}
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{
-					Value: int64(len(arg.Value)),
-				}
+				return object.NewInteger(int64(len(arg.Value)))
+			case *object.Array:
+				return object.NewInteger(int64(len(arg.Elements)))
+			case *object.Hash:
+				return object.NewInteger(int64(len(arg.Keys)))
 			default:
 				return object.NewError("argument to `len` not supported: got %s", arg.Type())
 			}
 		},
 	},
+	"map":     {Fn: builtinMap},
+	"filter":  {Fn: builtinFilter},
+	"reduce":  {Fn: builtinReduce},
+	"each":    {Fn: builtinEach},
+	"sort_by": {Fn: builtinSortBy},
+	"find":    {Fn: builtinFind},
+	"any":     {Fn: builtinAny},
+	"all":     {Fn: builtinAll},
+	"slice":   {Fn: builtinSlice},
+	"range":   {Fn: builtinRange},
+	"take":    {Fn: builtinTake},
+	"collect": {Fn: builtinCollect},
+
+	"spawn":   {Fn: builtinSpawn},
+	"join":    {Fn: builtinJoin},
+	"channel": {Fn: builtinChannel},
+	"send":    {Fn: builtinSend},
+	"recv":    {Fn: builtinRecv},
+	"close":   {Fn: builtinClose},
+	"select":  {Fn: builtinSelect},
+
+	"json_parse":     {Fn: builtinJSONParse},
+	"json_stringify": {Fn: builtinJSONStringify},
+
+	"repr":   {Fn: builtinRepr},
+	"pretty": {Fn: builtinPretty},
+
+	"regex":    {Fn: builtinRegex},
+	"match":    {Fn: builtinMatch},
+	"find_all": {Fn: builtinFindAll},
+	"replace":  {Fn: builtinReplace},
+	"split_re": {Fn: builtinSplitRe},
+
+	"assert":    {Fn: builtinAssert},
+	"assert_eq": {Fn: builtinAssertEq},
+}
+
+// forEach calls f for each element of coll with the arguments to be passed
+// to a callback: an array element or a value of an iterable is passed as
+// (elem) and a hash pair is passed as (key, value). The iteration stops
+// when f returns false.
+func forEach(name string, coll object.Object, f func(args []object.Object) bool) *object.Error {
+	switch coll := coll.(type) {
+	case *object.Array:
+		for _, elem := range coll.Elements {
+			if !f([]object.Object{elem}) {
+				break
+			}
+		}
+	case *object.Hash:
+		for _, pair := range coll.Ordered() {
+			if !f([]object.Object{pair.Key, pair.Value}) {
+				break
+			}
+		}
+	case object.Iterable:
+		it := coll.Iterate()
+		defer it.Close()
+		for {
+			val, ok := it.Next()
+			if !ok {
+				break
+			}
+			if err, ok := val.(*object.Error); ok {
+				return err
+			}
+			if !f([]object.Object{val}) {
+				break
+			}
+		}
+	default:
+		return object.NewError("argument to `%s` not supported: got %s", name, coll.Type())
+	}
+	return nil
+}
+
+// callbackResult returns the array element or the hash value corresponding to args.
+func callbackResult(args []object.Object) object.Object {
+	if len(args) == 1 {
+		return args[0]
+	}
+	return &object.Array{Elements: args}
+}
+
+func builtinMap(apply object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+	if iterable, ok := args[0].(object.Iterable); ok {
+		it := iterable.Iterate()
+		return &object.LazyIterator{
+			NextFunc: func() (object.Object, bool) {
+				val, ok := it.Next()
+				if !ok || isError(val) {
+					return val, ok
+				}
+				return apply(args[1], []object.Object{val}), true
+			},
+			CloseFunc: it.Close,
+		}
+	}
+
+	var elems []object.Object
+	hash := object.NewHash()
+	var result object.Object
+	err := forEach("map", args[0], func(cbArgs []object.Object) bool {
+		mapped := apply(args[1], cbArgs)
+		if isError(mapped) {
+			result = mapped
+			return false
+		}
+		if len(cbArgs) == 1 {
+			elems = append(elems, mapped)
+		} else {
+			hash.Set(cbArgs[0].(object.Hashable), mapped)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if result != nil {
+		return result
+	}
+
+	if args[0].Type() == object.HashObjectType {
+		return hash
+	}
+	return &object.Array{Elements: elems}
+}
+
+func builtinFilter(apply object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+	if iterable, ok := args[0].(object.Iterable); ok {
+		it := iterable.Iterate()
+		return &object.LazyIterator{
+			NextFunc: func() (object.Object, bool) {
+				for {
+					val, ok := it.Next()
+					if !ok || isError(val) {
+						return val, ok
+					}
+					keep := apply(args[1], []object.Object{val})
+					if isError(keep) {
+						return keep, true
+					}
+					if isTruthy(keep) {
+						return val, true
+					}
+				}
+			},
+			CloseFunc: it.Close,
+		}
+	}
+
+	var elems []object.Object
+	hash := object.NewHash()
+	var result object.Object
+	err := forEach("filter", args[0], func(cbArgs []object.Object) bool {
+		ok := apply(args[1], cbArgs)
+		if isError(ok) {
+			result = ok
+			return false
+		}
+		if !isTruthy(ok) {
+			return true
+		}
+		if len(cbArgs) == 1 {
+			elems = append(elems, cbArgs[0])
+		} else {
+			hash.Set(cbArgs[0].(object.Hashable), cbArgs[1])
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if result != nil {
+		return result
+	}
+
+	if args[0].Type() == object.HashObjectType {
+		return hash
+	}
+	return &object.Array{Elements: elems}
+}
+
+func builtinReduce(apply object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return object.NewError("wrong number of arguments: got=%d, want=3", len(args))
+	}
+
+	acc := args[2]
+	err := forEach("reduce", args[0], func(cbArgs []object.Object) bool {
+		acc = apply(args[1], append([]object.Object{acc}, cbArgs...))
+		return !isError(acc)
+	})
+	if err != nil {
+		return err
+	}
+
+	return acc
+}
+
+func builtinEach(apply object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+
+	var result object.Object = object.Null
+	err := forEach("each", args[0], func(cbArgs []object.Object) bool {
+		if ret := apply(args[1], cbArgs); isError(ret) {
+			result = ret
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	return result
+}
+
+func builtinSortBy(apply object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+
+	var elems, keys []object.Object
+	var result object.Object
+	err := forEach("sort_by", args[0], func(cbArgs []object.Object) bool {
+		key := apply(args[1], cbArgs)
+		if isError(key) {
+			result = key
+			return false
+		}
+		if key.Type() != object.IntegerObjectType && key.Type() != object.StringObjectType {
+			result = object.NewError("sort key must be INTEGER or STRING: got %s", key.Type())
+			return false
+		}
+		if len(keys) > 0 && keys[0].Type() != key.Type() {
+			result = object.NewError("sort keys must have the same type: %s and %s", keys[0].Type(), key.Type())
+			return false
+		}
+		elems = append(elems, callbackResult(cbArgs))
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if result != nil {
+		return result
+	}
+
+	indices := make([]int, len(elems))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		switch ki := keys[indices[i]].(type) {
+		case *object.Integer:
+			return ki.Value < keys[indices[j]].(*object.Integer).Value
+		case *object.String:
+			return ki.Value < keys[indices[j]].(*object.String).Value
+		}
+		return false
+	})
+
+	sorted := make([]object.Object, len(elems))
+	for i, index := range indices {
+		sorted[i] = elems[index]
+	}
+	return &object.Array{Elements: sorted}
+}
+
+func builtinFind(apply object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+
+	var result object.Object = object.Null
+	err := forEach("find", args[0], func(cbArgs []object.Object) bool {
+		ok := apply(args[1], cbArgs)
+		if isError(ok) {
+			result = ok
+			return false
+		}
+		if isTruthy(ok) {
+			result = callbackResult(cbArgs)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	return result
+}
+
+func builtinAny(apply object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+
+	var result object.Object = object.False
+	err := forEach("any", args[0], func(cbArgs []object.Object) bool {
+		ok := apply(args[1], cbArgs)
+		if isError(ok) {
+			result = ok
+			return false
+		}
+		if isTruthy(ok) {
+			result = object.True
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	return result
+}
+
+func builtinAll(apply object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+
+	var result object.Object = object.True
+	err := forEach("all", args[0], func(cbArgs []object.Object) bool {
+		ok := apply(args[1], cbArgs)
+		if isError(ok) {
+			result = ok
+			return false
+		}
+		if !isTruthy(ok) {
+			result = object.False
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	return result
+}
+
+func builtinSlice(_ object.ApplyFunc, args ...object.Object) object.Object {
+	switch len(args) {
+	case 2:
+		return sliceObject(args[0], args[1], object.Null)
+	case 3:
+		return sliceObject(args[0], args[1], args[2])
+	default:
+		return object.NewError("wrong number of arguments: got=%d, want=2..3", len(args))
+	}
+}
+
+// builtinRange returns a lazy range: range(stop), range(start, stop) or
+// range(start, stop, step).
+func builtinRange(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) < 1 || 3 < len(args) {
+		return object.NewError("wrong number of arguments: got=%d, want=1..3", len(args))
+	}
+
+	bounds := make([]int64, len(args))
+	for i, arg := range args {
+		integer, ok := arg.(*object.Integer)
+		if !ok {
+			return object.NewError("argument to `range` must be INTEGER, got %s", arg.Type())
+		}
+		bounds[i] = integer.Value
+	}
+
+	r := &object.Range{Step: 1}
+	switch len(bounds) {
+	case 1:
+		r.Stop = bounds[0]
+	case 2:
+		r.Start, r.Stop = bounds[0], bounds[1]
+	case 3:
+		r.Start, r.Stop, r.Step = bounds[0], bounds[1], bounds[2]
+	}
+	if r.Step == 0 {
+		return object.NewError("range step must not be zero")
+	}
+	return r
+}
+
+// builtinTake returns a lazy iterator over the first n values of an iterable.
+func builtinTake(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+	it, ok := object.Iterate(args[0])
+	if !ok {
+		return object.NewError("argument to `take` not supported: got %s", args[0].Type())
+	}
+	n, ok := args[1].(*object.Integer)
+	if !ok {
+		return object.NewError("second argument to `take` must be INTEGER, got %s", args[1].Type())
+	}
+
+	taken := int64(0)
+	return &object.LazyIterator{
+		NextFunc: func() (object.Object, bool) {
+			if taken >= n.Value {
+				return nil, false
+			}
+			taken++
+			return it.Next()
+		},
+		CloseFunc: it.Close,
+	}
+}
+
+// builtinCollect returns an array of the values of an iterable.
+func builtinCollect(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+	return collect("collect", args[0])
+}
+
+// collect returns an array of the values iterated by object.Iterate(obj),
+// or an error.
+func collect(name string, obj object.Object) object.Object {
+	it, ok := object.Iterate(obj)
+	if !ok {
+		return object.NewError("argument to `%s` not supported: got %s", name, obj.Type())
+	}
+	defer it.Close()
+
+	elems := []object.Object{}
+	for {
+		val, ok := it.Next()
+		if !ok {
+			return &object.Array{Elements: elems}
+		}
+		if isError(val) {
+			return val
+		}
+		elems = append(elems, val)
+	}
 }
@@ -0,0 +1,53 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// builtinAssert returns an error if the condition is not truthy:
+// assert(cond) or assert(cond, message).
+func builtinAssert(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) < 1 || 2 < len(args) {
+		return object.NewError("wrong number of arguments: got=%d, want=1..2", len(args))
+	}
+	if isTruthy(args[0]) {
+		return object.Null
+	}
+	return assertionError(args[1:], "")
+}
+
+// builtinAssertEq returns an error unless the two values are equal by
+// object.Equal: assert_eq(got, want) or assert_eq(got, want, message).
+func builtinAssertEq(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) < 2 || 3 < len(args) {
+		return object.NewError("wrong number of arguments: got=%d, want=2..3", len(args))
+	}
+	got, want := args[0], args[1]
+	if object.Equal(got, want) {
+		return object.Null
+	}
+	if got.Type() == want.Type() {
+		return assertionError(args[2:], "got %s, want %s", got.Inspect(), want.Inspect())
+	}
+	return assertionError(args[2:], "got %s (%s), want %s (%s)", got.Inspect(), got.Type(), want.Inspect(), want.Type())
+}
+
+// assertionError returns the error of a failed assertion, which is
+// described by format and args following the message given by the script,
+// if any.
+func assertionError(message []object.Object, format string, args ...interface{}) *object.Error {
+	prefix := "assertion failed"
+	if len(message) > 0 {
+		if s, ok := message[0].(*object.String); ok {
+			prefix += ": " + s.Value
+		} else {
+			prefix += ": " + message[0].Inspect()
+		}
+	}
+	if format == "" {
+		return object.NewError("%s", prefix)
+	}
+	return object.NewError("%s: %s", prefix, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,201 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// builtinJSONParse converts a JSON text to a Monkey value: objects become
+// hashes preserving the order of keys, arrays become arrays, and null
+// becomes null. Numbers must be integral and fit in an integer, e.g. 1e3 is
+// accepted but 1.5 is not.
+func builtinJSONParse(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+	text, ok := args[0].(*object.String)
+	if !ok {
+		return object.NewError("argument to `json_parse` must be STRING, got %s", args[0].Type())
+	}
+
+	dec := json.NewDecoder(strings.NewReader(text.Value))
+	dec.UseNumber()
+	value, err := decodeJSONValue(dec, "$")
+	if err != nil {
+		return object.NewError("json_parse: %s", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return object.NewError("json_parse: unexpected data after the top-level value")
+	}
+	return value
+}
+
+// decodeJSONValue decodes the next value from dec. path locates the value
+// in the text for errors, e.g. $["a"][0].
+func decodeJSONValue(dec *json.Decoder, path string) (object.Object, error) {
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '[':
+			elems := []object.Object{}
+			for dec.More() {
+				elem, err := decodeJSONValue(dec, fmt.Sprintf("%s[%d]", path, len(elems)))
+				if err != nil {
+					return nil, err
+				}
+				elems = append(elems, elem)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return &object.Array{Elements: elems}, nil
+		case '{':
+			hash := object.NewHash()
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeJSONValue(dec, fmt.Sprintf("%s[%q]", path, key))
+				if err != nil {
+					return nil, err
+				}
+				hash.Set(&object.String{Value: key.(string)}, value)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return hash, nil
+		}
+		return nil, fmt.Errorf("unexpected delimiter %s", tok)
+	case json.Number:
+		if i, err := strconv.ParseInt(tok.String(), 10, 64); err == nil {
+			return object.NewInteger(i), nil
+		}
+		// The number has a fraction or an exponent, or is out of range.
+		f, err := strconv.ParseFloat(tok.String(), 64)
+		if math.IsInf(f, 0) || math.Abs(f) >= math.MaxInt64 {
+			return nil, fmt.Errorf("number %s at %s is out of range", tok, path)
+		}
+		// A tiny number underflows to zero without an error.
+		mantissa, _, _ := strings.Cut(strings.ToLower(tok.String()), "e")
+		underflow := f == 0 && strings.ContainsAny(mantissa, "123456789")
+		if err != nil || underflow || f != math.Trunc(f) {
+			return nil, fmt.Errorf("unsupported number %s at %s: only integers are supported", tok, path)
+		}
+		return object.NewInteger(int64(f)), nil
+	case string:
+		return &object.String{Value: tok}, nil
+	case bool:
+		return object.BooleanFromNative(tok), nil
+	case nil:
+		return object.Null, nil
+	}
+	return nil, fmt.Errorf("unexpected token %v", tok)
+}
+
+// builtinJSONStringify converts a value to a JSON text. The optional second
+// argument is the indentation for pretty-printing, given as the number of
+// spaces up to maxJSONIndent or a string.
+func builtinJSONStringify(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=1..2", len(args))
+	}
+
+	var out bytes.Buffer
+	if err := encodeJSONValue(&out, args[0]); err != nil {
+		return object.NewError("json_stringify: %s", err)
+	}
+	if len(args) == 1 {
+		return &object.String{Value: out.String()}
+	}
+
+	var indent string
+	switch arg := args[1].(type) {
+	case *object.Integer:
+		if arg.Value < 0 || arg.Value > maxJSONIndent {
+			return object.NewError("indent of `json_stringify` must be 0..%d, got %d", maxJSONIndent, arg.Value)
+		}
+		indent = strings.Repeat(" ", int(arg.Value))
+	case *object.String:
+		indent = arg.Value
+	default:
+		return object.NewError("indent of `json_stringify` must be INTEGER or STRING, got %s", arg.Type())
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, out.Bytes(), "", indent); err != nil {
+		return object.NewError("json_stringify: %s", err)
+	}
+	return &object.String{Value: pretty.String()}
+}
+
+// maxJSONIndent is the maximum number of spaces of an indent.
+const maxJSONIndent = 10
+
+func encodeJSONValue(out *bytes.Buffer, obj object.Object) error {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		out.WriteString(strconv.FormatInt(obj.Value, 10))
+	case *object.String:
+		encodeJSONString(out, obj.Value)
+	case *object.Array:
+		out.WriteByte('[')
+		for i, elem := range obj.Elements {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := encodeJSONValue(out, elem); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	case *object.Hash:
+		out.WriteByte('{')
+		for i, pair := range obj.Ordered() {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return fmt.Errorf("hash key must be STRING, got %s", pair.Key.Type())
+			}
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			encodeJSONString(out, key.Value)
+			out.WriteByte(':')
+			if err := encodeJSONValue(out, pair.Value); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	default:
+		switch obj {
+		case object.True, object.False, object.Null:
+			out.WriteString(obj.Inspect())
+		default:
+			return fmt.Errorf("%s cannot be encoded", obj.Type())
+		}
+	}
+	return nil
+}
+
+func encodeJSONString(out *bytes.Buffer, s string) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	// Encode appends a newline.
+	out.Truncate(out.Len() - 1)
+}
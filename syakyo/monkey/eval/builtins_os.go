@@ -0,0 +1,208 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// osBuiltin is a builtin which interacts with the outside of the interpreter.
+type osBuiltin struct {
+	capability Capability // required to call the builtin; zero if none
+	fn         func(c *config, args ...object.Object) object.Object
+}
+
+var capabilityNames = map[Capability]string{
+	CapabilityFS:      "filesystem",
+	CapabilityEnv:     "environment",
+	CapabilityProcess: "process",
+	CapabilityTime:    "time",
+}
+
+var osBuiltins = map[string]osBuiltin{
+	"puts":       {fn: builtinPuts},
+	"print":      {fn: builtinPrint},
+	"read_file":  {capability: CapabilityFS, fn: builtinReadFile},
+	"write_file": {capability: CapabilityFS, fn: builtinWriteFile},
+	"getenv":     {capability: CapabilityEnv, fn: builtinGetenv},
+	"args":       {capability: CapabilityEnv, fn: builtinArgs},
+	"exit":       {capability: CapabilityProcess, fn: builtinExit},
+	"now":        {capability: CapabilityTime, fn: builtinNow},
+	"sleep":      {capability: CapabilityTime, fn: builtinSleep},
+}
+
+// BuiltinNames returns the sorted names of the builtin functions.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins)+len(osBuiltins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range osBuiltins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Builtins returns the builtins available to the programs evaluated in an
+// environment configured by opts. Programs compiled to Go call them too.
+func Builtins(opts ...Option) map[string]*object.Builtin {
+	return newBuiltins(newConfig(opts))
+}
+
+// newBuiltins returns the builtins available under c. The builtins whose
+// capabilities are not granted are replaced with ones returning an error.
+func newBuiltins(c *config) map[string]*object.Builtin {
+	m := make(map[string]*object.Builtin, len(builtins)+len(osBuiltins))
+	for name, builtin := range builtins {
+		m[name] = builtin
+	}
+
+	for name, builtin := range osBuiltins {
+		name, builtin := name, builtin
+		if builtin.capability != 0 && c.capabilities&builtin.capability == 0 {
+			m[name] = &object.Builtin{
+				Fn: func(_ object.ApplyFunc, args ...object.Object) object.Object {
+					return object.NewError("%s: %s access is disabled", name, capabilityNames[builtin.capability])
+				},
+			}
+			continue
+		}
+		m[name] = &object.Builtin{
+			Fn: func(_ object.ApplyFunc, args ...object.Object) object.Object {
+				return builtin.fn(c, args...)
+			},
+		}
+	}
+	return m
+}
+
+// displayString returns the string printed by print for args.
+func displayString(args []object.Object) string {
+	var strs []string
+	for _, arg := range args {
+		strs = append(strs, arg.Inspect())
+	}
+	return strings.Join(strs, " ")
+}
+
+func builtinPuts(c *config, args ...object.Object) object.Object {
+	c.stdoutMu.Lock()
+	defer c.stdoutMu.Unlock()
+	for _, arg := range args {
+		fmt.Fprintln(c.stdout, arg.Inspect())
+	}
+	return object.Null
+}
+
+func builtinPrint(c *config, args ...object.Object) object.Object {
+	c.stdoutMu.Lock()
+	defer c.stdoutMu.Unlock()
+	fmt.Fprint(c.stdout, displayString(args))
+	return object.Null
+}
+
+func builtinReadFile(c *config, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return object.NewError("argument to `read_file` must be STRING, got %s", args[0].Type())
+	}
+
+	content, err := os.ReadFile(path.Value)
+	if err != nil {
+		return object.NewError("read_file: %s", err)
+	}
+	return &object.String{Value: string(content)}
+}
+
+func builtinWriteFile(c *config, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return object.NewError("first argument to `write_file` must be STRING, got %s", args[0].Type())
+	}
+	content, ok := args[1].(*object.String)
+	if !ok {
+		return object.NewError("second argument to `write_file` must be STRING, got %s", args[1].Type())
+	}
+
+	if err := os.WriteFile(path.Value, []byte(content.Value), 0o644); err != nil {
+		return object.NewError("write_file: %s", err)
+	}
+	return object.Null
+}
+
+func builtinGetenv(c *config, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+	name, ok := args[0].(*object.String)
+	if !ok {
+		return object.NewError("argument to `getenv` must be STRING, got %s", args[0].Type())
+	}
+
+	value, ok := os.LookupEnv(name.Value)
+	if !ok {
+		return object.Null
+	}
+	return &object.String{Value: value}
+}
+
+func builtinArgs(c *config, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return object.NewError("wrong number of arguments: got=%d, want=0", len(args))
+	}
+
+	elems := make([]object.Object, 0, len(c.args))
+	for _, arg := range c.args {
+		elems = append(elems, &object.String{Value: arg})
+	}
+	return &object.Array{Elements: elems}
+}
+
+func builtinExit(c *config, args ...object.Object) object.Object {
+	code := int64(0)
+	switch len(args) {
+	case 0:
+	case 1:
+		i, ok := args[0].(*object.Integer)
+		if !ok {
+			return object.NewError("argument to `exit` must be INTEGER, got %s", args[0].Type())
+		}
+		code = i.Value
+	default:
+		return object.NewError("wrong number of arguments: got=%d, want=0..1", len(args))
+	}
+
+	c.exit(int(code))
+	return object.Null
+}
+
+func builtinNow(c *config, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return object.NewError("wrong number of arguments: got=%d, want=0", len(args))
+	}
+	return &object.Integer{Value: time.Now().UnixNano() / int64(time.Millisecond)}
+}
+
+func builtinSleep(c *config, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+	ms, ok := args[0].(*object.Integer)
+	if !ok {
+		return object.NewError("argument to `sleep` must be INTEGER, got %s", args[0].Type())
+	}
+
+	time.Sleep(time.Duration(ms.Value) * time.Millisecond)
+	return object.Null
+}
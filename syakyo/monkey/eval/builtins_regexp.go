@@ -0,0 +1,180 @@
+package eval
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// maxCachedRegexps is the maximum number of compiled patterns kept in regexpCache.
+const maxCachedRegexps = 256
+
+// regexpCache holds compiled patterns so that calling the builtins with
+// the same pattern string repeatedly does not compile it every time.
+var regexpCache = struct {
+	sync.Mutex
+	m map[string]*regexp.Regexp
+}{
+	m: map[string]*regexp.Regexp{},
+}
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	regexpCache.Lock()
+	defer regexpCache.Unlock()
+
+	if re, ok := regexpCache.m[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(regexpCache.m) >= maxCachedRegexps {
+		regexpCache.m = map[string]*regexp.Regexp{}
+	}
+	regexpCache.m[pattern] = re
+	return re, nil
+}
+
+// toRegexp returns the regular expression given as a regexp object or a
+// pattern string to the builtin name.
+func toRegexp(name string, obj object.Object) (*regexp.Regexp, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Regexp:
+		return obj.Value, nil
+	case *object.String:
+		re, err := compileRegexp(obj.Value)
+		if err != nil {
+			return nil, object.NewError("%s: %s", name, err)
+		}
+		return re, nil
+	default:
+		return nil, object.NewError("pattern of `%s` must be REGEXP or STRING, got %s", name, obj.Type())
+	}
+}
+
+func stringArray(strs []string) *object.Array {
+	elems := make([]object.Object, 0, len(strs))
+	for _, s := range strs {
+		elems = append(elems, &object.String{Value: s})
+	}
+	return &object.Array{Elements: elems}
+}
+
+func builtinRegex(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+	pattern, ok := args[0].(*object.String)
+	if !ok {
+		return object.NewError("argument to `regex` must be STRING, got %s", args[0].Type())
+	}
+
+	re, err := compileRegexp(pattern.Value)
+	if err != nil {
+		return object.NewError("regex: %s", err)
+	}
+	return &object.Regexp{Value: re}
+}
+
+// builtinMatch returns the leftmost match and its submatches as an array,
+// or null if there is no match.
+func builtinMatch(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+	re, errObj := toRegexp("match", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	s, ok := args[1].(*object.String)
+	if !ok {
+		return object.NewError("second argument to `match` must be STRING, got %s", args[1].Type())
+	}
+
+	m := re.FindStringSubmatch(s.Value)
+	if m == nil {
+		return object.Null
+	}
+	return stringArray(m)
+}
+
+func builtinFindAll(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+	re, errObj := toRegexp("find_all", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	s, ok := args[1].(*object.String)
+	if !ok {
+		return object.NewError("second argument to `find_all` must be STRING, got %s", args[1].Type())
+	}
+
+	return stringArray(re.FindAllString(s.Value, -1))
+}
+
+// builtinReplace replaces all the matches. The replacement is either a
+// template string, in which $1 denotes the first submatch and so on, or a
+// function which takes the match and returns its replacement.
+func builtinReplace(apply object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return object.NewError("wrong number of arguments: got=%d, want=3", len(args))
+	}
+	re, errObj := toRegexp("replace", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	s, ok := args[1].(*object.String)
+	if !ok {
+		return object.NewError("second argument to `replace` must be STRING, got %s", args[1].Type())
+	}
+
+	switch repl := args[2].(type) {
+	case *object.String:
+		return &object.String{Value: re.ReplaceAllString(s.Value, repl.Value)}
+	case *object.Function, *object.Builtin:
+		var result object.Object
+		replaced := re.ReplaceAllStringFunc(s.Value, func(match string) string {
+			if result != nil {
+				return match
+			}
+			ret := apply(repl, []object.Object{&object.String{Value: match}})
+			str, ok := ret.(*object.String)
+			if !ok {
+				if isError(ret) {
+					result = ret
+				} else {
+					result = object.NewError("replacement must be STRING, got %s", ret.Type())
+				}
+				return match
+			}
+			return str.Value
+		})
+		if result != nil {
+			return result
+		}
+		return &object.String{Value: replaced}
+	default:
+		return object.NewError("third argument to `replace` must be STRING or FUNCTION, got %s", repl.Type())
+	}
+}
+
+func builtinSplitRe(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+	re, errObj := toRegexp("split_re", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	s, ok := args[1].(*object.String)
+	if !ok {
+		return object.NewError("second argument to `split_re` must be STRING, got %s", args[1].Type())
+	}
+
+	return stringArray(re.Split(s.Value, -1))
+}
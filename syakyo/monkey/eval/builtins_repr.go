@@ -0,0 +1,30 @@
+package eval
+
+import "github.com/daichimukai/x/syakyo/monkey/object"
+
+// builtinRepr returns the source of an expression evaluating to a value
+// equal to the argument, e.g. `repr(["a", 1])` is `["a", 1]`.
+func builtinRepr(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+	return &object.String{Value: object.Repr(args[0])}
+}
+
+// builtinPretty returns repr of the argument with nested arrays, hashes and
+// struct instances written over multiple lines. The second argument is the
+// indentation per level, which defaults to two spaces.
+func builtinPretty(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=1..2", len(args))
+	}
+	indent := "  "
+	if len(args) == 2 {
+		s, ok := args[1].(*object.String)
+		if !ok {
+			return object.NewError("second argument to `pretty` must be STRING, got %s", args[1].Type())
+		}
+		indent = s.Value
+	}
+	return &object.String{Value: object.Pretty(args[0], indent)}
+}
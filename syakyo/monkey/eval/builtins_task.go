@@ -0,0 +1,165 @@
+package eval
+
+import (
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// builtinSpawn calls a function with the rest of the arguments on a new
+// task and returns the task.
+func builtinSpawn(apply object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1+", len(args))
+	}
+	switch args[0].(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return object.NewError("argument to `spawn` must be FUNCTION, got %s", args[0].Type())
+	}
+
+	fn, fnArgs := args[0], args[1:]
+	return object.Spawn(func() object.Object {
+		// A function ending with a statement results in nil.
+		if result := apply(fn, fnArgs); result != nil {
+			return result
+		}
+		return object.Null
+	})
+}
+
+// builtinJoin waits for all the tasks and returns the result of the task,
+// or an array of the results if more than one task is given. If some tasks
+// fail, the error of the first one in the arguments is returned regardless
+// of the order in which they failed.
+func builtinJoin(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1+", len(args))
+	}
+	tasks := make([]*object.Task, len(args))
+	for i, arg := range args {
+		task, ok := arg.(*object.Task)
+		if !ok {
+			return object.NewError("argument to `join` must be TASK, got %s", arg.Type())
+		}
+		tasks[i] = task
+	}
+
+	results := make([]object.Object, len(tasks))
+	for i, task := range tasks {
+		results[i] = task.Wait()
+	}
+	for _, result := range results {
+		if isError(result) {
+			return result
+		}
+	}
+	if len(results) == 1 {
+		return results[0]
+	}
+	return &object.Array{Elements: results}
+}
+
+// maxChannelCapacity is the maximum capacity of a channel, which is allocated
+// in advance.
+const maxChannelCapacity = 1 << 20
+
+// builtinChannel returns a new channel with the optional capacity.
+func builtinChannel(_ object.ApplyFunc, args ...object.Object) object.Object {
+	switch len(args) {
+	case 0:
+		return object.NewChannel(0)
+	case 1:
+		capacity, ok := args[0].(*object.Integer)
+		if !ok {
+			return object.NewError("argument to `channel` must be INTEGER, got %s", args[0].Type())
+		}
+		if capacity.Value < 0 {
+			return object.NewError("channel capacity must not be negative")
+		}
+		if capacity.Value > maxChannelCapacity {
+			return object.NewError("channel capacity must not exceed %d, got %d", maxChannelCapacity, capacity.Value)
+		}
+		return object.NewChannel(int(capacity.Value))
+	default:
+		return object.NewError("wrong number of arguments: got=%d, want=0..1", len(args))
+	}
+}
+
+func builtinSend(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return object.NewError("wrong number of arguments: got=%d, want=2", len(args))
+	}
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return object.NewError("argument to `send` must be CHANNEL, got %s", args[0].Type())
+	}
+	if err := ch.Send(args[1]); err != nil {
+		return err
+	}
+	return object.Null
+}
+
+// builtinRecv receives an object from a channel. It returns null if the
+// channel is closed.
+func builtinRecv(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return object.NewError("argument to `recv` must be CHANNEL, got %s", args[0].Type())
+	}
+	if val, ok := ch.Recv(); ok {
+		return val
+	}
+	return object.Null
+}
+
+func builtinClose(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1", len(args))
+	}
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return object.NewError("argument to `close` must be CHANNEL, got %s", args[0].Type())
+	}
+	if err := ch.Close(); err != nil {
+		return err
+	}
+	return object.Null
+}
+
+// builtinSelect waits for the first of the cases which can proceed. A case
+// is a channel to receive from or a [channel, value] array to send value to
+// the channel. It returns [i, value] where i is the index of the case
+// performed and value is the received object, or null if the channel is
+// closed or the case is a send.
+func builtinSelect(_ object.ApplyFunc, args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return object.NewError("wrong number of arguments: got=%d, want=1+", len(args))
+	}
+	cases := make([]object.SelectCase, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case *object.Channel:
+			cases[i] = object.SelectCase{Channel: arg}
+			continue
+		case *object.Array:
+			if len(arg.Elements) == 2 {
+				if ch, ok := arg.Elements[0].(*object.Channel); ok {
+					cases[i] = object.SelectCase{Channel: ch, Send: true, Value: arg.Elements[1]}
+					continue
+				}
+			}
+		}
+		return object.NewError("argument to `select` must be CHANNEL or [CHANNEL, value], got %s", arg.Inspect())
+	}
+
+	i, val, err := object.Select(cases)
+	if err != nil {
+		return err
+	}
+	if val == nil {
+		val = object.Null
+	}
+	return &object.Array{Elements: []object.Object{object.NewInteger(int64(i)), val}}
+}
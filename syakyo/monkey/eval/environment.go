@@ -0,0 +1,153 @@
+package eval
+
+import (
+	"sync"
+
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// Environment binds names to objects. It is safe for concurrent use by
+// tasks sharing it through closures.
+//
+// The variables are kept in the order of definition, which the resolver
+// predicts, so that a resolved identifier finds its variable by index. Only
+// the top-level environment, to which a program or a REPL session adds any
+// number of variables, also indexes them by name.
+type Environment struct {
+	mu     sync.RWMutex // guards vars, index, consts and yield
+	vars   []variable
+	index  map[string]int           // indices of vars by name; nil unless top-level
+	consts map[string]bool          // names of vars bound by const statements
+	yield  func(object.Object) bool // set if the environment runs a generator
+	frame  *frame                   // set if the environment runs a call recorded by a profile
+
+	outer *Environment
+	top   *Environment // the top-level environment, which holds the fields below
+
+	builtins   map[string]*object.Builtin
+	memProfile *MemProfile // nil unless profiling
+	profile    *Profile    // nil unless profiling
+	tracer     *tracer     // nil unless tracing
+	optimize   bool        // whether to optimize programs before evaluating them
+	stepLimit  int64       // the maximum number of the steps, or 0 if unlimited
+	steps      int64       // the number of the steps taken; accessed atomically
+
+	// byName indexes the variables of every environment by name, as they
+	// were kept in a map per environment before the resolver. It is set only
+	// by the benchmarks comparing the lookups by slot and by name.
+	byName bool
+}
+
+type variable struct {
+	name  string
+	value object.Object
+}
+
+// NewEnvironment returns a new top-level environment configured by opts.
+func NewEnvironment(opts ...Option) *Environment {
+	c := newConfig(opts)
+	e := &Environment{
+		index:      make(map[string]int),
+		builtins:   newBuiltins(c),
+		memProfile: c.memProfile,
+		profile:    c.profile,
+		optimize:   c.optimize,
+		stepLimit:  c.stepLimit,
+		byName:     c.byName,
+	}
+	if c.trace != nil {
+		e.tracer = &tracer{w: c.trace}
+	}
+	e.top = e
+	return e
+}
+
+func (e *Environment) NewEnclosedEnvironment() object.Environment {
+	env := &Environment{
+		outer: e,
+		top:   e.top,
+	}
+	if e.top.byName {
+		env.index = make(map[string]int)
+	}
+	return env
+}
+
+// Get returns the object bound to name in e or the outer environments.
+func (e *Environment) Get(name string) (object.Object, bool) {
+	for env := e; env != nil; env = env.outer {
+		if val, ok := env.lookup(name); ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+func (e *Environment) Set(name string, val object.Object) object.Object {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if i, ok := e.indexOf(name); ok {
+		e.vars[i].value = val
+		return val
+	}
+	if e.index != nil {
+		e.index[name] = len(e.vars)
+	}
+	e.vars = append(e.vars, variable{name: name, value: val})
+	return val
+}
+
+// lookup returns the object bound to name in e, not counting the outer
+// environments.
+func (e *Environment) lookup(name string) (object.Object, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if i, ok := e.indexOf(name); ok {
+		return e.vars[i].value, true
+	}
+	return nil, false
+}
+
+// slot returns the object in the i-th variable of e if the variable is
+// named name. It fails if the variable is not defined yet.
+func (e *Environment) slot(i int, name string) (object.Object, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if i < len(e.vars) && e.vars[i].name == name {
+		return e.vars[i].value, true
+	}
+	return nil, false
+}
+
+// indexOf returns the index of the variable named name. e.mu must be held.
+func (e *Environment) indexOf(name string) (int, bool) {
+	if e.index != nil {
+		i, ok := e.index[name]
+		return i, ok
+	}
+	for i := range e.vars {
+		if e.vars[i].name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// isConst reports whether name is bound by a const statement in e, not
+// counting the outer environments.
+func (e *Environment) isConst(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.consts[name]
+}
+
+func (e *Environment) setConst(names []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.consts == nil {
+		e.consts = map[string]bool{}
+	}
+	for _, name := range names {
+		e.consts[name] = true
+	}
+}
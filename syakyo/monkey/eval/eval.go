@@ -2,50 +2,68 @@
 package eval
 
 import (
+	"strings"
+	"sync/atomic"
+
 	"github.com/daichimukai/x/syakyo/monkey/ast"
 	"github.com/daichimukai/x/syakyo/monkey/object"
+	"github.com/daichimukai/x/syakyo/monkey/optimize"
 )
 
-type Environment struct {
-	store map[string]object.Object
-	outer *Environment
-}
-
-func NewEnvironment() *Environment {
-	return &Environment{
-		store: make(map[string]object.Object),
+func (e *Environment) Eval(node ast.Node) object.Object {
+	if err := e.step(); err != nil {
+		return err
 	}
-}
-
-func (e *Environment) NewEnclosedEnvironment() object.Environment {
-	env := NewEnvironment()
-	env.outer = e
-	return env
-}
-
-func (e *Environment) Get(name string) (object.Object, bool) {
-	val, ok := e.store[name]
-	if !ok && e.outer != nil {
-		return e.outer.Get(name)
+	if e.top.tracer != nil {
+		return e.top.tracer.eval(e, node)
 	}
-	return val, ok
+	return e.eval(node)
 }
 
-func (e *Environment) Set(name string, val object.Object) object.Object {
-	e.store[name] = val
-	return val
+// step counts a step of the evaluation, i.e. a node evaluated or a function
+// applied, and returns an error if the steps exceed the limit.
+func (e *Environment) step() object.Object {
+	if e.top.stepLimit > 0 && atomic.AddInt64(&e.top.steps, 1) > e.top.stepLimit {
+		return object.NewError("step limit exceeded")
+	}
+	return nil
 }
 
-func (e *Environment) Eval(node ast.Node) object.Object {
+func (e *Environment) eval(node ast.Node) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
+		if e.top == e {
+			if err := resolve(node, e); err != nil {
+				return err
+			}
+			if e.optimize {
+				// The undefined variables are reported above since the
+				// optimizer may remove the references to them.
+				optimize.Program(node)
+				resolve(node, e)
+			}
+		}
 		return e.evalProgram(node)
 	case *ast.ExpressionStatement:
 		return e.Eval(node.Expression)
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return object.NewInteger(node.Value)
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.InterpolatedString:
+		return e.evalInterpolatedString(node)
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node)
+	case *ast.YieldExpression:
+		return e.evalYieldExpression(node)
+	case *ast.ForStatement:
+		return e.evalForStatement(node)
+	case *ast.StructStatement:
+		return e.evalStructStatement(node)
+	case *ast.MemberExpression:
+		return e.evalMemberExpression(node)
+	case *ast.AssignExpression:
+		return e.evalAssignExpression(node)
 	case *ast.ArrayLiteral:
 		elems := e.evalExpressions(node.Elements)
 		if len(elems) == 1 && isError(elems[0]) {
@@ -54,6 +72,8 @@ func (e *Environment) Eval(node ast.Node) object.Object {
 		return &object.Array{
 			Elements: elems,
 		}
+	case *ast.HashLiteral:
+		return e.evalHashLiteral(node)
 	case *ast.IndexExpression:
 		left := e.Eval(node.Left)
 		if isError(left) {
@@ -64,8 +84,12 @@ func (e *Environment) Eval(node ast.Node) object.Object {
 			return index
 		}
 		return e.evalIndexExpression(left, index)
+	case *ast.SliceExpression:
+		return e.evalSliceExpression(node)
 	case *ast.Boolean:
 		return object.BooleanFromNative(node.Value)
+	case *ast.Null:
+		return object.Null
 	case *ast.PrefixExpression:
 		right := e.Eval(node.Right)
 		if isError(right) {
@@ -93,20 +117,11 @@ func (e *Environment) Eval(node ast.Node) object.Object {
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
-		val := e.Eval(node.Value)
-		if isError(val) {
-			return val
-		}
-		e.Set(node.Name.Value, val)
-		return nil
+		return e.evalLetStatement(node)
 	case *ast.Identifier:
 		return e.evalIdentifier(node)
 	case *ast.FunctionLiteral:
-		return &object.Function{
-			Parameters: node.Parameters,
-			Body:       node.Body,
-			Env:        e,
-		}
+		return e.evalFunctionLiteral(node)
 	case *ast.CallExpression:
 		function := e.Eval(node.Function)
 		if isError(function) {
@@ -116,7 +131,16 @@ func (e *Environment) Eval(node ast.Node) object.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return object.ApplyFunction(function, args)
+		result := e.applyFunction(function, args)
+		if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+			if _, ok := function.(*object.Builtin); ok {
+				// copied since a builtin may return a shared error
+				return &object.Error{Message: err.Message, Pos: node.Function.Pos()}
+			}
+		}
+		return result
+	case *ast.SpreadExpression:
+		return object.NewError("spread is not allowed here: %s", node.String())
 	default:
 		return nil
 	}
@@ -157,6 +181,24 @@ func (e *Environment) evalBlockStatement(stmts []ast.Statement) object.Object {
 	return result
 }
 
+// evalInterpolatedString concatenates the parts of str. A string value is
+// embedded as is and the other values are embedded as their Inspect().
+func (e *Environment) evalInterpolatedString(str *ast.InterpolatedString) object.Object {
+	var out strings.Builder
+	for _, part := range str.Parts {
+		value := e.Eval(part)
+		if isError(value) {
+			return value
+		}
+		if s, ok := value.(*object.String); ok {
+			out.WriteString(s.Value)
+		} else {
+			out.WriteString(value.Inspect())
+		}
+	}
+	return &object.String{Value: out.String()}
+}
+
 func (e *Environment) evalPrefixExpression(op string, right object.Object) object.Object {
 	switch op {
 	case "!":
@@ -182,21 +224,57 @@ func (e *Environment) evalMinusOperatorExpression(right object.Object) object.Ob
 	}
 
 	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return object.NewInteger(-value)
+}
+
+func (e *Environment) evalHashLiteral(node *ast.HashLiteral) object.Object {
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		key := e.Eval(pair.Key)
+		if isError(key) {
+			return key
+		}
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return object.NewError("unusable as hash key: %s", key.Type())
+		}
+
+		value := e.Eval(pair.Value)
+		if isError(value) {
+			return value
+		}
+		hash.Set(hashKey, value)
+	}
+
+	return hash
 }
 
 func (e *Environment) evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ArrayObjectType && index.Type() == object.IntegerObjectType:
 		return e.evalArrayIndexExpression(left, index)
+	case left.Type() == object.StringObjectType && index.Type() == object.IntegerObjectType:
+		return e.evalStringIndexExpression(left, index)
+	case left.Type() == object.HashObjectType:
+		return e.evalHashIndexExpression(left, index)
 	default:
 		return object.NewError("index operator not supported: %s", left.Type().String())
 	}
 }
 
+// normalizeIndex converts a negative index, which counts from the end, to
+// the corresponding non-negative one.
+func normalizeIndex(i int64, length int) int64 {
+	if i < 0 {
+		return i + int64(length)
+	}
+	return i
+}
+
 func (e *Environment) evalArrayIndexExpression(left, index object.Object) object.Object {
 	arrayObject := left.(*object.Array)
-	i := index.(*object.Integer).Value
+	i := normalizeIndex(index.(*object.Integer).Value, len(arrayObject.Elements))
 	max := int64(len(arrayObject.Elements) - 1)
 	if i < 0 || i > max {
 		return object.Null
@@ -205,12 +283,122 @@ func (e *Environment) evalArrayIndexExpression(left, index object.Object) object
 	return arrayObject.Elements[i]
 }
 
+// evalStringIndexExpression returns the index-th byte of a string as a
+// string. Strings are indexed and sliced by byte, as len counts them, so the
+// byte may be a part of a multibyte char.
+func (e *Environment) evalStringIndexExpression(left, index object.Object) object.Object {
+	stringObject := left.(*object.String)
+	i := normalizeIndex(index.(*object.Integer).Value, len(stringObject.Value))
+	max := int64(len(stringObject.Value) - 1)
+	if i < 0 || i > max {
+		return object.Null
+	}
+
+	return &object.String{Value: stringObject.Value[i : i+1]}
+}
+
+func (e *Environment) evalSliceExpression(node *ast.SliceExpression) object.Object {
+	left := e.Eval(node.Left)
+	if isError(left) {
+		return left
+	}
+
+	var low, high object.Object = object.Null, object.Null
+	if node.Low != nil {
+		low = e.Eval(node.Low)
+		if isError(low) {
+			return low
+		}
+	}
+	if node.High != nil {
+		high = e.Eval(node.High)
+		if isError(high) {
+			return high
+		}
+	}
+
+	return sliceObject(left, low, high)
+}
+
+// sliceObject returns left[low:high] where left is an array or a string,
+// whose bounds are byte offsets. Null bounds mean the start and the end
+// respectively. Bounds out of range are clamped, so the result is empty
+// rather than an error.
+func sliceObject(left, low, high object.Object) object.Object {
+	var length int
+	switch left := left.(type) {
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.String:
+		length = len(left.Value)
+	default:
+		return object.NewError("slice operator not supported: %s", left.Type())
+	}
+
+	bound := func(obj object.Object, def int64) (int64, *object.Error) {
+		if obj == object.Null {
+			return def, nil
+		}
+		i, ok := obj.(*object.Integer)
+		if !ok {
+			return 0, object.NewError("slice index must be INTEGER: got %s", obj.Type())
+		}
+		v := normalizeIndex(i.Value, length)
+		if v < 0 {
+			v = 0
+		}
+		if v > int64(length) {
+			v = int64(length)
+		}
+		return v, nil
+	}
+	l, err := bound(low, 0)
+	if err != nil {
+		return err
+	}
+	h, err := bound(high, int64(length))
+	if err != nil {
+		return err
+	}
+	if l > h {
+		l = h
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		elems := make([]object.Object, h-l)
+		copy(elems, left.Elements[l:h])
+		return &object.Array{Elements: elems}
+	default:
+		return &object.String{Value: left.(*object.String).Value[l:h]}
+	}
+}
+
+func (e *Environment) evalHashIndexExpression(left, index object.Object) object.Object {
+	hashObject := left.(*object.Hash)
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return object.NewError("unusable as hash key: %s", index.Type())
+	}
+
+	if value, ok := hashObject.Get(key); ok {
+		return value
+	}
+	return object.Null
+}
+
 func (e *Environment) evalInfixExpression(op string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.IntegerObjectType && right.Type() == object.IntegerObjectType:
 		return e.evalIntegerInfixExpression(op, left, right)
+	case op == "==":
+		return object.BooleanFromNative(object.Equal(left, right))
+	case op == "!=":
+		return object.BooleanFromNative(!object.Equal(left, right))
 	case left.Type() == object.StringObjectType && right.Type() == object.StringObjectType:
 		return e.evalStringInfixExpression(op, left, right)
+	case left.Type() == object.ArrayObjectType && right.Type() == object.ArrayObjectType:
+		return e.evalArrayInfixExpression(op, left, right)
 	case left.Type() != right.Type():
 		return object.NewError(
 			"type mismatch: %s %s %s",
@@ -236,6 +424,9 @@ func (e *Environment) evalIntegerInfixExpression(op string, left, right object.O
 	case "*":
 		value = lvalue * rvalue
 	case "/":
+		if rvalue == 0 {
+			return object.NewError("division by zero")
+		}
 		value = lvalue / rvalue
 	case "==":
 		return object.BooleanFromNative(lvalue == rvalue)
@@ -251,19 +442,75 @@ func (e *Environment) evalIntegerInfixExpression(op string, left, right object.O
 			left.Type().String(), op, right.Type().String(),
 		)
 	}
-	return &object.Integer{Value: value}
+	return object.NewInteger(value)
 }
 
 func (e *Environment) evalStringInfixExpression(op string, left, right object.Object) object.Object {
-	if op != "+" {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+	switch op {
+	case "+":
+		return &object.String{
+			Value: leftVal + rightVal,
+		}
+	case "<":
+		return object.BooleanFromNative(leftVal < rightVal)
+	case ">":
+		return object.BooleanFromNative(leftVal > rightVal)
+	default:
 		return object.NewError("unknown operator: %s %s %s", left.Type(), op, right.Type())
 	}
+}
 
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
-	return &object.String{
-		Value: leftVal + rightVal,
+// evalArrayInfixExpression compares arrays lexicographically.
+func (e *Environment) evalArrayInfixExpression(op string, left, right object.Object) object.Object {
+	if op != "<" && op != ">" {
+		return object.NewError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+	}
+	c, ok := object.Compare(left, right)
+	if !ok {
+		return object.NewError("incomparable elements: %s %s %s", left.Type(), op, right.Type())
+	}
+	if op == "<" {
+		return object.BooleanFromNative(c < 0)
+	}
+	return object.BooleanFromNative(c > 0)
+}
+
+func (e *Environment) evalLetStatement(node *ast.LetStatement) object.Object {
+	var names []string
+	if node.Pattern != nil {
+		ast.Inspect(node.Pattern, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Identifier); ok && ident.Value != "_" {
+				names = append(names, ident.Value)
+			}
+			return true
+		})
+	} else {
+		names = append(names, node.Name.Value)
+	}
+	for _, name := range names {
+		if e.isConst(name) {
+			return object.NewError("cannot reassign constant %s", name)
+		}
+	}
+
+	val := e.Eval(node.Value)
+	if isError(val) {
+		return val
+	}
+	if node.Pattern != nil {
+		if err := e.Destructure(node.Pattern, val); err != nil {
+			return err
+		}
+	} else {
+		e.Set(node.Name.Value, val)
 	}
+
+	if node.Const {
+		e.setConst(names)
+	}
+	return nil
 }
 
 func (e *Environment) evalIfExpression(ie *ast.IfExpression) object.Object {
@@ -272,31 +519,128 @@ func (e *Environment) evalIfExpression(ie *ast.IfExpression) object.Object {
 		return condition
 	}
 
+	// Blocks have their own scopes.
+	var result object.Object
 	if isTruthy(condition) {
-		return e.Eval(ie.Consequence)
+		result = e.NewEnclosedEnvironment().Eval(ie.Consequence)
 	} else if ie.Alternative != nil {
-		return e.Eval(ie.Alternative)
-	} else {
+		result = e.NewEnclosedEnvironment().Eval(ie.Alternative)
+	}
+	if result == nil {
+		// The block ends with a statement.
 		return object.Null
 	}
+	return result
 }
 
 func (e *Environment) evalIdentifier(node *ast.Identifier) object.Object {
-	if val, ok := e.Get(node.Value); ok {
-		return val
+	switch node.Ref.Kind {
+	case ast.RefLocal:
+		env := e
+		for i := 0; i < node.Ref.Depth && env != nil; i++ {
+			env = env.outer
+		}
+		if env != nil {
+			if val, ok := env.slot(node.Ref.Slot, node.Value); ok {
+				return val
+			}
+		}
+		// The variable is not defined yet, so an outer one is visible.
+		if val, ok := e.Get(node.Value); ok {
+			return val
+		}
+	case ast.RefGlobal:
+		if val, ok := e.top.lookup(node.Value); ok {
+			return val
+		}
+	default:
+		if val, ok := e.Get(node.Value); ok {
+			return val
+		}
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin, ok := e.top.builtins[node.Value]; ok {
 		return builtin
 	}
 
 	return object.NewError("identifier not found: %s", node.Value)
 }
 
+// applyFunction calls fn with args. Builtins can call back functions through it.
+func (e *Environment) applyFunction(fn object.Object, args []object.Object) object.Object {
+	if err := e.step(); err != nil {
+		return err
+	}
+	if builtin, ok := fn.(*object.Builtin); ok {
+		return builtin.Fn(e.applyFunction, args...)
+	}
+	f, ok := fn.(*object.Function)
+	if !ok || e.top.memProfile == nil && e.top.profile == nil {
+		return object.ApplyFunction(fn, args)
+	}
+	call := func() object.Object {
+		return object.ApplyFunction(fn, args)
+	}
+	if p := e.top.profile; p != nil {
+		call = func() object.Object {
+			return p.call(e, f, args)
+		}
+	}
+	if p := e.top.memProfile; p != nil {
+		return p.call(f, call)
+	}
+	return call()
+}
+
+func (e *Environment) evalFunctionLiteral(node *ast.FunctionLiteral) object.Object {
+	fn := &object.Function{
+		Parameters: node.Parameters,
+		Defaults:   node.Defaults,
+		Patterns:   node.Patterns,
+		Rest:       node.Rest,
+		Body:       node.Body,
+		Generator:  node.Generator,
+		Binding:    node.Binding,
+		Env:        e,
+	}
+	if node.Isolated {
+		// The function does not need the local variables around it.
+		// Otherwise it keeps all of them, not only the ones it uses.
+		fn.Env = e.top
+	}
+	if node.Name != nil {
+		// A named function can refer to itself from its body.
+		env := fn.Env.NewEnclosedEnvironment()
+		env.Set(node.Name.Value, fn)
+		fn.Name = node.Name.Value
+		fn.Env = env
+	}
+	return fn
+}
+
 func (e *Environment) evalExpressions(exprs []ast.Expression) []object.Object {
 	var result []object.Object
 
 	for _, expr := range exprs {
+		if spread, ok := expr.(*ast.SpreadExpression); ok {
+			evaluated := e.Eval(spread.Value)
+			if isError(evaluated) {
+				return []object.Object{evaluated}
+			}
+			if _, ok := evaluated.(object.Iterable); ok {
+				evaluated = collect("spread", evaluated)
+				if isError(evaluated) {
+					return []object.Object{evaluated}
+				}
+			}
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				return []object.Object{object.NewError("cannot spread %s", evaluated.Type())}
+			}
+			result = append(result, arr.Elements...)
+			continue
+		}
+
 		evaluated := e.Eval(expr)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
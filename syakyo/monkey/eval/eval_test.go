@@ -1,8 +1,17 @@
 package eval_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
+	"github.com/daichimukai/x/syakyo/monkey/ast"
 	"github.com/daichimukai/x/syakyo/monkey/eval"
 	"github.com/daichimukai/x/syakyo/monkey/lexer"
 	"github.com/daichimukai/x/syakyo/monkey/object"
@@ -205,8 +214,95 @@ func TestEvalArrayIndexExpressions(t *testing.T) {
 		},
 		{
 			input:  `[1,2,3][-1]`,
+			expect: 3,
+		},
+		{
+			input:  `[1,2,3][-3]`,
+			expect: 1,
+		},
+		{
+			input:  `[1,2,3][-4]`,
+			expect: nil,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			integer, ok := tt.expect.(int)
+			if ok {
+				testIntegerObject(t, int64(integer), evaluated)
+			} else {
+				testNullObject(t, evaluated)
+			}
+		})
+	}
+}
+
+func TestEvalHashLiteral(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(t, input)
+	hash, ok := evaluated.(*object.Hash)
+	require.True(t, ok)
+
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{object.True, 5},
+		{object.False, 6},
+	}
+	require.Len(t, hash.Keys, len(expected))
+	for i, tt := range expected {
+		require.Equal(t, tt.key.HashKey(), hash.Keys[i])
+		value, ok := hash.Get(tt.key)
+		require.True(t, ok)
+		testIntegerObject(t, tt.value, value)
+	}
+}
+
+func TestEvalHashIndexExpressions(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect any
+	}{
+		{
+			input:  `{"foo": 5}["foo"]`,
+			expect: 5,
+		},
+		{
+			input:  `{"foo": 5}["bar"]`,
 			expect: nil,
 		},
+		{
+			input:  `let key = "foo"; {"foo": 5}[key]`,
+			expect: 5,
+		},
+		{
+			input:  `{}["foo"]`,
+			expect: nil,
+		},
+		{
+			input:  `{5: 5}[5]`,
+			expect: 5,
+		},
+		{
+			input:  `{true: 5}[true]`,
+			expect: 5,
+		},
 	}
 
 	for _, tt := range testcases {
@@ -222,6 +318,106 @@ func TestEvalArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestEvalSliceAndStringIndexExpressions(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{
+			input:  `[1, 2, 3, 4][1:3]`,
+			expect: `[2, 3]`,
+		},
+		{
+			input:  `[1, 2, 3, 4][:2]`,
+			expect: `[1, 2]`,
+		},
+		{
+			input:  `[1, 2, 3, 4][2:]`,
+			expect: `[3, 4]`,
+		},
+		{
+			input:  `[1, 2, 3, 4][:]`,
+			expect: `[1, 2, 3, 4]`,
+		},
+		{
+			input:  `[1, 2, 3, 4][-2:]`,
+			expect: `[3, 4]`,
+		},
+		{
+			input:  `[1, 2, 3, 4][1:-1]`,
+			expect: `[2, 3]`,
+		},
+		{
+			input:  `[1, 2, 3, 4][3:1]`,
+			expect: `[]`,
+		},
+		{
+			input:  `[1, 2, 3, 4][-10:10]`,
+			expect: `[1, 2, 3, 4]`,
+		},
+		{
+			input:  `let n = 1; [1, 2, 3][n + 1:]`,
+			expect: `[3]`,
+		},
+		{
+			input:  `"hello"[1]`,
+			expect: `e`,
+		},
+		{
+			input:  `"hello"[-1]`,
+			expect: `o`,
+		},
+		{
+			input:  `"hello"[5]`,
+			expect: `null`,
+		},
+		{
+			input:  `"hello"[1:3]`,
+			expect: `el`,
+		},
+		{
+			input:  `"hello"[:-1]`,
+			expect: `hell`,
+		},
+		{
+			input:  `"hello"[10:]`,
+			expect: ``,
+		},
+		// strings are indexed by byte
+		{
+			input:  `"aé"[1:]`,
+			expect: `é`,
+		},
+		{
+			input:  `[len("é"), len("é"[0]), "é"[0] + "é"[1] == "é", "é"[0] == "é"[:1]]`,
+			expect: `[2, 1, true, true]`,
+		},
+		{
+			input:  `"é"[0] == "é"`,
+			expect: `false`,
+		},
+		{
+			input:  `slice([1, 2, 3], 1)`,
+			expect: `[2, 3]`,
+		},
+		{
+			input:  `slice([1, 2, 3], 0, -1)`,
+			expect: `[1, 2]`,
+		},
+		{
+			input:  `slice("hello", -3, 4)`,
+			expect: `ll`,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
 func TestEvalStringConcatenation(t *testing.T) {
 	input := `"Hello" + " " + "World!"`
 
@@ -231,6 +427,119 @@ func TestEvalStringConcatenation(t *testing.T) {
 	require.Equal(t, "Hello World!", str.Value)
 }
 
+func TestEvalInterpolatedString(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`let name = "monkey"; "hello ${name}"`, `hello monkey`},
+		{`let age = 3; "you are ${age + 1}"`, `you are 4`},
+		{`"${[1, "a"]} ${true} ${{"k": 1}}"`, `[1, "a"] true {"k": 1}`},
+		{`"${"nested ${1 + 1}"}"`, `nested 2`},
+		{`"\${1}"`, `${1}`},
+		{"`a\n${1}\\n`", "a\n${1}\\n"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			str, ok := evaluated.(*object.String)
+			require.Truef(t, ok, "got %s", evaluated.Inspect())
+			require.Equal(t, tt.expect, str.Value)
+		})
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`match (1) { 1 => "one", _ => "other" }`, `one`},
+		{`match (2) { 1 => "one", _ => "other" }`, `other`},
+		{`match (-1) { 1 => "one", -1 => "minus one" }`, `minus one`},
+		{`match ("a") { "a" => 1, _ => 2 }`, `1`},
+		{`match (true) { false => 1, true => 2 }`, `2`},
+		{`match (1) { "1" => 1, true => 2, _ => 3 }`, `3`},
+		{`match ("1") { 1 => 1, "1" => 2 }`, `2`},
+		{`let x = null; match (x) { null => 1, _ => 2 }`, `1`},
+		{`match (0) { null => 1, _ => 2 }`, `2`},
+		{`match ([1, null]) { [a, null] => a }`, `1`},
+		{`match (1 + 2) { n => n * 2 }`, `6`},
+		{`let n = 1; match (2) { n => n }; n`, `1`},
+		{`match ([1, 2, 3]) { [a, b] => 0, [a, b, c] => a + b + c }`, `6`},
+		{`match ([1, 2, 3]) { [a, ...rest] => rest }`, `[2, 3]`},
+		{`match ([1]) { [a, ...rest] => rest }`, `[]`},
+		{`match ([]) { [a, ...rest] => 1, [] => 2 }`, `2`},
+		{`match ([1, [2, 3]]) { [1, [_, x]] => x }`, `3`},
+		{`match ([1, 2]) { [2, x] => x, [1, x] => x * 10 }`, `20`},
+		{`match ("abc") { [x] => 1, _ => 2 }`, `2`},
+		{`match ({"x": 1, "y": 2}) { {x, y} => x + y }`, `3`},
+		{`match ({"x": 1}) { {x, y} => 0, {x} => x }`, `1`},
+		{`match ({"x": 1, "y": 2, 3: 4}) { {y: b, ...rest} => rest }`, `{"x": 1, 3: 4}`},
+		{`match ({"t": "circle", "r": 2}) { {"t": "square", s} => s * s, {"t": "circle", r} => 3 * r * r }`, `12`},
+		{`match ({1: [1, 2]}) { {1: [a, b]} => a + b }`, `3`},
+		{`match (5) { n if n < 3 => "small", n if n < 10 => "medium", _ => "large" }`, `medium`},
+		{`match ([1, 2]) { [a, b] if a > b => a, [a, b] => b }`, `2`},
+		{`let f = fn(x) { match (x) { 0 => 1, n => n * f(n - 1) } }; f(5)`, `120`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestComparisonOperators(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect bool
+	}{
+		// Booleans and null are equal to themselves only.
+		{`true == true`, true},
+		{`true != false`, true},
+		{`null == null`, true},
+		{`null == false`, false},
+		// Values of different types are never equal.
+		{`1 == "1"`, false},
+		{`1 != "1"`, true},
+		{`[] == {}`, false},
+		// Arrays, hashes and struct instances are compared structurally.
+		{`[1, "a", [true]] == [1, "a", [true]]`, true},
+		{`[1, 2] == [2, 1]`, false},
+		{`[1] != [1, 1]`, true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`struct P { x }; P([1]) == P([1])`, true},
+		// Functions, builtins and the other objects are compared by identity.
+		{`let f = fn() { 1 }; f == f`, true},
+		{`fn() { 1 } == fn() { 1 }`, false},
+		{`len == len`, true},
+		{`len != puts`, true},
+		// Strings are ordered bytewise, and arrays lexicographically.
+		{`"a" < "b"`, true},
+		{`"ab" > "b"`, false},
+		{`"a" < "ab"`, true},
+		{`"" < ""`, false},
+		{`[1, 2] < [1, 3]`, true},
+		{`[1, 2] > [1]`, true},
+		{`[] < [0]`, true},
+		{`["b"] > ["a", "z"]`, true},
+		{`[[1, 2], 0] < [[1, 3]]`, true},
+		{`[true, 1] < [true, 2]`, true},
+		{`[1, 2] < [1, 2]`, false},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			testBooleanObject(t, tt.expect, evaluated)
+		})
+	}
+}
+
 func TestEvalBangOperator(t *testing.T) {
 	testcases := []struct {
 		input  string
@@ -260,6 +569,10 @@ func TestEvalBangOperator(t *testing.T) {
 			input:  `!!5`,
 			expect: true,
 		},
+		{
+			input:  `!null`,
+			expect: true,
+		},
 	}
 
 	for _, tt := range testcases {
@@ -299,6 +612,14 @@ func TestIfExpression(t *testing.T) {
 			input:  `if (0) { 10 }`, // ¯\_(ツ)_/¯
 			expect: 10,
 		},
+		{
+			input:  `if (true) {} else { 10 }`,
+			expect: nil,
+		},
+		{
+			input:  `if (true) { let x = 10; }`,
+			expect: nil,
+		},
 	}
 
 	for _, tt := range testcases {
@@ -385,6 +706,171 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuring(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`let [a, b] = [1, 2]; a + b`, `3`},
+		{`let [a, b, ...rest] = [1, 2, 3, 4]; rest`, `[3, 4]`},
+		{`let [a, ...rest] = [1]; rest`, `[]`},
+		{`let [_, [b, c]] = [1, [2, 3]]; b * c`, `6`},
+		{`let {name, age} = {"name": "monkey", "age": 3}; name + ":" + "${age}"`, `monkey:3`},
+		{`let {"x": a, 1: [b]} = {1: [2], "x": 1}; a + b`, `3`},
+		{`let {x, ...rest} = {"x": 1, "y": 2}; rest`, `{"y": 2}`},
+		{`let divmod = fn(a, b) { [a / b, a - a / b * b] }; let [q, r] = divmod(7, 2); [q, r]`, `[3, 1]`},
+		{`let f = fn([a, b]) { a + b }; f([1, 2])`, `3`},
+		{`let f = fn(x, {y}) { x + y }; f(1, {"y": 2})`, `3`},
+		{`let f = fn([a, b] = [3, 4]) { a * b }; f()`, `12`},
+		{`let f = fn(n, [a, ...r]) { len(r) }; f(0, [1, 2, 3])`, `2`},
+		{`let f = fn([a]) { a }; map([[1], [2]], f)`, `[1, 2]`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestConstAndBlockScoping(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`const x = 1; x`, `1`},
+		{`const [a, b] = [1, 2]; a + b`, `3`},
+		{`let x = 1; const x = 2; x`, `2`},
+		{`const x = 1; let f = fn() { let x = 2; x }; [f(), x]`, `[2, 1]`},
+		{`const x = 1; let f = fn(x) { x }; f(2)`, `2`},
+		{`const x = 1; if (true) { const x = 2; x }`, `2`},
+		{`let x = 1; if (true) { let x = 2; }; x`, `1`},
+		{`let x = 1; if (false) { 0 } else { let x = 2; }; x`, `1`},
+		{`let x = 1; if (true) { let y = x + 1; y }`, `2`},
+		{`let f = fn() { if (true) { return 1; }; 2 }; f()`, `1`},
+		{`let len = fn(x) { 0 }; len("abc")`, `0`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestStructs(t *testing.T) {
+	const point = `
+struct Point {
+	x,
+	y,
+	fn norm() { self.x * self.x + self.y * self.y },
+	fn add(other) { Point(self.x + other.x, self.y + other.y) },
+	fn move(dx, dy) { self.x = self.x + dx; self.y = self.y + dy; self },
+	fn scaled(k = 2) { Point(self.x * k, self.y * k).norm() },
+};
+`
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`Point(1, 2)`, `Point{x: 1, y: 2}`},
+		{`Point`, `struct Point { x, y }`},
+		{`Point(1, 2).x`, `1`},
+		{`let p = Point(1, 2); p.y`, `2`},
+		{`let p = Point(3, 4); p.norm()`, `25`},
+		{`Point(1, 2).add(Point(3, 4))`, `Point{x: 4, y: 6}`},
+		{`let p = Point(1, 2); p.x = 10; p`, `Point{x: 10, y: 2}`},
+		{`let p = Point(1, 2); p.x = p.y = 5; p`, `Point{x: 5, y: 5}`},
+		{`let p = Point(1, 2); let q = p; q.x = 3; p.x`, `3`},
+		{`let p = Point(1, 2); p.move(1, 1); p`, `Point{x: 2, y: 3}`},
+		{`let p = Point(1, 2); let f = p.norm; p.x = 0; f()`, `4`},
+		{`Point(1, 1).scaled()`, `8`},
+		{`map([Point(1, 0), Point(0, 2)], fn(p) { p.norm() })`, `[1, 4]`},
+		{`Point(Point(1, 2), 3).x.y`, `2`},
+		{`struct Empty {}; Empty()`, `Empty{}`},
+		{`let self = 1; Point(2, 3).norm() + self`, `14`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, point+tt.input)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestReprBuiltinFunctions(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`repr(1)`, `1`},
+		{`repr(-1)`, `-1`},
+		{`repr("a")`, `"a"`},
+		{`repr("a\"b\\c\nd\t")`, `"a\"b\\c\nd\t"`},
+		{`repr("$" + "{x}")`, `"\${x}"`},
+		{`repr(true)`, `true`},
+		{`repr(null)`, `null`},
+		{`repr([1, "a", [true, null]])`, `[1, "a", [true, null]]`},
+		{`repr({"a": 1, 2: ["b"], false: {}})`, `{"a": 1, 2: ["b"], false: {}}`},
+		{`struct P { x, y }; repr(P(1, "a"))`, `P(1, "a")`},
+		{`repr(fn(a, [b], c = 1, ...d) { a })`, `fn(a, [b], c = 1, ...d) { a }`},
+		{`repr(regex("[0-9]+\\."))`, `regex("[0-9]+\\.")`},
+		{`repr(range(3))`, `range(0, 3, 1)`},
+		{`struct P { x }; let p = P(1); p.x = p; repr(p)`, `P(P(...))`},
+		{`struct P { x }; let p = P(1); let a = [p, {"k": p}]; p.x = a; repr(a)`, `[P([...]), {"k": P([...])}]`},
+		{`struct P { x }; let p = P(1); let a = [p, p]; repr(a)`, `[P(1), P(1)]`},
+		{`pretty([])`, `[]`},
+		{`pretty("a")`, `"a"`},
+		{`pretty({"a": [1, {"b": []}], "c": "x"})`, "{\n  \"a\": [\n    1,\n    {\n      \"b\": []\n    }\n  ],\n  \"c\": \"x\"\n}"},
+		{`struct P { x, y }; pretty(P(1, [2]), "\t")`, "P(\n\t1,\n\t[\n\t\t2\n\t]\n)"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			str, ok := evaluated.(*object.String)
+			require.Truef(t, ok, "got %s", evaluated.Inspect())
+			require.Equal(t, tt.expect, str.Value)
+		})
+	}
+}
+
+func TestReprRoundTrip(t *testing.T) {
+	testcases := []string{
+		`-5`,
+		`"quote \" backslash \\ newline \n tab \t dollar \${x}"`,
+		`[1, "2", [true, false, null], {}]`,
+		`{"a": {"b": [1, 2]}, 1: "one", true: []}`,
+		`struct P { x, y }; P(1, P("a", [null]))`,
+	}
+
+	for _, input := range testcases {
+		t.Run(input, func(t *testing.T) {
+			// The source is evaluated in the same environment so that it
+			// refers to the same struct types.
+			env := eval.NewEnvironment()
+			evalInEnv := func(input string) object.Object {
+				program, err := parser.New(lexer.New(input)).ParseProgram()
+				require.NoError(t, err)
+				return env.Eval(program)
+			}
+			evaluated := evalInEnv(input)
+			for _, repr := range []string{object.Repr(evaluated), object.Pretty(evaluated, "  ")} {
+				reparsed := evalInEnv(repr)
+				require.Truef(t, object.Equal(evaluated, reparsed), "%s", repr)
+			}
+		})
+	}
+}
+
+func TestInspectCycle(t *testing.T) {
+	evaluated := testEval(t, `struct P { x }; let p = P(1); p.x = [p, {"k": p}]; p`)
+	require.Equal(t, `P{x: [P{...}, {"k": P{...}}]}`, evaluated.Inspect())
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := `fn(x) { x + 2; };`
 
@@ -394,7 +880,7 @@ func TestFunctionObject(t *testing.T) {
 
 	require.Equal(t, 1, len(fn.Parameters))
 	require.Equal(t, "x", fn.Parameters[0].Value)
-	require.Equal(t, "(x + 2)", fn.Body.String())
+	require.Equal(t, "{ (x + 2) }", fn.Body.String())
 }
 
 func TestFunctionApplication(t *testing.T) {
@@ -445,43 +931,877 @@ func TestFunctionApplication(t *testing.T) {
 
 }
 
-func TestBuiltinFunctions(t *testing.T) {
+func TestFunctionApplicationExtendedParameters(t *testing.T) {
 	testcases := []struct {
 		input  string
 		expect int64
 	}{
 		{
-			input:  `len("")`,
+			input:  `let f = fn(x, y = 10) { x + y; }; f(1);`,
+			expect: 11,
+		},
+		{
+			input:  `let f = fn(x, y = 10) { x + y; }; f(1, 2);`,
+			expect: 3,
+		},
+		{
+			input:  `let f = fn(x, y = x * 2) { x + y; }; f(3);`,
+			expect: 9,
+		},
+		{
+			input:  `let f = fn(x, ...rest) { rest; }; len(f(1, 2, 3));`,
+			expect: 2,
+		},
+		{
+			input:  `let f = fn(x, ...rest) { rest; }; len(f(1));`,
 			expect: 0,
 		},
 		{
-			input:  `len("four")`,
-			expect: 4,
+			input:  `let f = fn(x, ...rest) { rest[1]; }; f(1, 2, 3);`,
+			expect: 3,
 		},
 		{
-			input:  `len("Hello World!")`,
-			expect: 12,
+			input:  `let add = fn(x, y) { x + y; }; let args = [1, 2]; add(...args);`,
+			expect: 3,
+		},
+		{
+			input:  `let add = fn(x, y, z) { x + y + z; }; add(1, ...[2, 3]);`,
+			expect: 6,
+		},
+		{
+			input:  `[0, ...[1, 2], 3][2];`,
+			expect: 2,
+		},
+		{
+			input:  `let fact = fn f(n) { if (n < 2) { 1 } else { n * f(n - 1) } }; fact(5);`,
+			expect: 120,
+		},
+		{
+			input:  `fn fib(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }(10);`,
+			expect: 55,
 		},
 	}
 
 	for _, tt := range testcases {
 		t.Run(tt.input, func(t *testing.T) {
-			evaluated := testEval(t, tt.input)
-			i, ok := evaluated.(*object.Integer)
-			require.True(t, ok)
-			require.Equal(t, tt.expect, i.Value)
+			testIntegerObject(t, tt.expect, testEval(t, tt.input))
 		})
 	}
 }
 
-func TestErrorHandling(t *testing.T) {
+func TestBuiltinFunctions(t *testing.T) {
 	testcases := []struct {
 		input  string
-		expect string
+		expect int64
 	}{
 		{
-			input:  `5 + true`,
-			expect: "type mismatch: INTEGER + BOOLEAN",
+			input:  `len("")`,
+			expect: 0,
+		},
+		{
+			input:  `len("four")`,
+			expect: 4,
+		},
+		{
+			input:  `len("Hello World!")`,
+			expect: 12,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			i, ok := evaluated.(*object.Integer)
+			require.True(t, ok)
+			require.Equal(t, tt.expect, i.Value)
+		})
+	}
+}
+
+func TestHigherOrderBuiltinFunctions(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{
+			input:  `map([1, 2, 3], fn(x) { x * 2 })`,
+			expect: `[2, 4, 6]`,
+		},
+		{
+			input:  `map({"a": 1, "b": 2}, fn(k, v) { v * 10 })`,
+			expect: `{"a": 10, "b": 20}`,
+		},
+		{
+			input:  `map([], fn(x) { x })`,
+			expect: `[]`,
+		},
+		{
+			input:  `filter([1, 2, 3, 4], fn(x) { x > 2 })`,
+			expect: `[3, 4]`,
+		},
+		{
+			input:  `filter({"a": 1, "b": 2}, fn(k, v) { v > 1 })`,
+			expect: `{"b": 2}`,
+		},
+		{
+			input:  `reduce([1, 2, 3], fn(acc, x) { acc + x }, 10)`,
+			expect: `16`,
+		},
+		{
+			input:  `reduce({"a": 1, "b": 2}, fn(acc, k, v) { acc + k }, "")`,
+			expect: `ab`,
+		},
+		{
+			input:  `each([1, 2], fn(x) { x })`,
+			expect: `null`,
+		},
+		{
+			input:  `sort_by([3, 1, 2], fn(x) { x })`,
+			expect: `[1, 2, 3]`,
+		},
+		{
+			input:  `sort_by(["bb", "a", "ccc"], fn(x) { 0 - len(x) })`,
+			expect: `["ccc", "bb", "a"]`,
+		},
+		{
+			input:  `sort_by({"a": 2, "b": 1}, fn(k, v) { v })`,
+			expect: `[["b", 1], ["a", 2]]`,
+		},
+		{
+			input:  `find([1, 2, 3], fn(x) { x > 1 })`,
+			expect: `2`,
+		},
+		{
+			input:  `find([1, 2, 3], fn(x) { x > 3 })`,
+			expect: `null`,
+		},
+		{
+			input:  `find({"a": 1, "b": 2}, fn(k, v) { v == 2 })`,
+			expect: `["b", 2]`,
+		},
+		{
+			input:  `any([1, 2, 3], fn(x) { x > 2 })`,
+			expect: `true`,
+		},
+		{
+			input:  `any([], fn(x) { true })`,
+			expect: `false`,
+		},
+		{
+			input:  `all([1, 2, 3], fn(x) { x > 0 })`,
+			expect: `true`,
+		},
+		{
+			input:  `all({"a": 1, "b": 2}, fn(k, v) { v > 1 })`,
+			expect: `false`,
+		},
+		{
+			input:  `let double = fn(x) { x * 2 }; map(map([1], double), double)`,
+			expect: `[4]`,
+		},
+		{
+			input:  `map(["a", "bb"], len)`,
+			expect: `[1, 2]`,
+		},
+		{
+			input:  `let k = 3; map([1, 2], fn(x) { x * k })`,
+			expect: `[3, 6]`,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestJSONBuiltinFunctions(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{
+			input:  `json_parse("{\"b\": [1, -2, true, null], \"a\": {\"s\": \"x\"}}")`,
+			expect: `{"b": [1, -2, true, null], "a": {"s": "x"}}`,
+		},
+		{
+			input:  `json_parse("[]")`,
+			expect: `[]`,
+		},
+		{
+			input:  `json_parse(" \"str\" ")`,
+			expect: `str`,
+		},
+		{
+			input:  `json_parse("{\"a\": 1}")["a"]`,
+			expect: `1`,
+		},
+		{
+			input:  `json_parse("[1e3, 2.0, -5E+1, -9223372036854775808]")`,
+			expect: `[1000, 2, -50, -9223372036854775808]`,
+		},
+		{
+			input:  `json_stringify({"b": [1, "x", true, false], "a": {}})`,
+			expect: `{"b":[1,"x",true,false],"a":{}}`,
+		},
+		{
+			input:  `json_stringify(["<\"quoted\">"])`,
+			expect: `["<\"quoted\">"]`,
+		},
+		{
+			input:  `json_stringify({"a": [1]}, 2)`,
+			expect: "{\n  \"a\": [\n    1\n  ]\n}",
+		},
+		{
+			input:  `json_stringify([1], "\t")`,
+			expect: "[\n\t1\n]",
+		},
+		{
+			input:  `json_stringify(json_parse("{\"k\": [null]}"))`,
+			expect: `{"k":[null]}`,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestRegexpBuiltinFunctions(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{
+			input:  `regex("a+b")`,
+			expect: `/a+b/`,
+		},
+		{
+			input:  `match(regex("(\\w+)@(\\w+)"), "mail: foo@example")`,
+			expect: `["foo@example", "foo", "example"]`,
+		},
+		{
+			input:  `match("x+", "abc")`,
+			expect: `null`,
+		},
+		{
+			input:  `find_all("[0-9]+", "a1 b22 c333")`,
+			expect: `["1", "22", "333"]`,
+		},
+		{
+			input:  `find_all("[0-9]+", "abc")`,
+			expect: `[]`,
+		},
+		{
+			input:  `replace("(\\w+)@(\\w+)", "foo@bar", "\${2}@\${1}")`,
+			expect: `bar@foo`,
+		},
+		{
+			input:  `let re = regex("o+"); replace(re, "foo boo", fn(m) { "<" + m + ">" })`,
+			expect: `f<oo> b<oo>`,
+		},
+		{
+			input:  `split_re(",\\s*", "a, b,c")`,
+			expect: `["a", "b", "c"]`,
+		},
+		{
+			input:  `map(["a1", "b"], fn(s) { match("[0-9]", s) })`,
+			expect: `[["1"], null]`,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestAssertBuiltinFunctions(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string // the message of the error, or empty if the assertions pass
+		pos    string // the position of the error
+	}{
+		{input: `assert(true); assert(1, "msg"); assert_eq(1 + 1, 2); assert_eq("a", "a")`},
+		{input: `assert_eq([1, {"a": [2]}], [1, {"a": [2]}]); assert_eq({"a": 1, "b": 2}, {"b": 2, "a": 1})`},
+		{input: `struct P { x }; assert_eq(P([1]), P([1])); let f = fn() {}; assert_eq(f, f)`},
+		{
+			input:  `assert(false)`,
+			expect: "assertion failed",
+			pos:    "1:1",
+		},
+		{
+			input:  `let x = 0;` + "\n" + `  assert(x > 1, "x is ${x}")`,
+			expect: "assertion failed: x is 0",
+			pos:    "2:3",
+		},
+		{
+			input:  `assert_eq(1 + 1, 3)`,
+			expect: "assertion failed: got 2, want 3",
+			pos:    "1:1",
+		},
+		{
+			input:  `assert_eq([1, 2], [1, "2"], "%s")`,
+			expect: "assertion failed: %s: got [1, 2], want [1, \"2\"]",
+			pos:    "1:1",
+		},
+		{
+			input:  `assert_eq(1, "1")`,
+			expect: "assertion failed: got 1 (INTEGER), want 1 (STRING)",
+			pos:    "1:1",
+		},
+		{
+			input:  `assert_eq({"a": 1}, {"a": 1, "b": 2})`,
+			expect: `assertion failed: got {"a": 1}, want {"a": 1, "b": 2}`,
+			pos:    "1:1",
+		},
+		{
+			input:  `assert_eq(len, puts)`,
+			expect: "assertion failed: got builtin function, want builtin function",
+			pos:    "1:1",
+		},
+		{
+			input:  `each([1, 2], fn(x) {` + "\n" + ` assert(x < 2) })`,
+			expect: "assertion failed",
+			pos:    "2:2",
+		},
+		{
+			input:  `assert()`,
+			expect: "wrong number of arguments: got=0, want=1..2",
+			pos:    "1:1",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			if tt.expect == "" {
+				require.Equal(t, object.Null, evaluated)
+				return
+			}
+			err, ok := evaluated.(*object.Error)
+			require.True(t, ok, evaluated.Inspect())
+			require.Equal(t, tt.expect, err.Message)
+			require.Equal(t, tt.pos, err.Pos.String())
+		})
+	}
+}
+
+func TestGeneratorsAndIterators(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`range(3)`, `range(0, 3, 1)`},
+		{`collect(range(5))`, `[0, 1, 2, 3, 4]`},
+		{`collect(range(2, 5))`, `[2, 3, 4]`},
+		{`collect(range(5, 0, -2))`, `[5, 3, 1]`},
+		{`collect(range(0))`, `[]`},
+		{`collect(range(9223372036854775800, 9223372036854775807, 5))`, `[9223372036854775800, 9223372036854775805]`},
+		{`collect(take(range(9223372036854775800, 9223372036854775807, 5), 4))`, `[9223372036854775800, 9223372036854775805]`},
+		{`collect(range(-9223372036854775800, -9223372036854775807 - 1, -5))`, `[-9223372036854775800, -9223372036854775805]`},
+		{`let r = range(3); [collect(r), collect(r)]`, `[[0, 1, 2], [0, 1, 2]]`},
+		{`let g = fn() { yield 1; yield 2; }; g()`, `generator`},
+		{`let g = fn() { yield 1; yield 2; }; collect(g())`, `[1, 2]`},
+		{`let g = fn(n) { for (i in range(n)) { yield i * i } }; collect(g(4))`, `[0, 1, 4, 9]`},
+		{`let g = fn() { yield 1; return 0; yield 2 }; collect(g())`, `[1]`},
+		{`let g = fn() { 1 }; g()`, `1`},
+		{`let g = fn() { yield 1 }; let it = g(); [collect(it), collect(it)]`, `[[1], []]`},
+		{`let nat = fn() { let loop = fn(n) { n }; for (i in range(0, 9223372036854775807)) { yield loop(i) } }; collect(take(nat(), 3))`, `[0, 1, 2]`},
+		{`struct P { a, b }; let fib = fn() { let p = P(0, 1); for (_ in range(100)) { yield p.a; let b = p.b; p.b = p.a + b; p.a = b; } }; collect(take(fib(), 10))`, `[0, 1, 1, 2, 3, 5, 8, 13, 21, 34]`},
+		{`let inner = fn() { yield 1; yield 2 }; let outer = fn() { for (x in inner()) { yield x * 10 } }; collect(outer())`, `[10, 20]`},
+		{`collect(map(range(4), fn(x) { x * 2 }))`, `[0, 2, 4, 6]`},
+		{`map(range(4), fn(x) { x * 2 })`, `iterator`},
+		{`collect(filter(range(10), fn(x) { x / 3 * 3 == x }))`, `[0, 3, 6, 9]`},
+		{`reduce(range(100001), fn(acc, x) { acc + x }, 0)`, `5000050000`},
+		{`find(map(range(1000000000), fn(x) { x * x }), fn(x) { x > 50 })`, `64`},
+		{`any(range(1000000000), fn(x) { x == 3 })`, `true`},
+		{`all(range(5), fn(x) { x < 5 })`, `true`},
+		{`sort_by(range(3), fn(x) { -x })`, `[2, 1, 0]`},
+		{`let f = fn(a, b, c) { a + b + c }; f(...range(3))`, `3`},
+		{`collect(take([1, 2, 3], 2))`, `[1, 2]`},
+		{`collect({"a": 1})`, `[["a", 1]]`},
+		{`let s = 0; for (i in range(5)) { let s = s + i; }; s`, `0`},
+		{`let f = fn() { let n = 0; for (i in [1, 2, 3]) { if (i == 2) { return i * 100; } }; n }; f()`, `200`},
+		{`let g = fn() { for (i in range(3)) { yield fn() { i } } }; map(collect(g()), fn(f) { f() })`, `[0, 1, 2]`},
+		{`let f = fn(xs) { let out = 0; for ([k, v] in xs) { let out = v; }; out }; f({"a": 1})`, `0`},
+		{`let g = fn() { for ([k, v] in {"a": 1, "b": 2}) { yield k } }; collect(g())`, `["a", "b"]`},
+		{`struct Counter { n, fn each() { for (i in range(self.n)) { yield i } } }; collect(Counter(3).each())`, `[0, 1, 2]`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	var out bytes.Buffer
+	evaluated := testEval(t, `
+for (i in range(3)) { puts(i) }
+for ([k, v] in {"a": 1, "b": 2}) { puts(k + "=" + "${v}") }
+let g = fn() { puts("start"); yield 1; puts("resumed"); yield 2; puts("unreachable") };
+for (x in take(g(), 2)) { puts(x) }
+`, eval.WithStdout(&out))
+
+	require.Nil(t, evaluated)
+	require.Equal(t, "0\n1\n2\na=1\nb=2\nstart\n1\nresumed\n2\n", out.String())
+}
+
+func TestTasksAndChannels(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`spawn(fn() { 1 })`, `task`},
+		{`join(spawn(fn(a, b) { a + b }, 1, 2))`, `3`},
+		{`join(spawn(len, "abc"))`, `3`},
+		{`join(spawn(fn() { let x = 1; }))`, `null`},
+		{`let t = spawn(fn() { 1 }); [join(t), join(t)]`, `[1, 1]`},
+		{`join(spawn(fn() { 1 }), spawn(fn() { 2 }))`, `[1, 2]`},
+		{`channel()`, `channel`},
+		{`let ch = channel(2); send(ch, 1); send(ch, 2); close(ch); [recv(ch), recv(ch), recv(ch)]`, `[1, 2, null]`},
+		{`let ch = channel(); spawn(fn() { for (i in range(5)) { send(ch, i) }; close(ch) }); collect(ch)`, `[0, 1, 2, 3, 4]`},
+		{`let ch = channel(); let t = spawn(fn() { reduce(ch, fn(acc, x) { acc + x }, 0) }); for (i in range(101)) { send(ch, i) }; close(ch); join(t)`, `5050`},
+		{`let out = channel(4); let ts = collect(map(range(4), fn(i) { spawn(fn() { send(out, i * i) }) })); join(...ts); close(out); reduce(out, fn(acc, x) { acc + x }, 0)`, `14`},
+		{`let a = channel(1); let b = channel(1); send(b, "x"); select(a, b)`, `[1, "x"]`},
+		{`let a = channel(1); [select([a, 5]), recv(a)]`, `[[0, null], 5]`},
+		{`let a = channel(); close(a); select(a)`, `[0, null]`},
+		{`let a = channel(); let b = channel(); spawn(fn() { send(b, 2) }); select(a, b)`, `[1, 2]`},
+		{`let done = channel(); let t = spawn(fn() { recv(done); "stopped" }); close(done); join(t)`, `stopped`},
+		{`struct Counter { n }; let c = Counter(0); let lock = channel(1); send(lock, true); let work = fn() { for (_ in range(50)) { recv(lock); c.n = c.n + 1; send(lock, true) } }; join(...collect(map(range(8), fn(_) { spawn(work) }))); c.n`, `400`},
+		{`let g = fn(n) { let x = n * 2; fn() { x + n } }; join(...collect(map(range(16), fn(i) { spawn(g(i)) })))`, `[0, 3, 6, 9, 12, 15, 18, 21, 24, 27, 30, 33, 36, 39, 42, 45]`},
+		{`let g = fn() { for (i in range(1000)) { yield i } }; let it = g(); let sum = fn() { reduce(it, fn(acc, x) { acc + x }, 0) }; reduce(join(...collect(map(range(8), fn(_) { spawn(sum) }))), fn(acc, x) { acc + x }, 0)`, `499500`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestTaskOutput(t *testing.T) {
+	var out bytes.Buffer
+	evaluated := testEval(t, `
+let ts = collect(map(range(8), fn(i) { spawn(fn() { puts("task"); print("") }) }));
+join(...ts);
+`, eval.WithStdout(&out))
+
+	require.Equal(t, "[null, null, null, null, null, null, null, null]", evaluated.Inspect())
+	require.Equal(t, strings.Repeat("task\n", 8), out.String())
+}
+
+func TestScopes(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`let f = fn() { x }; let x = 3; f()`, `3`},
+		{`let x = 1; let f = fn() { let y = x; let x = 2; [y, x] }; f()`, `[1, 2]`},
+		{`let f = fn() { let g = fn() { x }; let x = 5; g() }; f()`, `5`},
+		{`let x = 1; if (true) { let x = 2; }; x`, `1`},
+		{`let x = 1; let f = fn() { if (true) { let y = x; let x = 2; [y, x] } }; f()`, `[1, 2]`},
+		{`let f = fn() { let fact = fn(n) { if (n == 0) { 1 } else { n * fact(n - 1) } }; fact(5) }; f()`, `120`},
+		{`let f = fn() { fn go(n) { if (n == 0) { "done" } else { go(n - 1) } }(3) }; f()`, `done`},
+		{`let make = fn() { let n = 10; fn() { n } }; make()()`, `10`},
+		{`let make = fn(n) { fn(m) { fn() { n + m } } }; make(1)(2)()`, `3`},
+		{`let f = fn(a, b = a * 2) { b }; f(3)`, `6`},
+		{`let f = fn([a, b], {c}) { a + b + c }; f([1, 2], {"c": 3})`, `6`},
+		{`let f = fn(k) { struct P { x, fn m() { self.x + k } }; P(1).m() }; f(10)`, `11`},
+		{`let f = fn(v) { match (v) { [a, b] => a + b, x => x } }; [f([1, 2]), f(3)]`, `[3, 3]`},
+		{`let len = fn(x) { 0 }; len("abc")`, `0`},
+		{`let f = fn() { let len = 5; len }; [f(), len("ab")]`, `[5, 2]`},
+		{`let x = 1; let f = fn() { x }; let x = 2; f()`, `2`},
+		{`let f = fn() { let x = 1; let g = fn() { x }; let x = 2; g() }; f()`, `2`},
+		{`let fs = fn() { let a = 1; [fn() { a }, fn() { 2 }] }; map(fs(), fn(f) { f() })`, `[1, 2]`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	program, err := parser.New(lexer.New(`
+let a = 1;
+let f = fn(x) {
+	let g = fn() { x };
+	let h = fn(y) { a + y };
+	if (x) { let z = x; z };
+	g()
+};
+f(1)
+`)).ParseProgram()
+	require.NoError(t, err)
+	require.Equal(t, "1", eval.NewEnvironment().Eval(program).Inspect())
+
+	var refs []string
+	var isolated []bool
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			switch node.Ref.Kind {
+			case ast.RefLocal:
+				refs = append(refs, fmt.Sprintf("%s:local(%d,%d)", node.Value, node.Ref.Depth, node.Ref.Slot))
+			case ast.RefGlobal:
+				refs = append(refs, node.Value+":global")
+			}
+		case *ast.FunctionLiteral:
+			isolated = append(isolated, node.Isolated)
+		}
+		return true
+	})
+	require.Equal(t, []string{
+		"x:local(1,0)",
+		"a:global", "y:local(0,0)",
+		"x:local(0,0)", "x:local(1,0)", "z:local(0,0)",
+		"g:local(0,1)",
+		"f:global",
+	}, refs)
+	require.Equal(t, []bool{true, false, true}, isolated)
+}
+
+func TestEvalSession(t *testing.T) {
+	env := eval.NewEnvironment()
+	for _, tt := range []struct {
+		input  string
+		expect string
+	}{
+		{`let f = fn() { g() + x };`, ``},
+		{`f()`, `ERROR: identifier not found: g`},
+		{`let g = fn() { 7 }; let x = 1;`, ``},
+		{`f()`, `8`},
+		{`let x = 10; f()`, `17`},
+	} {
+		program, err := parser.New(lexer.New(tt.input)).ParseProgram()
+		require.NoError(t, err)
+		evaluated := env.Eval(program)
+		if tt.expect == "" {
+			require.Nil(t, evaluated)
+			continue
+		}
+		require.Equal(t, tt.expect, evaluated.Inspect())
+	}
+
+	// A program evaluated in an enclosed environment is not resolved.
+	program, err := parser.New(lexer.New(`let y = x + 1; let h = fn() { y }; h()`)).ParseProgram()
+	require.NoError(t, err)
+	enclosed := env.NewEnclosedEnvironment().(*eval.Environment)
+	require.Equal(t, "11", enclosed.Eval(program).Inspect())
+	_, ok := env.Get("y")
+	require.False(t, ok)
+}
+
+func TestUndefinedVariable(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+		output string
+	}{
+		{`puts("unreachable"); foo`, "identifier not found: foo", ""},
+		{`puts("unreachable"); if (false) { foo }`, "identifier not found: foo", ""},
+		{`puts("unreachable"); for (x in [1]) { x }; x`, "identifier not found: x", ""},
+		{`puts("unreachable"); match (1) { x => x }; x`, "identifier not found: x", ""},
+		// Identifiers in function literals are looked up when called.
+		{`puts("reached"); let f = fn() { [x, y] }; let x = 1; f()`, "identifier not found: y", "reached\n"},
+		{`puts("reached"); fn() { self }()`, "identifier not found: self", "reached\n"},
+		{`puts("reached"); struct P { x, fn m() { x } }; P(1).m()`, "identifier not found: x", "reached\n"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			var out bytes.Buffer
+			evaluated := testEval(t, tt.input, eval.WithStdout(&out))
+			errObj, ok := evaluated.(*object.Error)
+			require.True(t, ok, "got %s", evaluated)
+			require.Equal(t, tt.expect, errObj.Message)
+			require.Equal(t, tt.output, out.String())
+		})
+	}
+}
+
+func TestMemProfile(t *testing.T) {
+	profile := eval.NewMemProfile()
+	evaluated := testEval(t, `
+let pair = fn(n) { [n, n] };
+let rec = fn(n) { if (n == 0) { [] } else { rec(n - 1); [n] } };
+collect(map(range(10), pair));
+rec(5);
+`, eval.WithMemProfile(profile))
+	require.Equal(t, "[5]", evaluated.Inspect())
+
+	var out bytes.Buffer
+	require.NoError(t, profile.Report(&out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Equal(t, []string{"bytes", "objects", "calls", "function"}, strings.Fields(lines[0]))
+
+	calls := map[string]string{}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		require.Len(t, fields, 5)
+		calls[fields[3]+" "+fields[4]] = fields[2]
+	}
+	require.Equal(t, map[string]string{"pair (2:18)": "10", "rec (3:17)": "6"}, calls)
+}
+
+func TestProfile(t *testing.T) {
+	profile := eval.NewProfile()
+	evaluated := testEval(t, `
+let pair = fn(n) { [n, n] };
+let fib = fn f(n) { if (n < 2) { n } else { f(n - 1) + f(n - 2) } };
+collect(map(range(10), pair));
+fn() { 1 }();
+fib(5);
+`, eval.WithProfile(profile))
+	require.Equal(t, "5", evaluated.Inspect())
+
+	var out bytes.Buffer
+	require.NoError(t, profile.Report(&out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Equal(t, []string{"flat", "cum", "calls", "function"}, strings.Fields(lines[0]))
+
+	calls := map[string]string{}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		require.Len(t, fields, 5)
+		calls[fields[3]+" "+fields[4]] = fields[2]
+	}
+	require.Equal(t, map[string]string{"pair (2:18)": "10", "f (3:19)": "15", "fn (5:6)": "1"}, calls)
+
+	out.Reset()
+	require.NoError(t, profile.WritePprof(&out, "test.mk"))
+	r, err := gzip.NewReader(&out)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	for _, s := range []string{"calls", "count", "time", "nanoseconds", "pair (2:18)", "f (3:19)", "test.mk"} {
+		require.Contains(t, string(data), s)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var out bytes.Buffer
+	evaluated := testEval(t, "let f = fn(x) { x * 2 };\nf(1 + 2)", eval.WithTrace(&out))
+	require.Equal(t, "6", evaluated.Inspect())
+	require.Equal(t, `    1:9: fn(x) { (x * 2) } => fn(x) { (x * 2) }
+  1:1: let f = fn(x) { (x * 2) };
+      2:1: f => fn(x) { (x * 2) }
+        2:3: 1 => 1
+        2:7: 2 => 2
+      2:5: (1 + 2) => 3
+            1:17: x => 3
+            1:21: 2 => 2
+          1:19: (x * 2) => 6
+        1:17: (x * 2) => 6
+      1:15: { (x * 2) } => 6
+    2:2: f((1 + 2)) => 6
+  2:1: f((1 + 2)) => 6
+`, out.String())
+}
+
+func TestTraceMultibyte(t *testing.T) {
+	var out bytes.Buffer
+	testEval(t, `"`+strings.Repeat("é", 40)+`"`, eval.WithTrace(&out))
+	require.True(t, utf8.ValidString(out.String()), out.String())
+	require.Equal(t, `    1:1: "`+strings.Repeat("é", 28)+"...", strings.SplitN(out.String(), " => ", 2)[0])
+}
+
+func TestStepLimit(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`let loop = fn() { loop() }; loop()`, "ERROR: step limit exceeded"},
+		{`for (x in range(1000000)) { x }`, "ERROR: step limit exceeded"},
+		{`each(range(1000000), puts)`, "ERROR: step limit exceeded"},
+		{`join(spawn(fn() { let loop = fn() { loop() }; loop() }))`, "ERROR: step limit exceeded"},
+		{`let f = fn(n) { if (n > 0) { f(n - 1) } else { n } }; f(10)`, "0"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input, eval.WithStdout(io.Discard), eval.WithStepLimit(1000))
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestOptimization(t *testing.T) {
+	testcases := []string{
+		`2 * 60 * 60`,
+		`-(1 + 2) * 3 / 2 - 7`,
+		`[1 < 2, 1 > 2, 1 == 1, 1 != 1, !true, !!0, !"a"]`,
+		`"foo" + "bar" + "baz"`,
+		`-true`,
+		`"a" - "b"`,
+		`if (1 < 2) { 1 } else { 2 }`,
+		`if (false) { 1 }`,
+		`if (false) { puts(1) }; puts(2); if (true) { puts(3); puts(4) }; 5`,
+		`let x = 1; if (true) { let x = 2; puts(x) }; x`,
+		`let f = fn() { if (true) { return 1 }; 2 }; f()`,
+		`let sec = fn(h) { h * 60 * 60 }; let h = 3; [sec(2), sec(h), sec(h + 1)]`,
+		`let sub = fn(a, b) { a - b }; let a = 5; let b = 3; [sub(a, b), sub(b, a), sub(1, 2)]`,
+		`let double = fn(x) { x * 2 }; double("a")`,
+		`let k = fn(x) { 1 }; k(y)`,
+		`let f = fn(x) { x }; let g = fn(f) { f(1) }; g(fn(x) { x + 1 })`,
+		`let f = fn(x) { x + 1 }; collect(map([1, 2, 3], f))`,
+	}
+
+	for _, input := range testcases {
+		t.Run(input, func(t *testing.T) {
+			var out, optimizedOut bytes.Buffer
+			expect := testEval(t, input, eval.WithStdout(&out))
+			got := testEval(t, input, eval.WithStdout(&optimizedOut), eval.WithOptimization())
+			require.Equal(t, expect.Inspect(), got.Inspect())
+			require.Equal(t, out.String(), optimizedOut.String())
+		})
+	}
+}
+
+func TestOutputBuiltinFunctions(t *testing.T) {
+	var out bytes.Buffer
+	evaluated := testEval(t, `puts("hello", 1); print("a", [1, 2]); puts();`, eval.WithStdout(&out))
+
+	testNullObject(t, evaluated)
+	require.Equal(t, "hello\n1\na [1, 2]", out.String())
+}
+
+func TestOSBuiltinFunctions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("content"), 0o644))
+	t.Setenv("MONKEY_TEST_ENV", "value")
+
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{
+			input:  `read_file("` + path + `")`,
+			expect: `content`,
+		},
+		{
+			input:  `write_file("` + filepath.Join(dir, "new.txt") + `", "new"); read_file("` + filepath.Join(dir, "new.txt") + `")`,
+			expect: `new`,
+		},
+		{
+			input:  `getenv("MONKEY_TEST_ENV")`,
+			expect: `value`,
+		},
+		{
+			input:  `getenv("MONKEY_TEST_NO_SUCH_ENV")`,
+			expect: `null`,
+		},
+		{
+			input:  `args()`,
+			expect: `["a", "b"]`,
+		},
+		{
+			input:  `now() > 0`,
+			expect: `true`,
+		},
+		{
+			input:  `sleep(0)`,
+			expect: `null`,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input,
+				eval.WithCapabilities(eval.CapabilityAll),
+				eval.WithArgs([]string{"a", "b"}),
+			)
+			require.Equal(t, tt.expect, evaluated.Inspect())
+		})
+	}
+}
+
+func TestExitBuiltinFunction(t *testing.T) {
+	code := -1
+	testEval(t, `exit(3)`,
+		eval.WithCapabilities(eval.CapabilityProcess),
+		eval.WithExit(func(c int) { code = c }),
+	)
+	require.Equal(t, 3, code)
+}
+
+func TestCapabilities(t *testing.T) {
+	testcases := []struct {
+		input  string
+		caps   eval.Capability
+		expect string
+	}{
+		{
+			input:  `read_file("/dev/null")`,
+			caps:   eval.CapabilityNone,
+			expect: "read_file: filesystem access is disabled",
+		},
+		{
+			input:  `write_file("/dev/null", "")`,
+			caps:   eval.CapabilityAll &^ eval.CapabilityFS,
+			expect: "write_file: filesystem access is disabled",
+		},
+		{
+			input:  `getenv("HOME")`,
+			caps:   eval.CapabilityFS,
+			expect: "getenv: environment access is disabled",
+		},
+		{
+			input:  `args()`,
+			caps:   eval.CapabilityNone,
+			expect: "args: environment access is disabled",
+		},
+		{
+			input:  `exit()`,
+			caps:   eval.CapabilityNone,
+			expect: "exit: process access is disabled",
+		},
+		{
+			input:  `now()`,
+			caps:   eval.CapabilityNone,
+			expect: "now: time access is disabled",
+		},
+		{
+			input:  `read_file("/no/such/file")`,
+			caps:   eval.CapabilityFS,
+			expect: "read_file: open /no/such/file: no such file or directory",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(t, tt.input, eval.WithCapabilities(tt.caps))
+
+			errObj, ok := evaluated.(*object.Error)
+			require.True(t, ok)
+			require.Equal(t, tt.expect, errObj.Message)
+		})
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{
+			input:  `5 + true`,
+			expect: "type mismatch: INTEGER + BOOLEAN",
 		},
 		{
 			input:  `5 + true; 5;`,
@@ -507,10 +1827,42 @@ func TestErrorHandling(t *testing.T) {
 			input:  `foobar`,
 			expect: "identifier not found: foobar",
 		},
+		{
+			input:  `let x = 0; 1 / x`,
+			expect: "division by zero",
+		},
+		{
+			input:  `let f = fn() {}; f() * 2`,
+			expect: "type mismatch: NULL * INTEGER",
+		},
 		{
 			input:  `"foo" - "bar"`,
 			expect: "unknown operator: STRING - STRING",
 		},
+		{
+			input:  `true < false`,
+			expect: "unknown operator: BOOLEAN < BOOLEAN",
+		},
+		{
+			input:  `{} > {}`,
+			expect: "unknown operator: HASH > HASH",
+		},
+		{
+			input:  `1 < "1"`,
+			expect: "type mismatch: INTEGER < STRING",
+		},
+		{
+			input:  `[1] + [2]`,
+			expect: "unknown operator: ARRAY + ARRAY",
+		},
+		{
+			input:  `[1, true] < [1, false]`,
+			expect: "incomparable elements: ARRAY < ARRAY",
+		},
+		{
+			input:  `[1] < ["a"]`,
+			expect: "incomparable elements: ARRAY < ARRAY",
+		},
 		{
 			input:  `len()`,
 			expect: "wrong number of arguments: got=0, want=1",
@@ -523,6 +1875,334 @@ func TestErrorHandling(t *testing.T) {
 			input:  `len("one", "two")`,
 			expect: "wrong number of arguments: got=2, want=1",
 		},
+		{
+			input:  `fn(x, y) { x }(1)`,
+			expect: "wrong number of arguments: got=1, want=2",
+		},
+		{
+			input:  `fn(x) { x }(1, 2)`,
+			expect: "wrong number of arguments: got=2, want=1",
+		},
+		{
+			input:  `fn(x, y = 1) { x }()`,
+			expect: "wrong number of arguments: got=0, want=1..2",
+		},
+		{
+			input:  `fn(x, y, ...rest) { x }(1)`,
+			expect: "wrong number of arguments: got=1, want>=2",
+		},
+		{
+			input:  `fn(x, y = z) { x }(1)`,
+			expect: "identifier not found: z",
+		},
+		{
+			input:  `fn(x) { x }(...1)`,
+			expect: "cannot spread INTEGER",
+		},
+		{
+			input:  `...[1]`,
+			expect: "spread is not allowed here: ...[1]",
+		},
+		{
+			input:  `fn f() { 1 }; f()`,
+			expect: "identifier not found: f",
+		},
+		{
+			input:  `{"name": "Monkey"}[fn(x) { x }];`,
+			expect: "unusable as hash key: FUNCTION",
+		},
+		{
+			input:  `{[1]: 2}`,
+			expect: "unusable as hash key: ARRAY",
+		},
+		{
+			input:  `map([1, 2], fn(x) { x + true })`,
+			expect: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:  `filter([1], fn(x, y) { x })`,
+			expect: "wrong number of arguments: got=1, want=2",
+		},
+		{
+			input:  `reduce([1, 2], fn(acc, x) { acc + foo }, 0)`,
+			expect: "identifier not found: foo",
+		},
+		{
+			input:  `each([1], fn(x) { -true })`,
+			expect: "unknown operator: -BOOLEAN",
+		},
+		{
+			input:  `any(1, fn(x) { x })`,
+			expect: "argument to `any` not supported: got INTEGER",
+		},
+		{
+			input:  `sort_by([1, 2], fn(x) { true })`,
+			expect: "sort key must be INTEGER or STRING: got BOOLEAN",
+		},
+		{
+			input:  `1[0:1]`,
+			expect: "slice operator not supported: INTEGER",
+		},
+		{
+			input:  `[1, 2]["a":]`,
+			expect: "slice index must be INTEGER: got STRING",
+		},
+		{
+			input:  `slice([1])`,
+			expect: "wrong number of arguments: got=1, want=2..3",
+		},
+		{
+			input:  `json_parse("")`,
+			expect: "json_parse: unexpected EOF",
+		},
+		{
+			input:  `json_parse("1.5")`,
+			expect: "json_parse: unsupported number 1.5 at $: only integers are supported",
+		},
+		{
+			input:  `json_parse("{\"a\": [1, 2.5]}")`,
+			expect: "json_parse: unsupported number 2.5 at $[\"a\"][1]: only integers are supported",
+		},
+		{
+			input:  `json_parse("[1e-400]")`,
+			expect: "json_parse: unsupported number 1e-400 at $[0]: only integers are supported",
+		},
+		{
+			input:  `json_parse("9223372036854775808")`,
+			expect: "json_parse: number 9223372036854775808 at $ is out of range",
+		},
+		{
+			input:  `json_parse("1e999999999")`,
+			expect: "json_parse: number 1e999999999 at $ is out of range",
+		},
+		{
+			input:  `json_stringify([1], -1)`,
+			expect: "indent of `json_stringify` must be 0..10, got -1",
+		},
+		{
+			input:  `json_stringify([1], 9223372036854775807)`,
+			expect: "indent of `json_stringify` must be 0..10, got 9223372036854775807",
+		},
+		{
+			input:  `json_parse("1 2")`,
+			expect: "json_parse: unexpected data after the top-level value",
+		},
+		{
+			input:  `json_parse(1)`,
+			expect: "argument to `json_parse` must be STRING, got INTEGER",
+		},
+		{
+			input:  `json_stringify({1: 2})`,
+			expect: "json_stringify: hash key must be STRING, got INTEGER",
+		},
+		{
+			input:  `json_stringify([fn(x) { x }])`,
+			expect: "json_stringify: FUNCTION cannot be encoded",
+		},
+		{
+			input:  `json_stringify([], true)`,
+			expect: "indent of `json_stringify` must be INTEGER or STRING, got BOOLEAN",
+		},
+		{
+			input:  `regex("(")`,
+			expect: "regex: error parsing regexp: missing closing ): `(`",
+		},
+		{
+			input:  `match("[", "a")`,
+			expect: "match: error parsing regexp: missing closing ]: `[`",
+		},
+		{
+			input:  `find_all(1, "a")`,
+			expect: "pattern of `find_all` must be REGEXP or STRING, got INTEGER",
+		},
+		{
+			input:  `replace("a", "abc", fn(m) { 1 })`,
+			expect: "replacement must be STRING, got INTEGER",
+		},
+		{
+			input:  `replace("a", "abc", fn(m) { m + 1 })`,
+			expect: "type mismatch: STRING + INTEGER",
+		},
+		{
+			input:  `split_re("a", 1)`,
+			expect: "second argument to `split_re` must be STRING, got INTEGER",
+		},
+		{
+			input:  `map([1])`,
+			expect: "wrong number of arguments: got=1, want=2",
+		},
+		{
+			input:  `struct P { x }; P(1, 2)`,
+			expect: "wrong number of arguments: got=2, want=1",
+		},
+		{
+			input:  `struct P { x }; P(1).y`,
+			expect: "P has no member y",
+		},
+		{
+			input:  `struct P { x, fn f() { 1 } }; let p = P(1); p.f = 2`,
+			expect: "P has no field f",
+		},
+		{
+			input:  `let a = 1; a.x`,
+			expect: "cannot access member x of INTEGER",
+		},
+		{
+			input:  `let a = {"x": 1}; a.x = 2`,
+			expect: "cannot assign to member x of HASH",
+		},
+		{
+			input:  `struct P { x, fn f() { self.x + true } }; P(1).f()`,
+			expect: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:  `const P = 1; struct P { x }`,
+			expect: "cannot reassign constant P",
+		},
+		{
+			input:  `for (x in 1) { x }`,
+			expect: "cannot iterate over INTEGER",
+		},
+		{
+			input:  `for ([a, b] in [1]) { a }`,
+			expect: "pattern [a, b] does not match INTEGER",
+		},
+		{
+			input:  `yield 1`,
+			expect: "yield outside generator",
+		},
+		{
+			input:  `let g = fn() { yield 1; yield 1 + true }; collect(g())`,
+			expect: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:  `let g = fn() { yield 1; yield 1 + true }; for (x in g()) { x }`,
+			expect: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:  `collect(map(range(3), fn(x) { x + true }))`,
+			expect: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:  `range(0, 1, 0)`,
+			expect: "range step must not be zero",
+		},
+		{
+			input:  `range("a")`,
+			expect: "argument to `range` must be INTEGER, got STRING",
+		},
+		{
+			input:  `collect(1)`,
+			expect: "argument to `collect` not supported: got INTEGER",
+		},
+		{
+			input:  `take(range(3), "a")`,
+			expect: "second argument to `take` must be INTEGER, got STRING",
+		},
+		{
+			input:  `join(spawn(fn() { 1 }), spawn(fn() { 1 + true }), spawn(fn() { -true }))`,
+			expect: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:  `let t = spawn(fn() { -true }); 1; join(t)`,
+			expect: "unknown operator: -BOOLEAN",
+		},
+		{
+			input:  `spawn(1)`,
+			expect: "argument to `spawn` must be FUNCTION, got INTEGER",
+		},
+		{
+			input:  `join(1)`,
+			expect: "argument to `join` must be TASK, got INTEGER",
+		},
+		{
+			input:  `channel(-1)`,
+			expect: "channel capacity must not be negative",
+		},
+		{
+			input:  `channel(9223372036854775807)`,
+			expect: "channel capacity must not exceed 1048576, got 9223372036854775807",
+		},
+		{
+			input:  `let ch = channel(1); close(ch); send(ch, 1)`,
+			expect: "send on closed channel",
+		},
+		{
+			input:  `let ch = channel(); spawn(fn() { close(ch) }); send(ch, 1)`,
+			expect: "send on closed channel",
+		},
+		{
+			input:  `let ch = channel(); close(ch); close(ch)`,
+			expect: "close of closed channel",
+		},
+		{
+			input:  `let ch = channel(); close(ch); select([ch, 1])`,
+			expect: "send on closed channel",
+		},
+		{
+			input:  `select(1)`,
+			expect: "argument to `select` must be CHANNEL or [CHANNEL, value], got 1",
+		},
+		{
+			input:  `const x = 1; let x = 2;`,
+			expect: "cannot reassign constant x",
+		},
+		{
+			input:  `const x = 1; const x = 2;`,
+			expect: "cannot reassign constant x",
+		},
+		{
+			input:  `const x = 1; let [y, x] = [1, 2];`,
+			expect: "cannot reassign constant x",
+		},
+		{
+			input:  `if (true) { let y = 1; }; y`,
+			expect: "identifier not found: y",
+		},
+		{
+			input:  `let [a, b] = 1;`,
+			expect: "pattern [a, b] does not match INTEGER",
+		},
+		{
+			input:  `let [a, b] = [1, 2, 3];`,
+			expect: "pattern [a, b] does not match an array of 3 elements",
+		},
+		{
+			input:  `let [a, b, ...c] = [1];`,
+			expect: "pattern [a, b, ...c] does not match an array of 1 elements",
+		},
+		{
+			input:  `let {name, age} = {"name": "monkey"};`,
+			expect: `pattern {"name": name, "age": age} does not match a hash without key age`,
+		},
+		{
+			input:  `let {x} = [1];`,
+			expect: `pattern {"x": x} does not match ARRAY`,
+		},
+		{
+			input:  `let [1, a] = [2, 3];`,
+			expect: "pattern 1 does not match 2",
+		},
+		{
+			input:  `let f = fn(x, [a, b]) { a }; f(1, [1]);`,
+			expect: "pattern [a, b] does not match an array of 1 elements",
+		},
+		{
+			input:  `let f = fn({a} = 1) { a }; f();`,
+			expect: `pattern {"a": a} does not match INTEGER`,
+		},
+		{
+			input:  `match (3) { 1 => 1, 2 => 2 }`,
+			expect: "no match arm matches 3",
+		},
+		{
+			input:  `match ([1]) { [x] if x + true => 1 }`,
+			expect: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:  `match (foo) { _ => 1 }`,
+			expect: "identifier not found: foo",
+		},
 	}
 
 	for _, tt := range testcases {
@@ -536,11 +2216,11 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
-func testEval(t *testing.T, input string) object.Object {
+func testEval(t *testing.T, input string, opts ...eval.Option) object.Object {
 	t.Helper()
 	l := lexer.New(input)
 	p := parser.New(l)
-	env := eval.NewEnvironment()
+	env := eval.NewEnvironment(opts...)
 	program, err := p.ParseProgram()
 	require.NoError(t, err)
 	return env.Eval(program)
@@ -562,3 +2242,51 @@ func testNullObject(t *testing.T, obj object.Object) {
 	t.Helper()
 	require.Equal(t, object.Null, obj)
 }
+
+// BenchmarkEval compares the evaluation of resolved programs with that of
+// unresolved ones, which look up every variable by name through the
+// environments. A program is not resolved if it is evaluated in an
+// enclosed environment.
+func BenchmarkEval(b *testing.B) {
+	benchmarks := []struct {
+		name  string
+		input string
+	}{
+		{"fib", `let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)`},
+		{"closures", `let make = fn(n) { fn(m) { fn() { n + m } } }; reduce(range(1000), fn(acc, i) { acc + make(i)(1)() }, 0)`},
+		{"higher-order", `let xs = collect(range(1000)); reduce(filter(map(xs, fn(x) { x * x }), fn(x) { x / 2 * 2 == x }), fn(acc, x) { acc + x }, 0)`},
+		{"loop", `let f = fn(n) { let total = 0; for (i in range(n)) { let j = i * 2; if (j > total) { total } }; total }; f(1000)`},
+		{"match", `let sum = fn(xs) { match (xs) { [] => 0, [x, ...rest] => x + sum(rest) } }; sum(collect(range(200)))`},
+		{"struct", `struct P { x, y, fn norm() { self.x * self.x + self.y * self.y } }; reduce(range(1000), fn(acc, i) { acc + P(i, 1).norm() }, 0)`},
+		{"generator", `let nat = fn() { for (i in range(1000000)) { yield i } }; collect(take(nat(), 1000))`},
+	}
+
+	// The programs are evaluated statement by statement so that the
+	// resolver runs only once, before the timed loops. byName is never
+	// resolved and looks up its identifiers in a map per environment, as
+	// the evaluator did before the resolver.
+	evalStatements := func(env *eval.Environment, program *ast.Program) {
+		for _, stmt := range program.Statements {
+			env.Eval(stmt)
+		}
+	}
+	for _, bm := range benchmarks {
+		resolved, err := parser.New(lexer.New(bm.input)).ParseProgram()
+		require.NoError(b, err)
+		_, failed := eval.NewEnvironment().Eval(resolved).(*object.Error)
+		require.False(b, failed)
+		byName, err := parser.New(lexer.New(bm.input)).ParseProgram()
+		require.NoError(b, err)
+
+		b.Run(bm.name+"/slot", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				evalStatements(eval.NewEnvironment(), resolved)
+			}
+		})
+		b.Run(bm.name+"/name", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				evalStatements(eval.NewEnvironment(eval.WithLookupByName()), byName)
+			}
+		})
+	}
+}
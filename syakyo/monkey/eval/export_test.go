@@ -0,0 +1,10 @@
+package eval
+
+// WithLookupByName makes the environments index their variables by name, so
+// that the benchmarks can compare the lookup of unresolved identifiers in a
+// map per environment with the lookup by slot.
+func WithLookupByName() Option {
+	return func(c *config) {
+		c.byName = true
+	}
+}
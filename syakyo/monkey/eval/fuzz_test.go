@@ -0,0 +1,56 @@
+package eval_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/eval"
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/parser"
+)
+
+// unboundedBuiltins may block forever or run without taking steps, so the
+// programs referring to them are not evaluated.
+var unboundedBuiltins = map[string]bool{
+	"recv":    true,
+	"send":    true,
+	"select":  true,
+	"collect": true,
+}
+
+func FuzzEval(f *testing.F) {
+	for _, seed := range []string{
+		"let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(20)",
+		"let loop = fn() { loop() }; loop()",
+		`let s = "a${1 + 2}b"; len(s) / 0`,
+		`let {a, b: [c, ...d]} = {"a": 1, "b": [2, 3]}; match (d) { [x] if x > 2 => x, _ => -1 }`,
+		"struct P { x, fn get() { self.x } }; let p = P(1); p.x = p.get() + 1; p",
+		"let g = fn() { for (x in range(10)) { yield x * 2 } }; map(g(), fn(x) { x })",
+		`json_parse(json_stringify({"a": [1, true, "s"]}))`,
+		`assert_eq(replace(regex("a+"), "baaa", "c"), "bc")`,
+		"join(spawn(fn(n) { n * 2 }, 21))",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		program, err := parser.New(lexer.New(input)).ParseProgram()
+		if err != nil {
+			return
+		}
+		skip := false
+		ast.Inspect(program, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Identifier); ok && unboundedBuiltins[ident.Value] {
+				skip = true
+			}
+			return !skip
+		})
+		if skip {
+			return
+		}
+
+		env := eval.NewEnvironment(eval.WithStdout(io.Discard), eval.WithStepLimit(10000))
+		env.Eval(program)
+	})
+}
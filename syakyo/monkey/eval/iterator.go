@@ -0,0 +1,76 @@
+package eval
+
+import (
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// errGeneratorClosed unwinds the evaluation of a closed generator.
+var errGeneratorClosed = object.NewError("generator is closed")
+
+// Generate returns a generator which evaluates body in e lazily. The yield
+// expressions in body suspend the evaluation.
+func (e *Environment) Generate(body *ast.BlockStatement) object.Object {
+	return object.NewGenerator(func(yield func(object.Object) bool) object.Object {
+		e.mu.Lock()
+		e.yield = yield
+		e.mu.Unlock()
+		return e.Eval(body)
+	})
+}
+
+func (e *Environment) evalYieldExpression(node *ast.YieldExpression) object.Object {
+	val := e.Eval(node.Value)
+	if isError(val) {
+		return val
+	}
+
+	// The nearest generator is the one running the function containing the
+	// expression, since a function containing yield is a generator.
+	for env := e; env != nil; env = env.outer {
+		env.mu.RLock()
+		yield := env.yield
+		env.mu.RUnlock()
+		if yield == nil {
+			continue
+		}
+		if !yield(val) {
+			return errGeneratorClosed
+		}
+		return object.Null
+	}
+	return object.NewError("yield outside generator")
+}
+
+func (e *Environment) evalForStatement(node *ast.ForStatement) object.Object {
+	iterable := e.Eval(node.Iterable)
+	if isError(iterable) {
+		return iterable
+	}
+	it, ok := object.Iterate(iterable)
+	if !ok {
+		return object.NewError("cannot iterate over %s", iterable.Type())
+	}
+	defer it.Close()
+
+	for {
+		val, ok := it.Next()
+		if !ok {
+			return nil
+		}
+		if isError(val) {
+			return val
+		}
+
+		// Each iteration has its own scope so that closures capture the
+		// value of the iteration.
+		env := e.NewEnclosedEnvironment().(*Environment)
+		if err := env.Destructure(node.Pattern, val); err != nil {
+			return err
+		}
+		result := env.Eval(node.Body)
+		if result != nil && (result.Type() == object.ReturnValueObjectType || result.Type() == object.ErrorObjectType) {
+			return result
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"runtime/metrics"
+	"sort"
+	"sync"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// MemProfile records the memory allocated during the calls of each
+// function, including the allocations by the functions called from it. The
+// allocations are counted by the Go runtime for the whole process, so the
+// numbers are approximate while tasks are running concurrently. The runtime
+// updates the counters as it hands out memory to the threads in chunks
+// rather than per object, so a single call may be charged for a chunk
+// allocated in it or for nothing; the totals of the functions called many
+// times are accurate on average.
+type MemProfile struct {
+	mu      sync.Mutex
+	entries map[*ast.BlockStatement]*memProfileEntry // keyed by the body of a function
+}
+
+type memProfileEntry struct {
+	name    string
+	calls   uint64
+	active  int // the number of the calls in progress
+	bytes   uint64
+	objects uint64
+}
+
+// NewMemProfile returns an empty profile.
+func NewMemProfile() *MemProfile {
+	return &MemProfile{entries: map[*ast.BlockStatement]*memProfileEntry{}}
+}
+
+// call calls apply, which calls fn, recording the allocations during the
+// call. The allocations during a recursive call are counted only once by
+// the outermost call.
+func (p *MemProfile) call(fn *object.Function, apply func() object.Object) object.Object {
+	p.mu.Lock()
+	entry, ok := p.entries[fn.Body]
+	if !ok {
+		entry = &memProfileEntry{name: functionName(fn)}
+		p.entries[fn.Body] = entry
+	}
+	entry.calls++
+	entry.active++
+	outermost := entry.active == 1
+	p.mu.Unlock()
+
+	// runtime/metrics is read instead of runtime.ReadMemStats, which stops
+	// the world and would slow down the calls being profiled.
+	var before, after [2]metrics.Sample
+	if outermost {
+		readAllocs(&before)
+	}
+	result := apply()
+	if outermost {
+		readAllocs(&after)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry.active--
+	if outermost {
+		entry.bytes += after[0].Value.Uint64() - before[0].Value.Uint64()
+		entry.objects += after[1].Value.Uint64() - before[1].Value.Uint64()
+	}
+	return result
+}
+
+// readAllocs reads the cumulative numbers of the allocated bytes and
+// objects into samples.
+func readAllocs(samples *[2]metrics.Sample) {
+	samples[0].Name = "/gc/heap/allocs:bytes"
+	samples[1].Name = "/gc/heap/allocs:objects"
+	metrics.Read(samples[:])
+}
+
+// Report writes the profile to w as a table of the functions sorted by the
+// allocated bytes.
+func (p *MemProfile) Report(w io.Writer) error {
+	p.mu.Lock()
+	entries := make([]*memProfileEntry, 0, len(p.entries))
+	for _, entry := range p.entries {
+		entries = append(entries, entry)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].bytes != entries[j].bytes {
+			return entries[i].bytes > entries[j].bytes
+		}
+		return entries[i].name < entries[j].name
+	})
+
+	if _, err := fmt.Fprintf(w, "%12s %12s %10s  %s\n", "bytes", "objects", "calls", "function"); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, err := fmt.Fprintf(w, "%12d %12d %10d  %s\n", entry.bytes, entry.objects, entry.calls, entry.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
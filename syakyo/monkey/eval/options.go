@@ -0,0 +1,120 @@
+package eval
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Capability is a set of permissions for scripts to access the outside of
+// the interpreter. Builtins requiring a capability which is not granted
+// return an error when called.
+type Capability uint
+
+const (
+	CapabilityFS      Capability = 1 << iota // read_file, write_file
+	CapabilityEnv                            // getenv, args
+	CapabilityProcess                        // exit
+	CapabilityTime                           // now, sleep
+
+	CapabilityNone Capability = 0
+	CapabilityAll             = CapabilityFS | CapabilityEnv | CapabilityProcess | CapabilityTime
+)
+
+// config is shared by an environment and all the environments enclosed by it.
+type config struct {
+	capabilities Capability
+	stdout       io.Writer
+	stdoutMu     sync.Mutex // serializes the writes by tasks
+	args         []string
+	exit         func(code int)
+	memProfile   *MemProfile
+	profile      *Profile
+	trace        io.Writer
+	optimize     bool
+	stepLimit    int64
+	byName       bool // see Environment.byName
+}
+
+// Option configures an environment created by NewEnvironment.
+type Option func(*config)
+
+// WithCapabilities grants caps to scripts. No capability is granted by default.
+func WithCapabilities(caps Capability) Option {
+	return func(c *config) {
+		c.capabilities = caps
+	}
+}
+
+// WithStdout sets the writer to which puts and print write. It is os.Stdout by default.
+func WithStdout(w io.Writer) Option {
+	return func(c *config) {
+		c.stdout = w
+	}
+}
+
+// WithArgs sets the arguments returned by args().
+func WithArgs(args []string) Option {
+	return func(c *config) {
+		c.args = args
+	}
+}
+
+// WithExit sets the function called by exit(). It is os.Exit by default.
+func WithExit(exit func(code int)) Option {
+	return func(c *config) {
+		c.exit = exit
+	}
+}
+
+// WithMemProfile records the memory allocated by the function calls in p.
+// It reads the allocation counters of the runtime twice per outermost call
+// of each function, which costs about a microsecond per call.
+func WithMemProfile(p *MemProfile) Option {
+	return func(c *config) {
+		c.memProfile = p
+	}
+}
+
+// WithProfile records the calls of the functions and their time in p.
+func WithProfile(p *Profile) Option {
+	return func(c *config) {
+		c.profile = p
+	}
+}
+
+// WithTrace writes each evaluated node with its result to w.
+func WithTrace(w io.Writer) Option {
+	return func(c *config) {
+		c.trace = w
+	}
+}
+
+// WithOptimization optimizes programs in place by the optimize package
+// before evaluating them. Programs are evaluated as written by default.
+func WithOptimization() Option {
+	return func(c *config) {
+		c.optimize = true
+	}
+}
+
+// WithStepLimit limits the number of the nodes evaluated and the functions
+// applied to n, after which the evaluation results in an error. There is no
+// limit by default.
+func WithStepLimit(n int64) Option {
+	return func(c *config) {
+		c.stepLimit = n
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		capabilities: CapabilityNone,
+		stdout:       os.Stdout,
+		exit:         os.Exit,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
@@ -0,0 +1,117 @@
+package eval
+
+import (
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+func (e *Environment) evalMatchExpression(node *ast.MatchExpression) object.Object {
+	subject := e.Eval(node.Subject)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		env := e.NewEnclosedEnvironment().(*Environment)
+		if env.matchPattern(arm.Pattern, subject) != nil {
+			continue
+		}
+		if arm.Guard != nil {
+			guard := env.Eval(arm.Guard)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return env.Eval(arm.Value)
+	}
+
+	return object.NewError("no match arm matches %s", subject.Inspect())
+}
+
+// Destructure binds the identifiers in pattern to the corresponding parts
+// of value in e. It returns an error if value does not match pattern.
+func (e *Environment) Destructure(pattern ast.Expression, value object.Object) *object.Error {
+	return e.matchPattern(pattern, value)
+}
+
+// matchPattern returns nil if value matches pattern, or an error describing
+// the mismatch. The identifiers in the pattern are bound to the matched
+// values in e, even if the match fails halfway.
+func (e *Environment) matchPattern(pattern ast.Expression, value object.Object) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			e.Set(pattern.Value, value)
+		}
+		return nil
+	case *ast.ArrayPattern:
+		arr, ok := value.(*object.Array)
+		if !ok {
+			return object.NewError("pattern %s does not match %s", pattern, value.Type())
+		}
+		if len(arr.Elements) < len(pattern.Elements) ||
+			pattern.Rest == nil && len(arr.Elements) != len(pattern.Elements) {
+			return object.NewError("pattern %s does not match an array of %d elements", pattern, len(arr.Elements))
+		}
+		for i, elem := range pattern.Elements {
+			if err := e.matchPattern(elem, arr.Elements[i]); err != nil {
+				return err
+			}
+		}
+		if pattern.Rest != nil {
+			rest := make([]object.Object, len(arr.Elements)-len(pattern.Elements))
+			copy(rest, arr.Elements[len(pattern.Elements):])
+			e.Set(pattern.Rest.Value, &object.Array{Elements: rest})
+		}
+		return nil
+	case *ast.HashPattern:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return object.NewError("pattern %s does not match %s", pattern, value.Type())
+		}
+		matched := map[object.HashKey]bool{}
+		for _, pair := range pattern.Pairs {
+			key, ok := e.Eval(pair.Key).(object.Hashable)
+			if !ok {
+				return object.NewError("unusable as hash key: %s", pair.Key)
+			}
+			v, ok := hash.Get(key)
+			if !ok {
+				return object.NewError("pattern %s does not match a hash without key %s", pattern, key.Inspect())
+			}
+			if err := e.matchPattern(pair.Value, v); err != nil {
+				return err
+			}
+			matched[key.HashKey()] = true
+		}
+		if pattern.Rest != nil {
+			rest := object.NewHash()
+			for _, key := range hash.Keys {
+				if !matched[key] {
+					pair := hash.Pairs[key]
+					rest.Set(pair.Key.(object.Hashable), pair.Value)
+				}
+			}
+			e.Set(pattern.Rest.Value, rest)
+		}
+		return nil
+	case *ast.Null:
+		if value != object.Null {
+			return object.NewError("pattern %s does not match %s", pattern, value.Inspect())
+		}
+		return nil
+	default:
+		// literal patterns
+		want, ok := e.Eval(pattern).(object.Hashable)
+		if !ok {
+			return object.NewError("invalid pattern %s", pattern)
+		}
+		if !object.Equal(value, want) {
+			return object.NewError("pattern %s does not match %s", pattern, value.Inspect())
+		}
+		return nil
+	}
+}
@@ -0,0 +1,128 @@
+package eval
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// WritePprof writes the profile to w in the gzip-compressed protocol buffer
+// format read by pprof. There is a sample for each call stack with the
+// number of the calls and the time spent in the innermost function. The
+// functions are located in filename at the lines of their bodies.
+func (p *Profile) WritePprof(w io.Writer, filename string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	indices := map[string]uint64{"": 0}
+	table := []string{""}
+	str := func(s string) uint64 {
+		i, ok := indices[s]
+		if !ok {
+			i = uint64(len(table))
+			indices[s] = i
+			table = append(table, s)
+		}
+		return i
+	}
+
+	var prof protobuf
+	for _, typ := range [][2]string{{"calls", "count"}, {"time", "nanoseconds"}} {
+		var vt protobuf
+		vt.uint64(1, str(typ[0]))
+		vt.uint64(2, str(typ[1]))
+		prof.message(1, &vt)
+	}
+
+	var walk func(node *callNode, stack []uint64)
+	walk = func(node *callNode, stack []uint64) {
+		for _, child := range node.children {
+			stack := append([]uint64{uint64(child.entry.id)}, stack...)
+			var sample protobuf
+			sample.packed(1, stack)
+			sample.packed(2, []uint64{child.calls, uint64(child.flat)})
+			prof.message(2, &sample)
+			walk(child, stack)
+		}
+	}
+	walk(p.root, nil)
+
+	filenameIndex := str(filename)
+	for _, entry := range p.entries {
+		var line, loc protobuf
+		line.uint64(1, uint64(entry.id))
+		line.uint64(2, uint64(entry.line))
+		loc.uint64(1, uint64(entry.id))
+		loc.message(4, &line)
+		prof.message(4, &loc)
+
+		var fn protobuf
+		fn.uint64(1, uint64(entry.id))
+		fn.uint64(2, str(entry.name))
+		fn.uint64(3, str(entry.name))
+		fn.uint64(4, filenameIndex)
+		fn.uint64(5, uint64(entry.line))
+		prof.message(5, &fn)
+	}
+
+	var period protobuf
+	period.uint64(1, str("time"))
+	period.uint64(2, str("nanoseconds"))
+	for _, s := range table {
+		prof.string(6, s)
+	}
+	prof.uint64(9, uint64(p.start.UnixNano()))
+	prof.uint64(10, uint64(time.Since(p.start)))
+	prof.message(11, &period)
+	prof.uint64(12, 1)
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(prof.Bytes()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// protobuf encodes a message of protocol buffers.
+type protobuf struct {
+	bytes.Buffer
+}
+
+func (b *protobuf) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	b.Write(buf[:n])
+}
+
+func (b *protobuf) key(field, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protobuf) uint64(field int, v uint64) {
+	b.key(field, 0)
+	b.varint(v)
+}
+
+func (b *protobuf) bytes(field int, data []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(data)))
+	b.Write(data)
+}
+
+func (b *protobuf) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *protobuf) message(field int, msg *protobuf) {
+	b.bytes(field, msg.Bytes())
+}
+
+func (b *protobuf) packed(field int, vs []uint64) {
+	var packed protobuf
+	for _, v := range vs {
+		packed.varint(v)
+	}
+	b.bytes(field, packed.Bytes())
+}
@@ -0,0 +1,166 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// Profile records the number of calls of each function and the time spent
+// in it, by call stack. The time is measured on the wall clock, so it
+// includes waiting, e.g. for a task, and the calls in a task are counted as
+// if they were made from the function spawning it.
+type Profile struct {
+	mu      sync.Mutex
+	start   time.Time
+	entries map[*ast.BlockStatement]*profileEntry // keyed by the body of a function
+	root    *callNode                             // the calls from the top level
+}
+
+type profileEntry struct {
+	id     int // from 1 in the order of the first calls
+	name   string
+	line   int
+	calls  uint64
+	active int           // the number of the calls in progress
+	flat   time.Duration // spent in the function itself
+	cum    time.Duration // spent in the function and the ones called from it
+}
+
+// callNode is a node of the call tree, whose paths from the root are the
+// distinct call stacks.
+type callNode struct {
+	entry    *profileEntry // nil for the root
+	children map[*profileEntry]*callNode
+	calls    uint64
+	flat     time.Duration
+}
+
+// frame is a call in progress, held by the environment in which the
+// function runs.
+type frame struct {
+	node  *callNode
+	child time.Duration // spent in the calls made from the frame; guarded by Profile.mu
+}
+
+// NewProfile returns an empty profile.
+func NewProfile() *Profile {
+	return &Profile{
+		start:   time.Now(),
+		entries: map[*ast.BlockStatement]*profileEntry{},
+		root:    &callNode{},
+	}
+}
+
+// functionName names fn in profiles by its name, or else the name bound to
+// it by a let statement, if any, and the position of its body.
+func functionName(fn *object.Function) string {
+	name := fn.Name
+	if name == "" {
+		name = fn.Binding
+	}
+	if name == "" {
+		name = "fn"
+	}
+	return fmt.Sprintf("%s (%s)", name, fn.Body.Pos())
+}
+
+// call calls fn with args from caller, recording the call. The time of a
+// recursive call is counted in the cumulative time only once by the
+// outermost call.
+func (p *Profile) call(caller *Environment, fn *object.Function, args []object.Object) object.Object {
+	if fn.Native != nil {
+		return object.ApplyFunction(fn, args)
+	}
+
+	var parent *frame
+	for env := caller; env != nil; env = env.outer {
+		if env.frame != nil {
+			parent = env.frame
+			break
+		}
+	}
+
+	p.mu.Lock()
+	entry, ok := p.entries[fn.Body]
+	if !ok {
+		entry = &profileEntry{id: len(p.entries) + 1, name: functionName(fn), line: fn.Body.Pos().Line}
+		p.entries[fn.Body] = entry
+	}
+	parentNode := p.root
+	if parent != nil {
+		parentNode = parent.node
+	}
+	node, ok := parentNode.children[entry]
+	if !ok {
+		node = &callNode{entry: entry}
+		if parentNode.children == nil {
+			parentNode.children = map[*profileEntry]*callNode{}
+		}
+		parentNode.children[entry] = node
+	}
+	entry.calls++
+	entry.active++
+	node.calls++
+	outermost := entry.active == 1
+	p.mu.Unlock()
+
+	env := fn.Env.NewEnclosedEnvironment().(*Environment)
+	f := &frame{node: node}
+	env.frame = f
+	start := time.Now()
+	result := fn.Call(env, args)
+	elapsed := time.Since(start)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry.active--
+	if outermost {
+		entry.cum += elapsed
+	}
+	flat := elapsed - f.child
+	if flat < 0 {
+		// the calls in tasks may take longer than the caller
+		flat = 0
+	}
+	entry.flat += flat
+	node.flat += flat
+	if parent != nil {
+		parent.child += elapsed
+	}
+	return result
+}
+
+// Report writes the profile to w as a table of the functions sorted by the
+// cumulative time.
+func (p *Profile) Report(w io.Writer) error {
+	p.mu.Lock()
+	entries := make([]profileEntry, 0, len(p.entries))
+	for _, entry := range p.entries {
+		entries = append(entries, *entry)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].cum != entries[j].cum {
+			return entries[i].cum > entries[j].cum
+		}
+		return entries[i].name < entries[j].name
+	})
+
+	if _, err := fmt.Fprintf(w, "%12s %12s %10s  %s\n", "flat", "cum", "calls", "function"); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		flat, cum := entry.flat.Round(time.Microsecond), entry.cum.Round(time.Microsecond)
+		if _, err := fmt.Fprintf(w, "%12s %12s %10d  %s\n", flat, cum, entry.calls, entry.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package eval
+
+import (
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// scope mirrors an environment created during the evaluation: the top-level
+// environment, a named function binding its name, a struct method binding
+// self, a function call, an if block, a match arm or a loop iteration.
+type scope struct {
+	outer   *scope
+	slots   map[string]int  // nil in the top-level scope, whose variables are looked up by name
+	globals map[string]bool // the names declared in the top-level scope
+	level   int             // the number of the outer scopes
+}
+
+// declare assigns the next slot to name unless it already has one. The
+// slots are assigned in the order the evaluation defines the variables.
+func (s *scope) declare(name string) {
+	if s.slots == nil {
+		s.globals[name] = true
+		return
+	}
+	if _, ok := s.slots[name]; !ok {
+		s.slots[name] = len(s.slots)
+	}
+}
+
+// global reports whether name is declared in the top-level scope.
+func (s *scope) global(name string) bool {
+	for s.outer != nil {
+		s = s.outer
+	}
+	return s.globals[name]
+}
+
+// closure is a function literal being resolved.
+type closure struct {
+	level    int // the level of the scope evaluating the literal
+	captures bool
+}
+
+// resolver computes where the variables referred to by identifiers live in
+// the environments at runtime, so that the evaluator need not look them up
+// by name through the environments. The variables of a scope are declared
+// before resolving its body, so an identifier referring to a variable which
+// is defined later in the scope resolves to it; the evaluator falls back on
+// looking up by name while the variable is not defined.
+//
+// An identifier outside function literals which refers to none of the
+// variables in the scopes, the variables already defined in the top-level
+// environment and the builtins is an error, reported before the evaluation.
+// One in a function literal is looked up when the function is called, since
+// the variable may be defined later by another program evaluated in the same
+// environment, e.g. the next line in the REPL.
+//
+// It also finds the function literals which refer to no local variables of
+// the enclosing scopes. Such a function need not keep the environment where
+// it is created, which would keep all the variables there from being garbage
+// collected as long as the function is alive. The capture is all or
+// nothing: a function referring to any local variable keeps the whole chain
+// of the enclosing environments, including the variables it does not use,
+// since the variables are shared with the enclosing scopes and may be
+// defined after the function is created.
+type resolver struct {
+	env      *Environment // the top-level environment
+	scope    *scope
+	closures []*closure
+	err      *object.Error // the first error found
+}
+
+// resolve fills in the Ref of the identifiers and the Isolated flag and the
+// Binding of the function literals in program, which is to be evaluated in env, a
+// top-level environment. It returns an error if program refers to an
+// undefined variable outside function literals.
+func resolve(program *ast.Program, env *Environment) *object.Error {
+	r := &resolver{
+		env:   env,
+		scope: &scope{globals: map[string]bool{}},
+	}
+	r.walkStatements(program.Statements)
+	return r.err
+}
+
+func (r *resolver) openScope() {
+	r.scope = &scope{
+		outer: r.scope,
+		slots: map[string]int{},
+		level: r.scope.level + 1,
+	}
+}
+
+func (r *resolver) closeScope() {
+	r.scope = r.scope.outer
+}
+
+// refer resolves ident referring to a variable.
+func (r *resolver) refer(ident *ast.Identifier) {
+	for s, depth := r.scope, 0; s != nil; s, depth = s.outer, depth+1 {
+		slot, ok := s.slots[ident.Value]
+		if !ok {
+			continue
+		}
+		ident.Ref = ast.Ref{Kind: ast.RefLocal, Depth: depth, Slot: slot}
+		for _, c := range r.closures {
+			if c.level >= s.level {
+				c.captures = true
+			}
+		}
+		return
+	}
+
+	ident.Ref = ast.Ref{Kind: ast.RefGlobal}
+	if r.err != nil || len(r.closures) > 0 || r.scope.global(ident.Value) {
+		return
+	}
+	if _, ok := r.env.lookup(ident.Value); ok {
+		return
+	}
+	if _, ok := r.env.builtins[ident.Value]; ok {
+		return
+	}
+	r.err = object.NewError("identifier not found: %s", ident.Value)
+}
+
+// declarePattern declares the identifiers in pattern except `_`.
+func (r *resolver) declarePattern(pattern ast.Expression) {
+	ast.Inspect(pattern, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok && ident.Value != "_" {
+			r.scope.declare(ident.Value)
+		}
+		return true
+	})
+}
+
+// walkStatements declares the variables defined by stmts and resolves them.
+func (r *resolver) walkStatements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			if stmt.Pattern != nil {
+				r.declarePattern(stmt.Pattern)
+			} else if stmt.Name != nil {
+				r.scope.declare(stmt.Name.Value)
+			}
+		case *ast.StructStatement:
+			r.scope.declare(stmt.Name.Value)
+		}
+	}
+	for _, stmt := range stmts {
+		r.walk(stmt)
+	}
+}
+
+func (r *resolver) walkBlock(block *ast.BlockStatement) {
+	if block != nil {
+		r.openScope()
+		r.walkStatements(block.Statements)
+		r.closeScope()
+	}
+}
+
+func (r *resolver) walk(node ast.Node) {
+	if node != nil {
+		ast.Walk(r, node)
+	}
+}
+
+// walkFunction resolves lit, which is a method of a struct if method is true.
+func (r *resolver) walkFunction(lit *ast.FunctionLiteral, method bool) {
+	c := &closure{level: r.scope.level}
+	r.closures = append(r.closures, c)
+	outer := r.scope
+
+	if lit.Name != nil {
+		r.openScope()
+		r.scope.declare(lit.Name.Value)
+	}
+	if method {
+		r.openScope()
+		r.scope.declare("self")
+	}
+
+	r.openScope()
+	for i, param := range lit.Parameters {
+		if i < len(lit.Defaults) && lit.Defaults[i] != nil {
+			r.walk(lit.Defaults[i])
+		}
+		if param == nil {
+			r.declarePattern(lit.Patterns[i])
+			continue
+		}
+		r.scope.declare(param.Value)
+	}
+	if lit.Rest != nil {
+		r.scope.declare(lit.Rest.Value)
+	}
+	if lit.Body != nil {
+		r.walkStatements(lit.Body.Statements)
+	}
+
+	r.scope = outer
+	r.closures = r.closures[:len(r.closures)-1]
+	lit.Isolated = !c.captures
+}
+
+func (r *resolver) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		r.refer(node)
+		return nil
+	case *ast.LetStatement:
+		// The names are declared by walkStatements.
+		if lit, ok := node.Value.(*ast.FunctionLiteral); ok && node.Name != nil {
+			lit.Binding = node.Name.Value
+		}
+		r.walk(node.Value)
+		return nil
+	case *ast.StructStatement:
+		for _, method := range node.Methods {
+			r.walkFunction(method, true)
+		}
+		return nil
+	case *ast.MemberExpression:
+		// The member is not a variable.
+		r.walk(node.Object)
+		return nil
+	case *ast.FunctionLiteral:
+		r.walkFunction(node, false)
+		return nil
+	case *ast.IfExpression:
+		r.walk(node.Condition)
+		r.walkBlock(node.Consequence)
+		r.walkBlock(node.Alternative)
+		return nil
+	case *ast.MatchExpression:
+		r.walk(node.Subject)
+		for _, arm := range node.Arms {
+			r.openScope()
+			r.declarePattern(arm.Pattern)
+			r.walk(arm.Guard)
+			r.walk(arm.Value)
+			r.closeScope()
+		}
+		return nil
+	case *ast.ForStatement:
+		r.walk(node.Iterable)
+		r.openScope()
+		r.declarePattern(node.Pattern)
+		r.walkStatements(node.Body.Statements)
+		r.closeScope()
+		return nil
+	default:
+		return r
+	}
+}
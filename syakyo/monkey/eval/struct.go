@@ -0,0 +1,68 @@
+package eval
+
+import (
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+func (e *Environment) evalStructStatement(node *ast.StructStatement) object.Object {
+	if e.isConst(node.Name.Value) {
+		return object.NewError("cannot reassign constant %s", node.Name.Value)
+	}
+
+	st := &object.StructType{
+		Name:    node.Name.Value,
+		Methods: make(map[string]*object.Function, len(node.Methods)),
+	}
+	for _, field := range node.Fields {
+		st.Fields = append(st.Fields, field.Value)
+	}
+	for _, method := range node.Methods {
+		st.Methods[method.Name.Value] = e.evalFunctionLiteral(method).(*object.Function)
+	}
+
+	e.Set(st.Name, st)
+	return nil
+}
+
+func (e *Environment) evalMemberExpression(node *ast.MemberExpression) object.Object {
+	obj := e.Eval(node.Object)
+	if isError(obj) {
+		return obj
+	}
+
+	s, ok := obj.(*object.Struct)
+	if !ok {
+		return object.NewError("cannot access member %s of %s", node.Member.Value, obj.Type())
+	}
+	member, ok := s.Member(node.Member.Value)
+	if !ok {
+		return object.NewError("%s has no member %s", s.StructType.Name, node.Member.Value)
+	}
+	return member
+}
+
+func (e *Environment) evalAssignExpression(node *ast.AssignExpression) object.Object {
+	target, ok := node.Target.(*ast.MemberExpression)
+	if !ok {
+		return object.NewError("cannot assign to %s", node.Target)
+	}
+
+	obj := e.Eval(target.Object)
+	if isError(obj) {
+		return obj
+	}
+	val := e.Eval(node.Value)
+	if isError(val) {
+		return val
+	}
+
+	s, ok := obj.(*object.Struct)
+	if !ok {
+		return object.NewError("cannot assign to member %s of %s", target.Member.Value, obj.Type())
+	}
+	if !s.SetField(target.Member.Value, val) {
+		return object.NewError("%s has no field %s", s.StructType.Name, target.Member.Value)
+	}
+	return val
+}
@@ -0,0 +1,60 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// maxTraceText is the maximum length of a node or a result in a trace.
+const maxTraceText = 60
+
+// tracer prints each evaluated node with its result, indented by the depth
+// of the evaluation. A node is printed after its children. The depth is
+// shared by the tasks, so the indentation is mixed up while they run.
+type tracer struct {
+	mu    sync.Mutex
+	w     io.Writer
+	depth int
+}
+
+func (t *tracer) eval(e *Environment, node ast.Node) object.Object {
+	t.mu.Lock()
+	t.depth++
+	t.mu.Unlock()
+
+	result := e.eval(node)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.depth--
+	if _, ok := node.(*ast.Program); ok {
+		return result
+	}
+	indent := strings.Repeat("  ", t.depth)
+	if result == nil {
+		fmt.Fprintf(t.w, "%s%s: %s\n", indent, node.Pos(), shorten(node.String()))
+	} else {
+		fmt.Fprintf(t.w, "%s%s: %s => %s\n", indent, node.Pos(), shorten(node.String()), shorten(result.Inspect()))
+	}
+	return result
+}
+
+// shorten collapses the white spaces in s and truncates it to maxTraceText
+// bytes, not splitting a multibyte char.
+func shorten(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > maxTraceText {
+		end := maxTraceText - 3
+		for end > 0 && !utf8.RuneStart(s[end]) {
+			end--
+		}
+		s = s[:end] + "..."
+	}
+	return s
+}
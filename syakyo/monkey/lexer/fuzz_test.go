@@ -0,0 +1,38 @@
+package lexer_test
+
+import (
+	"testing"
+
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/token"
+	"github.com/stretchr/testify/require"
+)
+
+func FuzzNextToken(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"let five = 5;\nlet add = fn(x, y) { x + y };",
+		`"a\"b\\c\nd" "x ${y + "}"} z" ` + "`raw ${s}`",
+		"!= == => ... . , : ; ( ) { } [ ] < > + - * /",
+		`"unterminated ${`,
+		"1 @ a # b",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := lexer.New(input)
+		var prev token.Position
+		// Each token consumes at least a byte.
+		for i := 0; i <= len(input)+1; i++ {
+			tok := l.NextToken()
+			if tok.Type == token.TypeEof {
+				return
+			}
+			require.True(t, prev.Line < tok.Pos.Line || prev.Line == tok.Pos.Line && prev.Column < tok.Pos.Column,
+				"position %s of %q does not follow %s", tok.Pos, tok.Literal, prev)
+			prev = tok.Pos
+		}
+		t.Fatal("the lexer does not reach the end of the input")
+	})
+}
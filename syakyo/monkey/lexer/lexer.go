@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/daichimukai/x/syakyo/monkey/token"
 )
 
@@ -9,11 +11,19 @@ type Lexer struct {
 	readPosition int  // position which we will read next
 	position     int  // position which we had read
 	ch           byte // char at `position`
+	line         int  // line of `ch`
+	column       int  // column of `ch`
 }
 
 // New returns a new lexer of `input`.
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewAt(input, token.Position{Line: 1, Column: 1})
+}
+
+// NewAt returns a new lexer of `input` which starts at pos of a source code.
+// It is used to lex a part of a source code such as an interpolation.
+func NewAt(input string, pos token.Position) *Lexer {
+	l := &Lexer{input: input, line: pos.Line, column: pos.Column - 1}
 	l.readChar()
 	return l
 }
@@ -25,6 +35,13 @@ func (l *Lexer) skipWhitespaces() {
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -41,9 +58,23 @@ func (l *Lexer) peekChar() byte {
 	return l.input[l.readPosition]
 }
 
+// peekString returns at most n bytes of the input starting from the current char.
+func (l *Lexer) peekString(n int) string {
+	end := l.position + n
+	if end > len(l.input) {
+		end = len(l.input)
+	}
+	return l.input[l.position:end]
+}
+
+var threeByteTokens map[string]token.TokenType = map[string]token.TokenType{
+	"...": token.TypeEllipsis,
+}
+
 var twoByteTokens map[string]token.TokenType = map[string]token.TokenType{
 	"==": token.TypeEq,
 	"!=": token.TypeNotEq,
+	"=>": token.TypeArrow,
 }
 
 var byteToTokenTypeMap map[byte]token.TokenType = map[byte]token.TokenType{
@@ -62,18 +93,27 @@ var byteToTokenTypeMap map[byte]token.TokenType = map[byte]token.TokenType{
 	'[': token.TypeLeftBraket,
 	']': token.TypeRightBraket,
 	',': token.TypeComma,
+	':': token.TypeColon,
 	';': token.TypeSemicolon,
+	'.': token.TypeDot,
 }
 
 // NextToken returns the next token from the input.
 func (l *Lexer) NextToken() token.Token {
 	l.skipWhitespaces()
 
+	pos := token.Position{Line: l.line, Column: l.column}
 	var typ token.TokenType
 	var literal string
 	if l.ch == 0 {
 		literal = ""
 		typ = token.TypeEof
+	} else if v, ok := threeByteTokens[l.peekString(3)]; ok {
+		literal = l.peekString(3)
+		typ = v
+		l.readChar()
+		l.readChar()
+		l.readChar()
 	} else if v, ok := twoByteTokens[string(l.ch)+string(l.peekChar())]; ok {
 		literal = string(l.ch) + string(l.peekChar())
 		typ = v
@@ -84,7 +124,9 @@ func (l *Lexer) NextToken() token.Token {
 		typ = v
 		l.readChar()
 	} else if l.ch == '"' {
-		literal = l.readString()
+		literal, typ = l.readString()
+	} else if l.ch == '`' {
+		literal = l.readRawString()
 		typ = token.TypeString
 	} else if isLetter(l.ch) {
 		literal = l.readIdentifier()
@@ -92,11 +134,16 @@ func (l *Lexer) NextToken() token.Token {
 	} else if isDigit(l.ch) {
 		literal = l.readNumber()
 		typ = token.TypeInt
+	} else {
+		literal = string(l.ch)
+		typ = token.TypeIllegal
+		l.readChar()
 	}
 
 	return token.Token{
 		Type:    typ,
 		Literal: literal,
+		Pos:     pos,
 	}
 }
 
@@ -108,16 +155,120 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1
+var escapes = map[byte]byte{
+	'"':  '"',
+	'\\': '\\',
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'$':  '$',
+}
+
+// Unescape replaces the escape sequences in s with the chars they denote.
+// A backslash not followed by a known escape char is left as is.
+func Unescape(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			if ch, ok := escapes[s[i+1]]; ok {
+				out.WriteByte(ch)
+				i++
+				continue
+			}
+		}
+		out.WriteByte(s[i])
+	}
+	return out.String()
+}
+
+// readString reads a string literal. If the literal contains interpolations
+// like ${expr}, it returns the source between the quotes as is with
+// TypeTemplate. Otherwise it returns the value of the literal, in which
+// escape sequences are replaced, with TypeString.
+func (l *Lexer) readString() (string, token.TokenType) {
+	start := l.position + 1
+	interpolated := l.skipString()
+	end := l.position
+	if end > len(l.input) {
+		end = len(l.input)
+	}
+	l.readChar()
+
+	if interpolated {
+		return l.input[start:end], token.TypeTemplate
+	}
+	return Unescape(l.input[start:end]), token.TypeString
+}
+
+// skipString advances the lexer from the opening quote of a string literal
+// to the closing one, and reports whether the literal has interpolations.
+func (l *Lexer) skipString() bool {
+	interpolated := false
+	for {
+		l.readChar()
+		switch {
+		case l.ch == '"' || l.ch == 0:
+			return interpolated
+		case l.ch == '\\':
+			l.readChar()
+			if l.ch == 0 {
+				return interpolated
+			}
+		case l.ch == '$' && l.peekChar() == '{':
+			interpolated = true
+			l.readChar()
+			l.skipInterpolation()
+			if l.ch == 0 {
+				return interpolated
+			}
+		}
+	}
+}
+
+// skipInterpolation advances the lexer from the opening brace of an
+// interpolation to the matching closing one, skipping nested literals.
+func (l *Lexer) skipInterpolation() {
+	depth := 1
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		switch l.ch {
+		case 0:
+			return
+		case '"':
+			l.skipString()
+		case '`':
+			l.skipRawString()
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return
+			}
 		}
 	}
+}
+
+// readRawString reads a raw string literal quoted by backquotes, which may
+// span multiple lines and has neither escapes nor interpolations.
+func (l *Lexer) readRawString() string {
+	start := l.position + 1
+	l.skipRawString()
+	end := l.position
+	if end > len(l.input) {
+		end = len(l.input)
+	}
 	l.readChar()
-	return l.input[position : l.position-1]
+	return l.input[start:end]
+}
+
+func (l *Lexer) skipRawString() {
+	for {
+		l.readChar()
+		if l.ch == '`' || l.ch == 0 {
+			return
+		}
+	}
 }
 
 func isLetter(ch byte) bool {
@@ -14,35 +14,47 @@ func TestNextToken_SingleToken(t *testing.T) {
 		expectedType    token.TokenType
 		expectedLiteral string
 	}{
-		"eof":          {"", token.TypeEof, ""},
-		"ident":        {"foo", token.TypeIdent, "foo"},
-		"int":          {"0", token.TypeInt, "0"},
-		"string":       {`"foo"`, token.TypeString, `foo`},
-		"assign":       {"=", token.TypeAssign, "="},
-		"plus":         {"+", token.TypePlus, "+"},
-		"minus":        {"-", token.TypeMinus, "-"},
-		"bang":         {"!", token.TypeBang, "!"},
-		"asterisk":     {"*", token.TypeAsterisk, "*"},
-		"slash":        {"/", token.TypeSlash, "/"},
-		"less than":    {"<", token.TypeLt, "<"},
-		"greater than": {">", token.TypeGt, ">"},
-		"equal":        {"==", token.TypeEq, "=="},
-		"not equal":    {"!=", token.TypeNotEq, "!="},
-		"comma":        {",", token.TypeComma, ","},
-		"semicolon":    {";", token.TypeSemicolon, ";"},
-		"left paren":   {"(", token.TypeLeftParen, "("},
-		"right paren":  {")", token.TypeRightParen, ")"},
-		"left brace":   {"{", token.TypeLeftBrace, "{"},
-		"right brace":  {"}", token.TypeRightBrace, "}"},
-		"left braket":  {"[", token.TypeLeftBraket, "["},
-		"right braket": {"]", token.TypeRightBraket, "]"},
-		"function":     {"fn", token.TypeFunction, "fn"},
-		"let":          {"let", token.TypeLet, "let"},
-		"true":         {"true", token.TypeTrue, "true"},
-		"false":        {"false", token.TypeFalse, "false"},
-		"if":           {"if", token.TypeIf, "if"},
-		"else":         {"else", token.TypeElse, "else"},
-		"return":       {"return", token.TypeReturn, "return"},
+		"eof":                {"", token.TypeEof, ""},
+		"ident":              {"foo", token.TypeIdent, "foo"},
+		"int":                {"0", token.TypeInt, "0"},
+		"string":             {`"foo"`, token.TypeString, `foo`},
+		"escape":             {`"a\"b\\c\nd\te\q"`, token.TypeString, "a\"b\\c\nd\te\\q"},
+		"unknown escape":     {`"\q\x41"`, token.TypeString, `\q\x41`},
+		"escaped backslash":  {`"a\\"`, token.TypeString, `a\`},
+		"trailing backslash": {`"a\`, token.TypeString, `a\`},
+		"escaped dollar":     {`"\${a}"`, token.TypeString, "${a}"},
+		"template":           {`"a ${b + "}"} c"`, token.TypeTemplate, `a ${b + "}"} c`},
+		"raw string":         {"`a\\n\n${b}`", token.TypeString, "a\\n\n${b}"},
+		"assign":             {"=", token.TypeAssign, "="},
+		"plus":               {"+", token.TypePlus, "+"},
+		"minus":              {"-", token.TypeMinus, "-"},
+		"bang":               {"!", token.TypeBang, "!"},
+		"asterisk":           {"*", token.TypeAsterisk, "*"},
+		"slash":              {"/", token.TypeSlash, "/"},
+		"less than":          {"<", token.TypeLt, "<"},
+		"greater than":       {">", token.TypeGt, ">"},
+		"equal":              {"==", token.TypeEq, "=="},
+		"not equal":          {"!=", token.TypeNotEq, "!="},
+		"comma":              {",", token.TypeComma, ","},
+		"colon":              {":", token.TypeColon, ":"},
+		"semicolon":          {";", token.TypeSemicolon, ";"},
+		"left paren":         {"(", token.TypeLeftParen, "("},
+		"right paren":        {")", token.TypeRightParen, ")"},
+		"left brace":         {"{", token.TypeLeftBrace, "{"},
+		"right brace":        {"}", token.TypeRightBrace, "}"},
+		"left braket":        {"[", token.TypeLeftBraket, "["},
+		"right braket":       {"]", token.TypeRightBraket, "]"},
+		"ellipsis":           {"...", token.TypeEllipsis, "..."},
+		"dot":                {".", token.TypeDot, "."},
+		"illegal":            {"@", token.TypeIllegal, "@"},
+		"function":           {"fn", token.TypeFunction, "fn"},
+		"let":                {"let", token.TypeLet, "let"},
+		"true":               {"true", token.TypeTrue, "true"},
+		"false":              {"false", token.TypeFalse, "false"},
+		"null":               {"null", token.TypeNull, "null"},
+		"if":                 {"if", token.TypeIf, "if"},
+		"else":               {"else", token.TypeElse, "else"},
+		"return":             {"return", token.TypeReturn, "return"},
 	}
 
 	for name, tt := range testCases {
@@ -62,7 +74,7 @@ func TestNextToken_SingleToken(t *testing.T) {
 func TestNextToken_Whitespace(t *testing.T) {
 	testCases := map[string]string{
 		"space": ` `,
-		"tab": `	`,
+		"tab":   `	`,
 		"newline": `
 `,
 	}
@@ -143,3 +155,43 @@ let str = "foo";
 		require.Equalf(t, tt.expectedTokenType, tok.Type, "type differs for the token at %d", i)
 	}
 }
+
+func TestNextToken_Position(t *testing.T) {
+	input := `let x = 5;
+  x + "a b";
+`
+
+	expected := []token.Position{
+		{Line: 1, Column: 1},
+		{Line: 1, Column: 5},
+		{Line: 1, Column: 7},
+		{Line: 1, Column: 9},
+		{Line: 1, Column: 10},
+		{Line: 2, Column: 3},
+		{Line: 2, Column: 5},
+		{Line: 2, Column: 7},
+		{Line: 2, Column: 12},
+		{Line: 3, Column: 1},
+	}
+
+	l := lexer.New(input)
+	for i, pos := range expected {
+		tok := l.NextToken()
+		require.Equalf(t, pos, tok.Pos, "position differs for the token at %d", i)
+	}
+}
+
+func TestSplitTemplate(t *testing.T) {
+	input := "\"a\\${ ${b + {1: 2}[1]}\n${c}\""
+
+	tok := lexer.New(input).NextToken()
+	require.Equal(t, token.TypeTemplate, tok.Type)
+
+	parts := lexer.SplitTemplate(tok.Literal, tok.Pos)
+	require.Equal(t, []lexer.TemplatePart{
+		{Value: "a${ ", Pos: token.Position{Line: 1, Column: 2}},
+		{Interpolation: true, Value: "b + {1: 2}[1]", Pos: token.Position{Line: 1, Column: 9}},
+		{Value: "\n", Pos: token.Position{Line: 1, Column: 23}},
+		{Interpolation: true, Value: "c", Pos: token.Position{Line: 2, Column: 3}},
+	}, parts)
+}
@@ -0,0 +1,53 @@
+package lexer
+
+import "github.com/daichimukai/x/syakyo/monkey/token"
+
+// TemplatePart is a part of a string literal with interpolations.
+type TemplatePart struct {
+	Interpolation bool           // whether the part is the source of an embedded expression
+	Value         string         // the text with escapes replaced, or the source of the expression
+	Pos           token.Position // position of Value in the source code
+}
+
+// SplitTemplate splits the literal of a TypeTemplate token which starts at
+// pos into texts and embedded expressions. It returns nil if an embedded
+// expression is not terminated.
+func SplitTemplate(literal string, pos token.Position) []TemplatePart {
+	// Lex the literal as if it is quoted so that positions are correct.
+	l := NewAt(`"`+literal+`"`, pos)
+
+	var parts []TemplatePart
+	textStart, textPos := 1, l.nextPosition()
+	for {
+		l.readChar()
+		switch {
+		case l.ch == 0 || l.position == len(l.input)-1:
+			if textStart < l.position {
+				parts = append(parts, TemplatePart{Value: Unescape(l.input[textStart:l.position]), Pos: textPos})
+			}
+			return parts
+		case l.ch == '\\':
+			l.readChar()
+		case l.ch == '$' && l.peekChar() == '{':
+			if textStart < l.position {
+				parts = append(parts, TemplatePart{Value: Unescape(l.input[textStart:l.position]), Pos: textPos})
+			}
+			l.readChar()
+			exprStart, exprPos := l.position+1, l.nextPosition()
+			l.skipInterpolation()
+			if l.ch == 0 {
+				return nil
+			}
+			parts = append(parts, TemplatePart{Interpolation: true, Value: l.input[exprStart:l.position], Pos: exprPos})
+			textStart, textPos = l.position+1, l.nextPosition()
+		}
+	}
+}
+
+// nextPosition returns the position of the char following the current one.
+func (l *Lexer) nextPosition() token.Position {
+	if l.ch == '\n' {
+		return token.Position{Line: l.line + 1, Column: 1}
+	}
+	return token.Position{Line: l.line, Column: l.column + 1}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/daichimukai/x/syakyo/monkey/eval"
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/lint"
+	"github.com/daichimukai/x/syakyo/monkey/parser"
+)
+
+type lintResult struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// lintMain implements `monkey lint [flags] file...`. It exits with 1 if
+// any problem is found.
+func lintMain(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print diagnostics in JSON")
+	enable := flags.String("enable", "", "comma-separated list of rules to run (default: all)")
+	disable := flags.String("disable", "", "comma-separated list of rules not to run")
+	list := flags.Bool("list", false, "list the available rules")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	available := lint.Rules(eval.BuiltinNames())
+	if *list {
+		for _, rule := range available {
+			fmt.Printf("%s\t%s\n", rule.Name, rule.Doc)
+		}
+		return 0
+	}
+
+	rules, err := lint.Select(available, splitList(*enable), splitList(*disable))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	results := []lintResult{}
+	for _, filename := range flags.Args() {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		program, err := parser.New(lexer.New(string(src))).ParseProgram()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: parse error: %s\n", filename, err)
+			return 2
+		}
+		for _, diag := range lint.Lint(program, rules) {
+			results = append(results, lintResult{
+				File:    filename,
+				Line:    diag.Pos.Line,
+				Column:  diag.Pos.Column,
+				Rule:    diag.Rule,
+				Message: diag.Message,
+			})
+		}
+	}
+
+	if *asJSON {
+		if err := writeLintJSON(os.Stdout, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	} else {
+		for _, r := range results {
+			fmt.Printf("%s:%d:%d: %s (%s)\n", r.File, r.Line, r.Column, r.Message, r.Rule)
+		}
+	}
+
+	if len(results) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func writeLintJSON(w io.Writer, results []lintResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// splitList splits a comma-separated list, ignoring empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, elem := range strings.Split(s, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			list = append(list, elem)
+		}
+	}
+	return list
+}
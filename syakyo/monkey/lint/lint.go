@@ -0,0 +1,96 @@
+// Package lint implements a linter which reports common mistakes in Monkey programs.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/token"
+)
+
+// Diagnostic is a problem found by a rule.
+type Diagnostic struct {
+	Pos     token.Position
+	Rule    string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Pos, d.Message, d.Rule)
+}
+
+// Rule checks a program for a kind of mistakes.
+type Rule struct {
+	Name  string // identifies the rule, e.g. in command line flags
+	Doc   string // one-line description of the rule
+	Check func(program *ast.Program) []Diagnostic
+}
+
+// Rules returns the list of the available rules. builtins are the names of
+// the builtin functions of the interpreter running the programs.
+func Rules(builtins []string) []*Rule {
+	return []*Rule{
+		UnusedLet,
+		ShadowedParam,
+		UnreachableCode,
+		TypeMismatch,
+		ShadowedBuiltin(builtins),
+	}
+}
+
+// Select returns the rules to run. If enable is not empty, only the rules
+// named in it are selected; the rules named in disable are excluded.
+// It returns an error if an unknown rule is named.
+func Select(rules []*Rule, enable, disable []string) ([]*Rule, error) {
+	byName := map[string]*Rule{}
+	for _, rule := range rules {
+		byName[rule.Name] = rule
+	}
+	for _, name := range append(append([]string{}, enable...), disable...) {
+		if _, ok := byName[name]; !ok {
+			return nil, fmt.Errorf("unknown rule: %s", name)
+		}
+	}
+
+	enabled := map[string]bool{}
+	for _, name := range enable {
+		enabled[name] = true
+	}
+	disabled := map[string]bool{}
+	for _, name := range disable {
+		disabled[name] = true
+	}
+
+	var selected []*Rule
+	for _, rule := range rules {
+		if len(enable) > 0 && !enabled[rule.Name] {
+			continue
+		}
+		if disabled[rule.Name] {
+			continue
+		}
+		selected = append(selected, rule)
+	}
+	return selected, nil
+}
+
+// Lint runs rules against program and returns the diagnostics sorted by position.
+func Lint(program *ast.Program, rules []*Rule) []Diagnostic {
+	var diags []Diagnostic
+	for _, rule := range rules {
+		for _, diag := range rule.Check(program) {
+			diag.Rule = rule.Name
+			diags = append(diags, diag)
+		}
+	}
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Pos, diags[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diags
+}
@@ -0,0 +1,272 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/lint"
+	"github.com/daichimukai/x/syakyo/monkey/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func testLint(t *testing.T, input string, rule *lint.Rule) []string {
+	t.Helper()
+
+	program, err := parser.New(lexer.New(input)).ParseProgram()
+	require.NoError(t, err)
+
+	var diags []string
+	for _, diag := range lint.Lint(program, []*lint.Rule{rule}) {
+		require.Equal(t, rule.Name, diag.Rule)
+		diags = append(diags, diag.Pos.String()+": "+diag.Message)
+	}
+	return diags
+}
+
+func TestUnusedLet(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect []string
+	}{
+		{
+			input:  `let x = 1;`,
+			expect: []string{"1:5: x is declared but never used"},
+		},
+		{
+			input: `let x = 1; x;`,
+		},
+		{
+			input: `let _x = 1;`,
+		},
+		{
+			input: `let x = 1; let f = fn() { x }; f();`,
+		},
+		{
+			input:  `let f = fn(x) { let y = x; x };`,
+			expect: []string{"1:5: f is declared but never used", "1:21: y is declared but never used"},
+		},
+		{
+			input: `let f = fn(n) { if (n < 1) { 0 } else { f(n - 1) } }; f(1);`,
+		},
+		{
+			input:  `let x = 1; let x = x + 1; x;`,
+			expect: nil,
+		},
+		{
+			input:  `let x = 1; let x = 2; x;`,
+			expect: []string{"1:5: x is declared but never used"},
+		},
+		{
+			input:  `let x = 1; match (2) { [x] => x, x => x }`,
+			expect: []string{"1:5: x is declared but never used"},
+		},
+		{
+			input: `let x = 1; match (2) { y if y > x => y }`,
+		},
+		{
+			input:  `let x = 1; if (true) { let x = 2; x }`,
+			expect: []string{"1:5: x is declared but never used"},
+		},
+		{
+			input:  `let x = 1; let p = q; p.x`,
+			expect: []string{"1:5: x is declared but never used"},
+		},
+		{
+			input: `let k = 2; struct P { x, fn f() { self.x * k } }`,
+		},
+		{
+			input: `for (x in xs) { 1 }`,
+		},
+		{
+			input:  `let x = 1; for (x in xs) { x }`,
+			expect: []string{"1:5: x is declared but never used"},
+		},
+		{
+			input:  `const x = 1;`,
+			expect: []string{"1:7: x is declared but never used"},
+		},
+		{
+			input:  `let [a, {b}] = c; a;`,
+			expect: []string{"1:10: b is declared but never used"},
+		},
+		{
+			input: `let f = fn([a, b]) { a }; f([1, 2]);`,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expect, testLint(t, tt.input, lint.UnusedLet))
+		})
+	}
+}
+
+func TestShadowedParam(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect []string
+	}{
+		{
+			input: `let f = fn(x) { x };`,
+		},
+		{
+			input:  `let x = 1; let f = fn(x) { x };`,
+			expect: []string{"1:23: parameter x shadows the binding declared at 1:5"},
+		},
+		{
+			input:  `let f = fn(x) { let x = 2; x };`,
+			expect: []string{"1:21: x shadows the parameter declared at 1:12"},
+		},
+		{
+			input:  `fn(x) { fn(...x) { x } }`,
+			expect: []string{"1:15: parameter x shadows the binding declared at 1:4"},
+		},
+		{
+			input: `let fact = fn fact(n) { fact(n) };`,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expect, testLint(t, tt.input, lint.ShadowedParam))
+		})
+	}
+}
+
+func TestUnreachableCode(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect []string
+	}{
+		{
+			input: `return 1;`,
+		},
+		{
+			input:  `return 1; 2; 3;`,
+			expect: []string{"1:11: unreachable code"},
+		},
+		{
+			input:  "fn() {\n  return 1;\n  2\n}",
+			expect: []string{"3:3: unreachable code"},
+		},
+		{
+			input: `if (true) { return 1; } 2;`,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expect, testLint(t, tt.input, lint.UnreachableCode))
+		})
+	}
+}
+
+func TestTypeMismatch(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect []string
+	}{
+		{
+			input: `1 == 2`,
+		},
+		{
+			input: `x == "a"`,
+		},
+		{
+			input:  `1 == "a"`,
+			expect: []string{"1:3: comparison of INTEGER and STRING is always false"},
+		},
+		{
+			input:  `-x != true`,
+			expect: []string{"1:4: comparison of INTEGER and BOOLEAN is always true"},
+		},
+		{
+			input:  `[] == null`,
+			expect: []string{"1:4: comparison of ARRAY and NULL is always false"},
+		},
+		{
+			input:  `"a" + "b" < 1 + 2`,
+			expect: []string{"1:11: comparison of STRING and INTEGER always fails"},
+		},
+		{
+			input:  `(1 < 2) == [1]`,
+			expect: []string{"1:9: comparison of BOOLEAN and ARRAY is always false"},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expect, testLint(t, tt.input, lint.TypeMismatch))
+		})
+	}
+}
+
+func TestShadowedBuiltin(t *testing.T) {
+	builtins := []string{"filter", "find", "len", "map", "puts"}
+	testcases := []struct {
+		input  string
+		expect []string
+	}{
+		{
+			input: `let length = len("a");`,
+		},
+		{
+			input:  `let len = 1;`,
+			expect: []string{"1:5: len shadows the builtin function"},
+		},
+		{
+			input:  `let f = fn(map, ...puts) { fn filter() {} };`,
+			expect: []string{"1:12: map shadows the builtin function", "1:20: puts shadows the builtin function", "1:31: filter shadows the builtin function"},
+		},
+		{
+			input:  `const [first, {find}] = x;`,
+			expect: []string{"1:16: find shadows the builtin function"},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expect, testLint(t, tt.input, lint.ShadowedBuiltin(builtins)))
+		})
+	}
+}
+
+func TestLintSortsDiagnostics(t *testing.T) {
+	program, err := parser.New(lexer.New("let x = 1;\nreturn 1 == \"a\";\nx;")).ParseProgram()
+	require.NoError(t, err)
+
+	var rules []string
+	for _, diag := range lint.Lint(program, lint.Rules(nil)) {
+		rules = append(rules, diag.String())
+	}
+	require.Equal(t, []string{
+		`2:10: comparison of INTEGER and STRING is always false (type-mismatch)`,
+		`3:1: unreachable code (unreachable-code)`,
+	}, rules)
+}
+
+func TestSelect(t *testing.T) {
+	names := func(rules []*lint.Rule) []string {
+		var names []string
+		for _, rule := range rules {
+			names = append(names, rule.Name)
+		}
+		return names
+	}
+
+	available := lint.Rules(nil)
+	rules, err := lint.Select(available, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, names(available), names(rules))
+
+	rules, err = lint.Select(available, []string{"type-mismatch", "unused-let"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"unused-let", "type-mismatch"}, names(rules))
+
+	rules, err = lint.Select(available, nil, []string{"unused-let"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"shadowed-param", "unreachable-code", "type-mismatch", "shadowed-builtin"}, names(rules))
+
+	_, err = lint.Select(available, nil, []string{"no-such-rule"})
+	require.Error(t, err)
+}
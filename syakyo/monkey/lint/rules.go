@@ -0,0 +1,200 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// UnusedLet reports let bindings which are never referred to. Bindings
+// whose names start with an underscore are ignored.
+var UnusedLet = &Rule{
+	Name: "unused-let",
+	Doc:  "reports let bindings which are never used",
+	Check: func(program *ast.Program) []Diagnostic {
+		var diags []Diagnostic
+		r := &resolver{
+			close: func(s *scope) {
+				for _, b := range s.order {
+					if b.kind != letBinding || b.used || strings.HasPrefix(b.name, "_") {
+						continue
+					}
+					diags = append(diags, Diagnostic{
+						Pos:     b.pos,
+						Message: fmt.Sprintf("%s is declared but never used", b.name),
+					})
+				}
+			},
+		}
+		r.resolve(program)
+		return diags
+	},
+}
+
+// ShadowedParam reports parameters which shadow bindings of outer scopes,
+// and let bindings which shadow parameters.
+var ShadowedParam = &Rule{
+	Name: "shadowed-param",
+	Doc:  "reports parameters shadowing or shadowed by other bindings",
+	Check: func(program *ast.Program) []Diagnostic {
+		var diags []Diagnostic
+		r := &resolver{
+			declare: func(s *scope, b *binding) {
+				switch b.kind {
+				case paramBinding:
+					if outer := s.outer.lookup(b.name); outer != nil {
+						diags = append(diags, Diagnostic{
+							Pos:     b.pos,
+							Message: fmt.Sprintf("parameter %s shadows the binding declared at %s", b.name, outer.pos),
+						})
+					}
+				case letBinding:
+					if prev, ok := s.bindings[b.name]; ok && prev.kind == paramBinding {
+						diags = append(diags, Diagnostic{
+							Pos:     b.pos,
+							Message: fmt.Sprintf("%s shadows the parameter declared at %s", b.name, prev.pos),
+						})
+					}
+				}
+			},
+		}
+		r.resolve(program)
+		return diags
+	},
+}
+
+// UnreachableCode reports statements following a return statement in the same block.
+var UnreachableCode = &Rule{
+	Name: "unreachable-code",
+	Doc:  "reports statements after return",
+	Check: func(program *ast.Program) []Diagnostic {
+		var diags []Diagnostic
+		check := func(stmts []ast.Statement) {
+			for i, stmt := range stmts {
+				if _, ok := stmt.(*ast.ReturnStatement); ok && i+1 < len(stmts) && stmts[i+1] != nil {
+					diags = append(diags, Diagnostic{
+						Pos:     stmts[i+1].Pos(),
+						Message: "unreachable code",
+					})
+					return
+				}
+			}
+		}
+		ast.Inspect(program, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.Program:
+				check(node.Statements)
+			case *ast.BlockStatement:
+				check(node.Statements)
+			}
+			return true
+		})
+		return diags
+	},
+}
+
+// TypeMismatch reports comparisons between operands whose types are known
+// to be different. Such values are never equal, and ordering them fails at
+// runtime.
+var TypeMismatch = &Rule{
+	Name: "type-mismatch",
+	Doc:  "reports comparisons between values of different types",
+	Check: func(program *ast.Program) []Diagnostic {
+		var diags []Diagnostic
+		ast.Inspect(program, func(node ast.Node) bool {
+			infix, ok := node.(*ast.InfixExpression)
+			if !ok {
+				return true
+			}
+			switch infix.Operator {
+			case "==", "!=", "<", ">":
+			default:
+				return true
+			}
+
+			left, lok := staticType(infix.Left)
+			right, rok := staticType(infix.Right)
+			if lok && rok && left != right {
+				result := "always fails"
+				if infix.Operator == "==" || infix.Operator == "!=" {
+					result = fmt.Sprintf("is always %t", infix.Operator == "!=")
+				}
+				diags = append(diags, Diagnostic{
+					Pos:     infix.Pos(),
+					Message: fmt.Sprintf("comparison of %s and %s %s", left, right, result),
+				})
+			}
+			return true
+		})
+		return diags
+	},
+}
+
+// ShadowedBuiltin returns a rule which reports bindings shadowing the
+// builtin functions named in names, making them inaccessible in the scope.
+func ShadowedBuiltin(names []string) *Rule {
+	builtins := map[string]bool{}
+	for _, name := range names {
+		builtins[name] = true
+	}
+	return &Rule{
+		Name: "shadowed-builtin",
+		Doc:  "reports bindings shadowing builtin functions",
+		Check: func(program *ast.Program) []Diagnostic {
+			var diags []Diagnostic
+			r := &resolver{
+				declare: func(s *scope, b *binding) {
+					if builtins[b.name] {
+						diags = append(diags, Diagnostic{
+							Pos:     b.pos,
+							Message: fmt.Sprintf("%s shadows the builtin function", b.name),
+						})
+					}
+				},
+			}
+			r.resolve(program)
+			return diags
+		},
+	}
+}
+
+// staticType returns the type of the value of expr if it is known without
+// evaluating the program.
+func staticType(expr ast.Expression) (object.ObjectType, bool) {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return object.IntegerObjectType, true
+	case *ast.StringLiteral, *ast.InterpolatedString:
+		return object.StringObjectType, true
+	case *ast.Boolean:
+		return object.BooleanObjectType, true
+	case *ast.Null:
+		return object.NullObjectType, true
+	case *ast.ArrayLiteral:
+		return object.ArrayObjectType, true
+	case *ast.HashLiteral:
+		return object.HashObjectType, true
+	case *ast.FunctionLiteral:
+		return object.FunctionObjectType, true
+	case *ast.PrefixExpression:
+		switch expr.Operator {
+		case "!":
+			return object.BooleanObjectType, true
+		case "-":
+			return object.IntegerObjectType, true
+		}
+	case *ast.InfixExpression:
+		switch expr.Operator {
+		case "==", "!=", "<", ">":
+			return object.BooleanObjectType, true
+		}
+		left, lok := staticType(expr.Left)
+		right, rok := staticType(expr.Right)
+		if lok && rok && left == right && (left == object.IntegerObjectType || left == object.StringObjectType) {
+			return left, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,196 @@
+package lint
+
+import (
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/token"
+)
+
+type bindingKind int
+
+const (
+	letBinding      bindingKind = iota // let x = ... or let [x] = ...
+	paramBinding                       // fn(x) { ... } or fn([x]) { ... }
+	funcNameBinding                    // fn x() { ... }
+	patternBinding                     // match (v) { x => ... }
+	structBinding                      // struct X { ... }
+	selfBinding                        // self in methods
+	loopBinding                        // for (x in ...) { ... }
+)
+
+type binding struct {
+	name string
+	pos  token.Position
+	kind bindingKind
+	used bool
+}
+
+// scope is a set of bindings introduced by a program or a function.
+type scope struct {
+	outer    *scope
+	bindings map[string]*binding
+	order    []*binding // bindings in the order of declaration
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{
+		outer:    outer,
+		bindings: map[string]*binding{},
+	}
+}
+
+func (s *scope) lookup(name string) *binding {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.bindings[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// resolver walks a program keeping track of scopes. It marks bindings as
+// used when they are referred to, and calls the hooks on the way.
+type resolver struct {
+	scope *scope
+
+	// declare is called before b is declared in s, if not nil.
+	declare func(s *scope, b *binding)
+	// close is called when the traversal of s finishes, if not nil.
+	close func(s *scope)
+}
+
+func (r *resolver) resolve(program *ast.Program) {
+	r.scope = newScope(nil)
+	ast.Walk(r, program)
+	r.closeScope()
+}
+
+func (r *resolver) openScope() {
+	r.scope = newScope(r.scope)
+}
+
+func (r *resolver) closeScope() {
+	if r.close != nil {
+		r.close(r.scope)
+	}
+	r.scope = r.scope.outer
+}
+
+func (r *resolver) bind(ident *ast.Identifier, kind bindingKind) {
+	b := &binding{name: ident.Value, pos: ident.Pos(), kind: kind}
+	if r.declare != nil {
+		r.declare(r.scope, b)
+	}
+	r.scope.bindings[b.name] = b
+	r.scope.order = append(r.scope.order, b)
+}
+
+// bindPattern binds the identifiers in pattern except `_`.
+func (r *resolver) bindPattern(pattern ast.Expression, kind bindingKind) {
+	ast.Inspect(pattern, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok && ident.Value != "_" {
+			r.bind(ident, kind)
+		}
+		return true
+	})
+}
+
+func (r *resolver) walk(node ast.Node) {
+	if node != nil {
+		ast.Walk(r, node)
+	}
+}
+
+func (r *resolver) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		if b := r.scope.lookup(node.Value); b != nil {
+			b.used = true
+		}
+		return nil
+	case *ast.LetStatement:
+		if node.Pattern != nil {
+			r.walk(node.Value)
+			r.bindPattern(node.Pattern, letBinding)
+			return nil
+		}
+		if node.Name == nil {
+			return r
+		}
+		// A function can refer to itself through the binding.
+		if _, ok := node.Value.(*ast.FunctionLiteral); ok {
+			r.bind(node.Name, letBinding)
+			r.walk(node.Value)
+			return nil
+		}
+		if node.Value != nil {
+			r.walk(node.Value)
+		}
+		r.bind(node.Name, letBinding)
+		return nil
+	case *ast.StructStatement:
+		r.bind(node.Name, structBinding)
+		for _, method := range node.Methods {
+			r.openScope()
+			r.bind(&ast.Identifier{Token: method.Token, Value: "self"}, selfBinding)
+			r.walk(method)
+			r.closeScope()
+		}
+		return nil
+	case *ast.MemberExpression:
+		// The member is not a variable.
+		r.walk(node.Object)
+		return nil
+	case *ast.ForStatement:
+		r.walk(node.Iterable)
+		r.openScope()
+		r.bindPattern(node.Pattern, loopBinding)
+		r.walk(node.Body)
+		r.closeScope()
+		return nil
+	case *ast.IfExpression:
+		r.walk(node.Condition)
+		for _, block := range []*ast.BlockStatement{node.Consequence, node.Alternative} {
+			if block != nil {
+				r.openScope()
+				r.walk(block)
+				r.closeScope()
+			}
+		}
+		return nil
+	case *ast.MatchExpression:
+		r.walk(node.Subject)
+		for _, arm := range node.Arms {
+			r.openScope()
+			r.bindPattern(arm.Pattern, patternBinding)
+			r.walk(arm.Guard)
+			r.walk(arm.Value)
+			r.closeScope()
+		}
+		return nil
+	case *ast.FunctionLiteral:
+		r.openScope()
+		if node.Name != nil {
+			r.bind(node.Name, funcNameBinding)
+		}
+		for i, param := range node.Parameters {
+			if i < len(node.Defaults) && node.Defaults[i] != nil {
+				r.walk(node.Defaults[i])
+			}
+			if param == nil {
+				r.bindPattern(node.Patterns[i], paramBinding)
+				continue
+			}
+			r.bind(param, paramBinding)
+		}
+		if node.Rest != nil {
+			r.bind(node.Rest, paramBinding)
+		}
+		if node.Body != nil {
+			r.walk(node.Body)
+		}
+		r.closeScope()
+		return nil
+	default:
+		return r
+	}
+}
@@ -9,7 +9,26 @@ import (
 	"github.com/daichimukai/x/syakyo/monkey/repl"
 )
 
+// commands maps the name of a subcommand to its implementation, which
+// returns the exit code.
+var commands = map[string]func(args []string) int{
+	"build":   buildMain,
+	"compile": compileMain,
+	"lint":    lintMain,
+	"run":     runMain,
+	"test":    testMain,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
+			os.Exit(2)
+		}
+		os.Exit(command(os.Args[2:]))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		log.Fatalf("failed to get user: %v", err)
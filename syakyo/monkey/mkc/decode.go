@@ -0,0 +1,369 @@
+package mkc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/token"
+)
+
+// decoder reads nodes written by encoder. The first error is kept in err,
+// after which the reads return zero values.
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (d *decoder) setErr(err error) {
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *decoder) read(buf []byte) {
+	if d.err != nil {
+		return
+	}
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		d.setErr(err)
+	}
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	b, err := d.r.ReadByte()
+	if err != nil {
+		d.setErr(err)
+	}
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.setErr(err)
+	}
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	if err != nil {
+		d.setErr(err)
+	}
+	return v
+}
+
+func (d *decoder) int() int {
+	v := d.uvarint()
+	if v > math.MaxInt32 {
+		d.fail("integer out of range: %d", v)
+		return 0
+	}
+	return int(v)
+}
+
+func (d *decoder) bool() bool {
+	return d.byte() != 0
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	// the length is not trusted to allocate the string at once
+	var s strings.Builder
+	if _, err := io.CopyN(&s, d.r, int64(n)); err != nil {
+		d.setErr(err)
+	}
+	return s.String()
+}
+
+// length returns the length of a slice and whether it is nil.
+func (d *decoder) length() (int, bool) {
+	n := d.int()
+	return n - 1, n == 0
+}
+
+func (d *decoder) token() token.Token {
+	return token.Token{
+		Type:    token.TokenType(d.int()),
+		Literal: d.string(),
+		Pos:     token.Position{Line: d.int(), Column: d.int()},
+	}
+}
+
+func (d *decoder) statements() []ast.Statement {
+	n, isNil := d.length()
+	if isNil {
+		return nil
+	}
+	stmts := []ast.Statement{}
+	for i := 0; i < n && d.err == nil; i++ {
+		stmt := d.statement()
+		if stmt == nil {
+			d.fail("missing statement")
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
+
+func (d *decoder) expressions() []ast.Expression {
+	n, isNil := d.length()
+	if isNil {
+		return nil
+	}
+	exprs := []ast.Expression{}
+	for i := 0; i < n && d.err == nil; i++ {
+		exprs = append(exprs, d.expression())
+	}
+	return exprs
+}
+
+func (d *decoder) identifiers() []*ast.Identifier {
+	n, isNil := d.length()
+	if isNil {
+		return nil
+	}
+	idents := []*ast.Identifier{}
+	for i := 0; i < n && d.err == nil; i++ {
+		idents = append(idents, d.identifier())
+	}
+	return idents
+}
+
+func (d *decoder) pairs() []ast.HashLiteralPair {
+	n, isNil := d.length()
+	if isNil {
+		return nil
+	}
+	pairs := []ast.HashLiteralPair{}
+	for i := 0; i < n && d.err == nil; i++ {
+		pairs = append(pairs, ast.HashLiteralPair{Key: d.expression(), Value: d.expression()})
+	}
+	return pairs
+}
+
+func (d *decoder) statement() ast.Statement {
+	node := d.node()
+	stmt, ok := node.(ast.Statement)
+	if node != nil && !ok {
+		d.fail("expected statement, got %T", node)
+	}
+	return stmt
+}
+
+func (d *decoder) expression() ast.Expression {
+	node := d.node()
+	expr, ok := node.(ast.Expression)
+	if node != nil && !ok {
+		d.fail("expected expression, got %T", node)
+	}
+	return expr
+}
+
+func (d *decoder) identifier() *ast.Identifier {
+	node := d.node()
+	ident, ok := node.(*ast.Identifier)
+	if node != nil && !ok {
+		d.fail("expected identifier, got %T", node)
+	}
+	return ident
+}
+
+func (d *decoder) block() *ast.BlockStatement {
+	node := d.node()
+	block, ok := node.(*ast.BlockStatement)
+	if node != nil && !ok {
+		d.fail("expected block, got %T", node)
+	}
+	return block
+}
+
+// checkFunction fails unless lit keeps the invariants of the function
+// literals made by the parser, on which the evaluator relies: the defaults
+// and the patterns are absent or given for each parameter, a parameter
+// without a name has a pattern, and the body is present.
+func (d *decoder) checkFunction(lit *ast.FunctionLiteral) {
+	if d.err != nil {
+		return
+	}
+	if lit.Body == nil {
+		d.fail("function without body")
+		return
+	}
+	if lit.Defaults != nil && len(lit.Defaults) != len(lit.Parameters) {
+		d.fail("function with %d parameters has %d defaults", len(lit.Parameters), len(lit.Defaults))
+		return
+	}
+	if lit.Patterns != nil && len(lit.Patterns) != len(lit.Parameters) {
+		d.fail("function with %d parameters has %d patterns", len(lit.Parameters), len(lit.Patterns))
+		return
+	}
+	for i, param := range lit.Parameters {
+		if param == nil && (lit.Patterns == nil || lit.Patterns[i] == nil) {
+			d.fail("parameter %d of function has neither name nor pattern", i)
+			return
+		}
+	}
+}
+
+func (d *decoder) function() *ast.FunctionLiteral {
+	node := d.node()
+	fl, ok := node.(*ast.FunctionLiteral)
+	if node != nil && !ok {
+		d.fail("expected function, got %T", node)
+	}
+	return fl
+}
+
+// node reads a node. The fields are read in the order of the composite
+// literals, which is the order in which the encoder writes them.
+func (d *decoder) node() ast.Node {
+	if d.err != nil {
+		return nil
+	}
+	switch tag := d.byte(); tag {
+	case tagNil:
+		return nil
+	case tagLet:
+		return &ast.LetStatement{
+			Token:   d.token(),
+			Const:   d.bool(),
+			Name:    d.identifier(),
+			Pattern: d.expression(),
+			Value:   d.expression(),
+		}
+	case tagReturn:
+		return &ast.ReturnStatement{Token: d.token(), ReturnValue: d.expression()}
+	case tagExpressionStatement:
+		return &ast.ExpressionStatement{Token: d.token(), Expression: d.expression()}
+	case tagBlock:
+		return &ast.BlockStatement{Token: d.token(), Statements: d.statements()}
+	case tagStruct:
+		stmt := &ast.StructStatement{Token: d.token(), Name: d.identifier(), Fields: d.identifiers()}
+		if n, isNil := d.length(); !isNil {
+			stmt.Methods = []*ast.FunctionLiteral{}
+			for i := 0; i < n && d.err == nil; i++ {
+				stmt.Methods = append(stmt.Methods, d.function())
+			}
+		}
+		return stmt
+	case tagFor:
+		return &ast.ForStatement{
+			Token:    d.token(),
+			Pattern:  d.expression(),
+			Iterable: d.expression(),
+			Body:     d.block(),
+		}
+	case tagIdentifier:
+		return &ast.Identifier{Token: d.token(), Value: d.string()}
+	case tagInteger:
+		return &ast.IntegerLiteral{Token: d.token(), Value: d.varint()}
+	case tagString:
+		return &ast.StringLiteral{Token: d.token(), Value: d.string()}
+	case tagInterpolated:
+		return &ast.InterpolatedString{Token: d.token(), Parts: d.expressions()}
+	case tagPrefix:
+		return &ast.PrefixExpression{Token: d.token(), Operator: d.string(), Right: d.expression()}
+	case tagInfix:
+		return &ast.InfixExpression{
+			Token:    d.token(),
+			Left:     d.expression(),
+			Operator: d.string(),
+			Right:    d.expression(),
+		}
+	case tagBoolean:
+		return &ast.Boolean{Token: d.token(), Value: d.bool()}
+	case tagNull:
+		return &ast.Null{Token: d.token()}
+	case tagIf:
+		return &ast.IfExpression{
+			Token:       d.token(),
+			Condition:   d.expression(),
+			Consequence: d.block(),
+			Alternative: d.block(),
+		}
+	case tagFunction:
+		lit := &ast.FunctionLiteral{
+			Token:      d.token(),
+			Name:       d.identifier(),
+			Parameters: d.identifiers(),
+			Defaults:   d.expressions(),
+			Patterns:   d.expressions(),
+			Rest:       d.identifier(),
+			Body:       d.block(),
+			Generator:  d.bool(),
+		}
+		d.checkFunction(lit)
+		return lit
+	case tagCall:
+		return &ast.CallExpression{Token: d.token(), Function: d.expression(), Arguments: d.expressions()}
+	case tagArray:
+		return &ast.ArrayLiteral{Token: d.token(), Elements: d.expressions()}
+	case tagIndex:
+		return &ast.IndexExpression{Token: d.token(), Left: d.expression(), Index: d.expression()}
+	case tagSpread:
+		return &ast.SpreadExpression{Token: d.token(), Value: d.expression()}
+	case tagHash:
+		return &ast.HashLiteral{Token: d.token(), Pairs: d.pairs()}
+	case tagSlice:
+		return &ast.SliceExpression{
+			Token: d.token(),
+			Left:  d.expression(),
+			Low:   d.expression(),
+			High:  d.expression(),
+		}
+	case tagMatch:
+		expr := &ast.MatchExpression{Token: d.token(), Subject: d.expression()}
+		if n, isNil := d.length(); !isNil {
+			expr.Arms = []ast.MatchArm{}
+			for i := 0; i < n && d.err == nil; i++ {
+				expr.Arms = append(expr.Arms, ast.MatchArm{
+					Pattern: d.expression(),
+					Guard:   d.expression(),
+					Value:   d.expression(),
+				})
+			}
+		}
+		return expr
+	case tagArrayPattern:
+		return &ast.ArrayPattern{Token: d.token(), Elements: d.expressions(), Rest: d.identifier()}
+	case tagHashPattern:
+		return &ast.HashPattern{Token: d.token(), Pairs: d.pairs(), Rest: d.identifier()}
+	case tagMember:
+		return &ast.MemberExpression{Token: d.token(), Object: d.expression(), Member: d.identifier()}
+	case tagAssign:
+		return &ast.AssignExpression{Token: d.token(), Target: d.expression(), Value: d.expression()}
+	case tagYield:
+		return &ast.YieldExpression{Token: d.token(), Value: d.expression()}
+	default:
+		d.fail("unknown node tag %d", tag)
+		return nil
+	}
+}
@@ -0,0 +1,264 @@
+package mkc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/token"
+)
+
+// encoder writes nodes in preorder. A node is a tag followed by its fields,
+// and a slice is its length plus one, or zero if it is nil. The fields set
+// by the resolver of the evaluator are not written.
+type encoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.buf[:], v)
+	e.w.Write(e.buf[:n])
+}
+
+func (e *encoder) varint(v int64) {
+	n := binary.PutVarint(e.buf[:], v)
+	e.w.Write(e.buf[:n])
+}
+
+func (e *encoder) bool(b bool) {
+	if b {
+		e.w.WriteByte(1)
+	} else {
+		e.w.WriteByte(0)
+	}
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.w.WriteString(s)
+}
+
+func (e *encoder) length(n int, isNil bool) {
+	if isNil {
+		e.uvarint(0)
+	} else {
+		e.uvarint(uint64(n) + 1)
+	}
+}
+
+func (e *encoder) token(tok token.Token) {
+	e.uvarint(uint64(tok.Type))
+	e.string(tok.Literal)
+	e.uvarint(uint64(tok.Pos.Line))
+	e.uvarint(uint64(tok.Pos.Column))
+}
+
+func (e *encoder) statements(stmts []ast.Statement) {
+	e.length(len(stmts), stmts == nil)
+	for _, stmt := range stmts {
+		e.node(stmt)
+	}
+}
+
+func (e *encoder) expressions(exprs []ast.Expression) {
+	e.length(len(exprs), exprs == nil)
+	for _, expr := range exprs {
+		e.node(expr)
+	}
+}
+
+func (e *encoder) identifiers(idents []*ast.Identifier) {
+	e.length(len(idents), idents == nil)
+	for _, ident := range idents {
+		e.identifier(ident)
+	}
+}
+
+func (e *encoder) pairs(pairs []ast.HashLiteralPair) {
+	e.length(len(pairs), pairs == nil)
+	for _, pair := range pairs {
+		e.node(pair.Key)
+		e.node(pair.Value)
+	}
+}
+
+func (e *encoder) identifier(ident *ast.Identifier) {
+	if ident == nil {
+		e.node(nil)
+	} else {
+		e.node(ident)
+	}
+}
+
+func (e *encoder) block(block *ast.BlockStatement) {
+	if block == nil {
+		e.node(nil)
+	} else {
+		e.node(block)
+	}
+}
+
+func (e *encoder) function(fl *ast.FunctionLiteral) {
+	if fl == nil {
+		e.node(nil)
+	} else {
+		e.node(fl)
+	}
+}
+
+func (e *encoder) node(node ast.Node) {
+	switch node := node.(type) {
+	case nil:
+		e.w.WriteByte(tagNil)
+	case *ast.LetStatement:
+		e.w.WriteByte(tagLet)
+		e.token(node.Token)
+		e.bool(node.Const)
+		e.identifier(node.Name)
+		e.node(node.Pattern)
+		e.node(node.Value)
+	case *ast.ReturnStatement:
+		e.w.WriteByte(tagReturn)
+		e.token(node.Token)
+		e.node(node.ReturnValue)
+	case *ast.ExpressionStatement:
+		e.w.WriteByte(tagExpressionStatement)
+		e.token(node.Token)
+		e.node(node.Expression)
+	case *ast.BlockStatement:
+		e.w.WriteByte(tagBlock)
+		e.token(node.Token)
+		e.statements(node.Statements)
+	case *ast.StructStatement:
+		e.w.WriteByte(tagStruct)
+		e.token(node.Token)
+		e.identifier(node.Name)
+		e.identifiers(node.Fields)
+		e.length(len(node.Methods), node.Methods == nil)
+		for _, method := range node.Methods {
+			e.function(method)
+		}
+	case *ast.ForStatement:
+		e.w.WriteByte(tagFor)
+		e.token(node.Token)
+		e.node(node.Pattern)
+		e.node(node.Iterable)
+		e.block(node.Body)
+	case *ast.Identifier:
+		e.w.WriteByte(tagIdentifier)
+		e.token(node.Token)
+		e.string(node.Value)
+	case *ast.IntegerLiteral:
+		e.w.WriteByte(tagInteger)
+		e.token(node.Token)
+		e.varint(node.Value)
+	case *ast.StringLiteral:
+		e.w.WriteByte(tagString)
+		e.token(node.Token)
+		e.string(node.Value)
+	case *ast.InterpolatedString:
+		e.w.WriteByte(tagInterpolated)
+		e.token(node.Token)
+		e.expressions(node.Parts)
+	case *ast.PrefixExpression:
+		e.w.WriteByte(tagPrefix)
+		e.token(node.Token)
+		e.string(node.Operator)
+		e.node(node.Right)
+	case *ast.InfixExpression:
+		e.w.WriteByte(tagInfix)
+		e.token(node.Token)
+		e.node(node.Left)
+		e.string(node.Operator)
+		e.node(node.Right)
+	case *ast.Boolean:
+		e.w.WriteByte(tagBoolean)
+		e.token(node.Token)
+		e.bool(node.Value)
+	case *ast.Null:
+		e.w.WriteByte(tagNull)
+		e.token(node.Token)
+	case *ast.IfExpression:
+		e.w.WriteByte(tagIf)
+		e.token(node.Token)
+		e.node(node.Condition)
+		e.block(node.Consequence)
+		e.block(node.Alternative)
+	case *ast.FunctionLiteral:
+		e.w.WriteByte(tagFunction)
+		e.token(node.Token)
+		e.identifier(node.Name)
+		e.identifiers(node.Parameters)
+		e.expressions(node.Defaults)
+		e.expressions(node.Patterns)
+		e.identifier(node.Rest)
+		e.block(node.Body)
+		e.bool(node.Generator)
+	case *ast.CallExpression:
+		e.w.WriteByte(tagCall)
+		e.token(node.Token)
+		e.node(node.Function)
+		e.expressions(node.Arguments)
+	case *ast.ArrayLiteral:
+		e.w.WriteByte(tagArray)
+		e.token(node.Token)
+		e.expressions(node.Elements)
+	case *ast.IndexExpression:
+		e.w.WriteByte(tagIndex)
+		e.token(node.Token)
+		e.node(node.Left)
+		e.node(node.Index)
+	case *ast.SpreadExpression:
+		e.w.WriteByte(tagSpread)
+		e.token(node.Token)
+		e.node(node.Value)
+	case *ast.HashLiteral:
+		e.w.WriteByte(tagHash)
+		e.token(node.Token)
+		e.pairs(node.Pairs)
+	case *ast.SliceExpression:
+		e.w.WriteByte(tagSlice)
+		e.token(node.Token)
+		e.node(node.Left)
+		e.node(node.Low)
+		e.node(node.High)
+	case *ast.MatchExpression:
+		e.w.WriteByte(tagMatch)
+		e.token(node.Token)
+		e.node(node.Subject)
+		e.length(len(node.Arms), node.Arms == nil)
+		for _, arm := range node.Arms {
+			e.node(arm.Pattern)
+			e.node(arm.Guard)
+			e.node(arm.Value)
+		}
+	case *ast.ArrayPattern:
+		e.w.WriteByte(tagArrayPattern)
+		e.token(node.Token)
+		e.expressions(node.Elements)
+		e.identifier(node.Rest)
+	case *ast.HashPattern:
+		e.w.WriteByte(tagHashPattern)
+		e.token(node.Token)
+		e.pairs(node.Pairs)
+		e.identifier(node.Rest)
+	case *ast.MemberExpression:
+		e.w.WriteByte(tagMember)
+		e.token(node.Token)
+		e.node(node.Object)
+		e.identifier(node.Member)
+	case *ast.AssignExpression:
+		e.w.WriteByte(tagAssign)
+		e.token(node.Token)
+		e.node(node.Target)
+		e.node(node.Value)
+	case *ast.YieldExpression:
+		e.w.WriteByte(tagYield)
+		e.token(node.Token)
+		e.node(node.Value)
+	default:
+		panic(fmt.Sprintf("mkc: unknown node %T", node))
+	}
+}
@@ -0,0 +1,120 @@
+// Package mkc implements the binary format of compiled Monkey programs,
+// which are parsed programs saved to skip parsing on startup.
+//
+// A file consists of a header and a program. The layout of the header is
+// the same in all the versions, so that a reader can find the source of a
+// file written in another version and parse it instead.
+package mkc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+)
+
+// Version is the version of the encoding of programs. It is incremented
+// whenever the encoding or the AST changes.
+const Version = 4
+
+const magic = "\x00mkc"
+
+var (
+	// ErrFormat is returned when decoding a file which is not a compiled program.
+	ErrFormat = errors.New("not a compiled Monkey program")
+	// ErrVersion is returned when decoding a program of another version.
+	ErrVersion = errors.New("unsupported version")
+)
+
+// Header is the header of a compiled program.
+type Header struct {
+	Version uint16
+	Source  string            // the path of the source file, relative to the directory of the compiled file
+	Hash    [sha256.Size]byte // the SHA-256 hash of the source
+}
+
+// Fresh reports whether the program was compiled from src by this version.
+func (h *Header) Fresh(src []byte) bool {
+	return h.Version == Version && h.Hash == sha256.Sum256(src)
+}
+
+// Encode writes program compiled from src, the content of the file at the
+// path source, to w.
+func Encode(w io.Writer, program *ast.Program, source string, src []byte) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+	e.w.WriteString(magic)
+	e.w.Write([]byte{Version >> 8, Version & 0xff})
+	e.string(source)
+	hash := sha256.Sum256(src)
+	e.w.Write(hash[:])
+
+	e.statements(program.Statements)
+	return e.w.Flush()
+}
+
+// Decode reads a compiled program from r. If the program is of another
+// version, it returns the header and an error wrapping ErrVersion.
+func Decode(r io.Reader) (*Header, *ast.Program, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+	var buf [len(magic) + 2]byte
+	if _, err := io.ReadFull(d.r, buf[:]); err != nil || !bytes.Equal(buf[:len(magic)], []byte(magic)) {
+		return nil, nil, ErrFormat
+	}
+	header := &Header{Version: uint16(buf[len(magic)])<<8 | uint16(buf[len(magic)+1])}
+	header.Source = d.string()
+	d.read(header.Hash[:])
+	if d.err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrFormat, d.err)
+	}
+	if header.Version != Version {
+		return header, nil, fmt.Errorf("%w %d", ErrVersion, header.Version)
+	}
+
+	program := &ast.Program{Statements: d.statements()}
+	if d.err == nil {
+		if _, err := d.r.ReadByte(); err != io.EOF {
+			d.fail("trailing data")
+		}
+	}
+	if d.err != nil {
+		return header, nil, fmt.Errorf("%w: %v", ErrFormat, d.err)
+	}
+	return header, program, nil
+}
+
+// tags of the nodes
+const (
+	tagNil byte = iota
+	tagLet
+	tagReturn
+	tagExpressionStatement
+	tagBlock
+	tagStruct
+	tagFor
+	tagIdentifier
+	tagInteger
+	tagString
+	tagInterpolated
+	tagPrefix
+	tagInfix
+	tagBoolean
+	tagIf
+	tagFunction
+	tagCall
+	tagArray
+	tagIndex
+	tagSpread
+	tagHash
+	tagSlice
+	tagMatch
+	tagArrayPattern
+	tagHashPattern
+	tagMember
+	tagAssign
+	tagYield
+	tagNull
+)
@@ -0,0 +1,102 @@
+package mkc_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/mkc"
+	"github.com/daichimukai/x/syakyo/monkey/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	testcases := []string{
+		``,
+		`let x = 5; const y = -10; return x + y * 2;`,
+		`"foo"; "x=${x + 1}, y=${[1, "a"]}"; true; !false; null; 1 < 2 == 2 > 1`,
+		`if (x) { 1 } else { 2 }; if (x != 1) { 3 }`,
+		`let f = fn g(a, [c, d], {e}, b = a * 2, ...rest) { g(...rest) }; f(); fn() {}`,
+		`[1, 2][0]; {"a": 1, 2: [3]}["a"]; xs[1:]; xs[:-1]; xs[a:b]`,
+		`match (x) { 0 => "zero", [a, ...b] if a > 1 => b, {"k": k} => k, _ => null }`,
+		`let [a, [b, c], ...rest] = xs; let {name, "age": age, ...r} = h;`,
+		`struct P { x, y, fn norm() { self.x * self.x + self.y * self.y }, fn move(dx) { self.x = self.x + dx } }`,
+		`let nat = fn() { let i = 0; for (i in range(10)) { yield i } }; for ([k, v] in {"a": 1}) { puts(k, v) }`,
+	}
+
+	for _, input := range testcases {
+		t.Run(input, func(t *testing.T) {
+			program, err := parser.New(lexer.New(input)).ParseProgram()
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, mkc.Encode(&buf, program, "test.mk", []byte(input)))
+			header, decoded, err := mkc.Decode(&buf)
+			require.NoError(t, err)
+			require.Equal(t, program, decoded)
+			require.Equal(t, uint16(mkc.Version), header.Version)
+			require.Equal(t, "test.mk", header.Source)
+			require.True(t, header.Fresh([]byte(input)))
+			require.False(t, header.Fresh([]byte(input+" ")))
+		})
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	input := `let f = fn(x) { match (x) { [a] => a, _ => "${x}" } }; puts(f([1]))`
+	program, err := parser.New(lexer.New(input)).ParseProgram()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, mkc.Encode(&buf, program, "test.mk", []byte(input)))
+	data := buf.Bytes()
+
+	t.Run("not compiled", func(t *testing.T) {
+		header, _, err := mkc.Decode(bytes.NewReader([]byte(input)))
+		require.Nil(t, header)
+		require.ErrorIs(t, err, mkc.ErrFormat)
+	})
+
+	t.Run("version", func(t *testing.T) {
+		old := append([]byte{}, data...)
+		old[5]++
+		header, program, err := mkc.Decode(bytes.NewReader(old))
+		require.ErrorIs(t, err, mkc.ErrVersion)
+		require.Nil(t, program)
+		require.Equal(t, "test.mk", header.Source)
+		require.False(t, header.Fresh([]byte(input)))
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		for n := 0; n < len(data); n++ {
+			_, _, err := mkc.Decode(bytes.NewReader(data[:n]))
+			require.ErrorIs(t, err, mkc.ErrFormat, n)
+		}
+	})
+
+	t.Run("trailing data", func(t *testing.T) {
+		_, _, err := mkc.Decode(bytes.NewReader(append(data, 0)))
+		require.ErrorIs(t, err, mkc.ErrFormat)
+	})
+
+	t.Run("malformed function", func(t *testing.T) {
+		for name, modify := range map[string]func(lit *ast.FunctionLiteral){
+			"no body":        func(lit *ast.FunctionLiteral) { lit.Body = nil },
+			"no pattern":     func(lit *ast.FunctionLiteral) { lit.Patterns[1] = nil },
+			"unnamed":        func(lit *ast.FunctionLiteral) { lit.Parameters[0] = nil },
+			"short patterns": func(lit *ast.FunctionLiteral) { lit.Patterns = lit.Patterns[:1] },
+			"short defaults": func(lit *ast.FunctionLiteral) { lit.Defaults = lit.Defaults[:1] },
+		} {
+			t.Run(name, func(t *testing.T) {
+				input := `fn(x, [y] = [1]) { x + y }`
+				program, err := parser.New(lexer.New(input)).ParseProgram()
+				require.NoError(t, err)
+				modify(program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral))
+				var buf bytes.Buffer
+				require.NoError(t, mkc.Encode(&buf, program, "test.mk", []byte(input)))
+				_, _, err = mkc.Decode(&buf)
+				require.ErrorIs(t, err, mkc.ErrFormat)
+			})
+		}
+	})
+}
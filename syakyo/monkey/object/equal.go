@@ -0,0 +1,118 @@
+package object
+
+import "strings"
+
+// Equal reports whether a and b are equal. Integers, strings, arrays,
+// hashes and instances of the same struct type are compared by value, and
+// the other objects by identity. Values referring to themselves are equal
+// if no difference is found before reaching the same pair of values again.
+func Equal(a, b Object) bool {
+	return equal(a, b, map[[2]Object]bool{})
+}
+
+func equal(a, b Object, visiting map[[2]Object]bool) bool {
+	if a == b {
+		return true
+	}
+	switch a := a.(type) {
+	case *Integer:
+		b, ok := b.(*Integer)
+		return ok && a.Value == b.Value
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		if visiting[[2]Object{a, b}] {
+			return true
+		}
+		visiting[[2]Object{a, b}] = true
+		for i := range a.Elements {
+			if !equal(a.Elements[i], b.Elements[i], visiting) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+		if visiting[[2]Object{a, b}] {
+			return true
+		}
+		visiting[[2]Object{a, b}] = true
+		for key, pair := range a.Pairs {
+			other, ok := b.Pairs[key]
+			if !ok || !equal(pair.Value, other.Value, visiting) {
+				return false
+			}
+		}
+		return true
+	case *Struct:
+		b, ok := b.(*Struct)
+		if !ok || a.StructType != b.StructType {
+			return false
+		}
+		if visiting[[2]Object{a, b}] {
+			return true
+		}
+		visiting[[2]Object{a, b}] = true
+		for _, name := range a.StructType.Fields {
+			x, _ := a.field(name)
+			y, _ := b.field(name)
+			if !equal(x, y, visiting) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// Compare returns a negative number, zero or a positive number as a is less
+// than, equal to or greater than b. Integers and strings are ordered as
+// usual, and arrays lexicographically: the first pair of unequal elements
+// decides the order, and an array precedes the longer ones it is a prefix
+// of. It returns false if a and b are not ordered, e.g. if they are of
+// different types or the first unequal elements are not ordered.
+func Compare(a, b Object) (int, bool) {
+	switch a := a.(type) {
+	case *Integer:
+		b, ok := b.(*Integer)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a.Value < b.Value:
+			return -1, true
+		case a.Value > b.Value:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case *String:
+		b, ok := b.(*String)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a.Value, b.Value), true
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok {
+			return 0, false
+		}
+		for i := 0; i < len(a.Elements) && i < len(b.Elements); i++ {
+			if !Equal(a.Elements[i], b.Elements[i]) {
+				return Compare(a.Elements[i], b.Elements[i])
+			}
+		}
+		return len(a.Elements) - len(b.Elements), true
+	default:
+		return 0, false
+	}
+}
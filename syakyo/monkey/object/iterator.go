@@ -0,0 +1,194 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Iterable is an object whose elements can be iterated lazily.
+type Iterable interface {
+	Object
+	// Iterate returns an iterator over the elements. Depending on the
+	// object, the iterator may be a fresh one or the object itself.
+	Iterate() Iterator
+}
+
+// Iterator produces values one by one.
+type Iterator interface {
+	// Next returns the next value and true, or nil and false if the
+	// iterator is exhausted. An *Error value ends the iteration.
+	Next() (Object, bool)
+	// Close releases the resources held by the iterator. It must be called
+	// if the iteration stops before the iterator is exhausted.
+	Close()
+}
+
+// Iterate returns an iterator over the elements of obj: the elements of an
+// array, the [key, value] pairs of a hash, or the elements of an iterable.
+func Iterate(obj Object) (Iterator, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		i := 0
+		return &LazyIterator{NextFunc: func() (Object, bool) {
+			if i >= len(obj.Elements) {
+				return nil, false
+			}
+			i++
+			return obj.Elements[i-1], true
+		}}, true
+	case *Hash:
+		pairs := obj.Ordered()
+		i := 0
+		return &LazyIterator{NextFunc: func() (Object, bool) {
+			if i >= len(pairs) {
+				return nil, false
+			}
+			i++
+			return &Array{Elements: []Object{pairs[i-1].Key, pairs[i-1].Value}}, true
+		}}, true
+	case Iterable:
+		return obj.Iterate(), true
+	default:
+		return nil, false
+	}
+}
+
+// LazyIterator is an iterator built from functions, e.g. by the map builtin.
+type LazyIterator struct {
+	NextFunc  func() (Object, bool)
+	CloseFunc func() // may be nil
+}
+
+func (li *LazyIterator) Type() ObjectType { return IteratorObjectType }
+func (li *LazyIterator) Inspect() string  { return "iterator" }
+
+func (li *LazyIterator) Iterate() Iterator    { return li }
+func (li *LazyIterator) Next() (Object, bool) { return li.NextFunc() }
+
+func (li *LazyIterator) Close() {
+	if li.CloseFunc != nil {
+		li.CloseFunc()
+	}
+}
+
+// Range is a lazy sequence of integers from Start to Stop (exclusive) by Step.
+type Range struct {
+	Start, Stop, Step int64
+}
+
+func (r *Range) Type() ObjectType { return RangeObjectType }
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.Stop, r.Step)
+}
+
+func (r *Range) Iterate() Iterator {
+	cur := r.Start
+	done := false
+	return &LazyIterator{NextFunc: func() (Object, bool) {
+		if done || r.Step > 0 && cur >= r.Stop || r.Step < 0 && cur <= r.Stop {
+			return nil, false
+		}
+		val := cur
+		// The range ends if the next value overflows, which is beyond Stop.
+		if r.Step > 0 && cur > math.MaxInt64-r.Step || r.Step < 0 && cur < math.MinInt64-r.Step {
+			done = true
+		} else {
+			cur += r.Step
+		}
+		return NewInteger(val), true
+	}}
+}
+
+type generatorState int
+
+const (
+	generatorCreated generatorState = iota
+	generatorSuspended
+	generatorDone
+)
+
+// generatorStep is sent from the goroutine running a generator.
+type generatorStep struct {
+	value Object // a yielded value, or the error which ended the generator
+	done  bool
+}
+
+// Generator is an iterator over the values yielded by a generator function.
+// The body of the function runs in another goroutine, which is suspended
+// while the consumer is running and vice versa. Next and Close may be called
+// from several tasks; they are serialized so that each value is produced
+// for one of them.
+type Generator struct {
+	run    func(yield func(Object) bool) Object
+	mu     sync.Mutex // held while the body is running; guards the fields below
+	state  generatorState
+	steps  chan generatorStep
+	resume chan bool
+}
+
+// NewGenerator returns a generator which calls run on the first call of
+// Next. run calls yield for each value to produce; if yield returns false,
+// the generator is closed and run must return as soon as possible. The
+// value returned by run is reported as the last value if it is an *Error.
+func NewGenerator(run func(yield func(Object) bool) Object) *Generator {
+	return &Generator{run: run}
+}
+
+func (g *Generator) Type() ObjectType { return GeneratorObjectType }
+func (g *Generator) Inspect() string  { return "generator" }
+
+func (g *Generator) Iterate() Iterator { return g }
+
+func (g *Generator) Next() (Object, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch g.state {
+	case generatorDone:
+		return nil, false
+	case generatorCreated:
+		g.steps = make(chan generatorStep)
+		g.resume = make(chan bool)
+		go g.start()
+	case generatorSuspended:
+		g.resume <- true
+	}
+
+	step := <-g.steps
+	if step.done {
+		g.state = generatorDone
+		return step.value, step.value != nil
+	}
+	g.state = generatorSuspended
+	return step.value, true
+}
+
+func (g *Generator) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.state == generatorSuspended {
+		g.resume <- false
+		<-g.steps
+	}
+	g.state = generatorDone
+}
+
+func (g *Generator) start() {
+	closed := false
+	result := g.run(func(v Object) bool {
+		if closed {
+			return false
+		}
+		g.steps <- generatorStep{value: v}
+		closed = !<-g.resume
+		return !closed
+	})
+
+	step := generatorStep{done: true}
+	if err, ok := result.(*Error); ok && !closed {
+		step.value = err
+	}
+	g.steps <- step
+}
@@ -4,9 +4,12 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"regexp"
 	"strings"
 
 	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/token"
 )
 
 //go:generate stringer -type ObjectType -linecomment
@@ -22,6 +25,15 @@ const (
 	ErrorObjectType                         // ERROR
 	FunctionObjectType                      // FUNCTION
 	BuiltinObjectType                       // BUILTIN
+	HashObjectType                          // HASH
+	RegexpObjectType                        // REGEXP
+	StructTypeObjectType                    // STRUCT_TYPE
+	StructObjectType                        // STRUCT
+	GeneratorObjectType                     // GENERATOR
+	IteratorObjectType                      // ITERATOR
+	RangeObjectType                         // RANGE
+	TaskObjectType                          // TASK
+	ChannelObjectType                       // CHANNEL
 )
 
 type Object interface {
@@ -36,6 +48,33 @@ type Integer struct {
 func (i *Integer) Type() ObjectType { return IntegerObjectType }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+// The integers in [smallIntMin, smallIntMax] are allocated in advance and
+// shared, since integers are immutable and small ones are the most common.
+const (
+	smallIntMin = -128
+	smallIntMax = 1023
+)
+
+var smallInts = func() []Integer {
+	ints := make([]Integer, smallIntMax-smallIntMin+1)
+	for i := range ints {
+		ints[i].Value = int64(i + smallIntMin)
+	}
+	return ints
+}()
+
+// NewInteger returns an integer of v. It does not allocate for small v.
+func NewInteger(v int64) *Integer {
+	if smallIntMin <= v && v <= smallIntMax {
+		return &smallInts[v-smallIntMin]
+	}
+	return &Integer{Value: v}
+}
+
 type String struct {
 	Value string
 }
@@ -43,26 +82,82 @@ type String struct {
 func (s *String) Type() ObjectType { return StringObjectType }
 func (s *String) Inspect() string  { return s.Value }
 
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
 type Array struct {
 	Elements []Object
 }
 
 func (a *Array) Type() ObjectType { return ArrayObjectType }
-func (a *Array) Inspect() string {
-	var out bytes.Buffer
+func (a *Array) Inspect() string  { return inspect(a) }
+
+// HashKey identifies a key of a hash.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by objects which can be used as keys of hashes.
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
 
-	var elems []string
-	for _, elem := range a.Elements {
-		elems = append(elems, elem.Inspect())
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	Keys  []HashKey // keys of Pairs in insertion order
+}
+
+func NewHash() *Hash {
+	return &Hash{
+		Pairs: make(map[HashKey]HashPair),
+	}
+}
+
+// Set associates value with key. A new key is placed after the existing ones.
+func (h *Hash) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+	if _, ok := h.Pairs[hashKey]; !ok {
+		h.Keys = append(h.Keys, hashKey)
 	}
+	h.Pairs[hashKey] = HashPair{Key: key, Value: value}
+}
 
-	out.WriteString("[")
-	out.WriteString(strings.Join(elems, ", "))
-	out.WriteString("]")
+// Get returns the value associated with key.
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.Pairs[key.HashKey()]
+	return pair.Value, ok
+}
 
-	return out.String()
+// Ordered returns the pairs of h in insertion order.
+func (h *Hash) Ordered() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Keys))
+	for _, key := range h.Keys {
+		pairs = append(pairs, h.Pairs[key])
+	}
+	return pairs
 }
 
+func (h *Hash) Type() ObjectType { return HashObjectType }
+func (h *Hash) Inspect() string  { return inspect(h) }
+
+// Regexp is a compiled regular expression.
+type Regexp struct {
+	Value *regexp.Regexp
+}
+
+func (r *Regexp) Type() ObjectType { return RegexpObjectType }
+func (r *Regexp) Inspect() string  { return "/" + r.Value.String() + "/" }
+
 var (
 	True  = &boolean{Value: true}
 	False = &boolean{Value: false}
@@ -83,6 +178,14 @@ type boolean struct {
 func (b *boolean) Type() ObjectType { return BooleanObjectType }
 func (b *boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
 
+func (b *boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
 var Null = &null{}
 
 type null struct{}
@@ -101,6 +204,7 @@ func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 // Error is an object that means some error happend.
 type Error struct {
 	Message string
+	Pos     token.Position // position of the call to the builtin returning the error, if known
 }
 
 func (e *Error) Type() ObjectType { return ErrorObjectType }
@@ -113,31 +217,65 @@ func NewError(format string, a ...interface{}) *Error {
 }
 
 type Function struct {
-	Parameters []*ast.Identifier
+	Name       string            // empty if the function is anonymous
+	Parameters []*ast.Identifier // an element is nil if the parameter is a pattern
+	Defaults   []ast.Expression  // default values of Parameters; an element is nil if none
+	Patterns   []ast.Expression  // destructuring patterns of Parameters; an element is nil if none
+	Rest       *ast.Identifier   // nil if the function takes no rest parameter
 	Body       *ast.BlockStatement
+	Generator  bool   // whether a call returns a generator instead of evaluating Body
+	Binding    string // the name bound to the function literal by a let or const statement, if any
 	Env        Environment
+
+	// Native, if not nil, implements the function in Go instead of the
+	// fields above, e.g. in a program compiled by the transpile package. It
+	// is called with the arguments as is and checks the arity by itself. A
+	// native method of a struct takes the receiver as the first argument.
+	Native func(args []Object) Object
+	Source string // Inspect() of a native function
 }
 
 type Environment interface {
 	NewEnclosedEnvironment() Environment
 	Eval(ast.Node) Object
 	Set(string, Object) Object
+	// Destructure binds the identifiers in pattern to the corresponding
+	// parts of value. It returns an error if value does not match pattern.
+	Destructure(pattern ast.Expression, value Object) *Error
+	// Generate returns a generator which evaluates body in the environment
+	// lazily, producing the values of the yield expressions.
+	Generate(body *ast.BlockStatement) Object
 }
 
 func (f *Function) Type() ObjectType { return FunctionObjectType }
 func (f *Function) Inspect() string {
+	if f.Native != nil {
+		return f.Source
+	}
+
 	var out bytes.Buffer
 
 	var params []string
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
+	for i, p := range f.Parameters {
+		var param string
+		if p == nil {
+			param = f.Patterns[i].String()
+		} else {
+			param = p.String()
+		}
+		if i < len(f.Defaults) && f.Defaults[i] != nil {
+			param += " = " + f.Defaults[i].String()
+		}
+		params = append(params, param)
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
 	}
 
 	out.WriteString("fn(")
-	out.WriteString(strings.Join(params, ","))
-	out.WriteString("{\n")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
 	out.WriteString(f.Body.String())
-	out.WriteString("\n}")
 
 	return out.String()
 }
@@ -145,24 +283,103 @@ func (f *Function) Inspect() string {
 func ApplyFunction(fn Object, args []Object) Object {
 	switch fn := fn.(type) {
 	case *Function:
-		extendedEnv := fn.Env.NewEnclosedEnvironment()
-		for i, param := range fn.Parameters {
-			extendedEnv.Set(param.Value, args[i])
-		}
-
-		evaluated := extendedEnv.Eval(fn.Body)
-		if retVal, ok := evaluated.(*ReturnValue); ok {
-			return retVal.Value
+		if fn.Native != nil {
+			return fn.Native(args)
 		}
-		return evaluated
+		return fn.Call(fn.Env.NewEnclosedEnvironment(), args)
 	case *Builtin:
-		return fn.Fn(args...)
+		return fn.Fn(ApplyFunction, args...)
+	case *StructType:
+		return fn.New(args)
 	default:
 		return NewError("not a function: %s", fn.Type().String())
 	}
 }
 
-type BuiltinFunction func(args ...Object) Object
+// Call calls f with args in env, which is a new environment enclosed by
+// f.Env. It lets the caller prepare the environment, e.g. to record the
+// call in it.
+func (f *Function) Call(env Environment, args []Object) Object {
+	if f.Native != nil {
+		return f.Native(args)
+	}
+	if err := f.checkArity(len(args)); err != nil {
+		return err
+	}
+
+	for i, param := range f.Parameters {
+		var val Object
+		if i < len(args) {
+			val = args[i]
+		} else {
+			// Default values are evaluated in the callee's environment so
+			// that they can refer to the preceding parameters.
+			val = env.Eval(f.Defaults[i])
+			if err, ok := val.(*Error); ok {
+				return err
+			}
+		}
+		if param == nil {
+			if err := env.Destructure(f.Patterns[i], val); err != nil {
+				return err
+			}
+			continue
+		}
+		env.Set(param.Value, val)
+	}
+	if f.Rest != nil {
+		rest := &Array{}
+		if len(args) > len(f.Parameters) {
+			rest.Elements = append(rest.Elements, args[len(f.Parameters):]...)
+		}
+		env.Set(f.Rest.Value, rest)
+	}
+
+	if f.Generator {
+		return env.Generate(f.Body)
+	}
+
+	evaluated := env.Eval(f.Body)
+	if retVal, ok := evaluated.(*ReturnValue); ok {
+		return retVal.Value
+	}
+	if evaluated == nil {
+		// The body ends with a statement.
+		return Null
+	}
+	return evaluated
+}
+
+// checkArity returns an error if the function cannot be called with n arguments.
+func (f *Function) checkArity(n int) *Error {
+	min := len(f.Parameters)
+	for i := range f.Parameters {
+		if i < len(f.Defaults) && f.Defaults[i] != nil {
+			min = i
+			break
+		}
+	}
+	max := len(f.Parameters)
+
+	switch {
+	case f.Rest != nil && n < min:
+		return NewError("wrong number of arguments: got=%d, want>=%d", n, min)
+	case f.Rest != nil:
+		return nil
+	case min != max && (n < min || max < n):
+		return NewError("wrong number of arguments: got=%d, want=%d..%d", n, min, max)
+	case n < min || max < n:
+		return NewError("wrong number of arguments: got=%d, want=%d", n, max)
+	}
+	return nil
+}
+
+// ApplyFunc calls fn with args and returns the result.
+type ApplyFunc func(fn Object, args []Object) Object
+
+// BuiltinFunction is the implementation of a builtin. It can call back
+// functions given as arguments through apply.
+type BuiltinFunction func(apply ApplyFunc, args ...Object) Object
 
 type Builtin struct {
 	Fn BuiltinFunction
@@ -17,11 +17,20 @@ func _() {
 	_ = x[ErrorObjectType-6]
 	_ = x[FunctionObjectType-7]
 	_ = x[BuiltinObjectType-8]
+	_ = x[HashObjectType-9]
+	_ = x[RegexpObjectType-10]
+	_ = x[StructTypeObjectType-11]
+	_ = x[StructObjectType-12]
+	_ = x[GeneratorObjectType-13]
+	_ = x[IteratorObjectType-14]
+	_ = x[RangeObjectType-15]
+	_ = x[TaskObjectType-16]
+	_ = x[ChannelObjectType-17]
 }
 
-const _ObjectType_name = "INTEGERSTRINGARRAYBOOLEANNULLRETURN_VALUEERRORFUNCTIONBUILTIN"
+const _ObjectType_name = "INTEGERSTRINGARRAYBOOLEANNULLRETURN_VALUEERRORFUNCTIONBUILTINHASHREGEXPSTRUCT_TYPESTRUCTGENERATORITERATORRANGETASKCHANNEL"
 
-var _ObjectType_index = [...]uint8{0, 7, 13, 18, 25, 29, 41, 46, 54, 61}
+var _ObjectType_index = [...]uint8{0, 7, 13, 18, 25, 29, 41, 46, 54, 61, 65, 71, 82, 88, 97, 105, 110, 114, 121}
 
 func (i ObjectType) String() string {
 	if i < 0 || i >= ObjectType(len(_ObjectType_index)-1) {
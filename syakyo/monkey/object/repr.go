@@ -0,0 +1,165 @@
+package object
+
+import (
+	"strings"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+)
+
+// Repr returns the source of an expression evaluating to a value equal to
+// obj. Strings are quoted, and an instance of a struct is written as a call
+// to its constructor. Objects without source representation, e.g.
+// builtins and iterators, are written as Inspect() does. A value reached
+// again while writing itself is written as [...], {...} or P(...).
+func Repr(obj Object) string {
+	p := &printer{repr: true}
+	p.print(obj)
+	return p.out.String()
+}
+
+// Pretty returns Repr(obj) with the elements of non-empty arrays, hashes and
+// struct instances written on their own lines, indented with indent per
+// level of nesting.
+func Pretty(obj Object, indent string) string {
+	p := &printer{repr: true, indent: indent, multiline: true}
+	p.print(obj)
+	return p.out.String()
+}
+
+// inspect returns Inspect() of obj, which is an array, a hash or a struct
+// instance.
+func inspect(obj Object) string {
+	p := &printer{}
+	p.print(obj)
+	return p.out.String()
+}
+
+// printer writes objects to out, as Repr does if repr is set, or as
+// Inspect does otherwise. Strings in arrays, hashes and struct instances are
+// quoted in either case so that they are distinguishable from the other
+// values.
+type printer struct {
+	out       strings.Builder
+	repr      bool
+	multiline bool
+	indent    string
+	depth     int
+	visiting  map[Object]bool
+}
+
+func (p *printer) print(obj Object) {
+	switch obj := obj.(type) {
+	case *String:
+		if p.repr || p.depth > 0 {
+			p.out.WriteString(ast.Quote(obj.Value))
+		} else {
+			p.out.WriteString(obj.Value)
+		}
+	case *Array:
+		if !p.enter(obj, "[...]") {
+			return
+		}
+		defer p.leave(obj)
+		p.out.WriteString("[")
+		for i, elem := range obj.Elements {
+			p.separate(i)
+			p.print(elem)
+		}
+		p.close(len(obj.Elements), "]")
+	case *Hash:
+		if !p.enter(obj, "{...}") {
+			return
+		}
+		defer p.leave(obj)
+		p.out.WriteString("{")
+		for i, pair := range obj.Ordered() {
+			p.separate(i)
+			p.print(pair.Key)
+			p.out.WriteString(": ")
+			p.print(pair.Value)
+		}
+		p.close(len(obj.Keys), "}")
+	case *Struct:
+		name := obj.StructType.Name
+		cycle := name + "{...}"
+		if p.repr {
+			cycle = name + "(...)"
+		}
+		if !p.enter(obj, cycle) {
+			return
+		}
+		defer p.leave(obj)
+		if p.repr {
+			p.out.WriteString(name + "(")
+		} else {
+			p.out.WriteString(name + "{")
+		}
+		for i, field := range obj.StructType.Fields {
+			p.separate(i)
+			if !p.repr {
+				p.out.WriteString(field + ": ")
+			}
+			val, _ := obj.field(field)
+			p.print(val)
+		}
+		if p.repr {
+			p.close(len(obj.StructType.Fields), ")")
+		} else {
+			p.close(len(obj.StructType.Fields), "}")
+		}
+	case *Regexp:
+		if p.repr {
+			p.out.WriteString("regex(" + ast.Quote(obj.Value.String()) + ")")
+		} else {
+			p.out.WriteString(obj.Inspect())
+		}
+	default:
+		p.out.WriteString(obj.Inspect())
+	}
+}
+
+// enter marks obj as being written. If obj is already being written, it
+// writes cycle instead and returns false.
+func (p *printer) enter(obj Object, cycle string) bool {
+	if p.visiting[obj] {
+		p.out.WriteString(cycle)
+		return false
+	}
+	if p.visiting == nil {
+		p.visiting = map[Object]bool{}
+	}
+	p.visiting[obj] = true
+	p.depth++
+	return true
+}
+
+func (p *printer) leave(obj Object) {
+	delete(p.visiting, obj)
+	p.depth--
+}
+
+// separate writes the separator before the i-th element.
+func (p *printer) separate(i int) {
+	if i > 0 {
+		p.out.WriteString(",")
+		if !p.multiline {
+			p.out.WriteString(" ")
+		}
+	}
+	if p.multiline {
+		p.newline(p.depth)
+	}
+}
+
+// close writes the closing bracket of a container of n elements.
+func (p *printer) close(n int, bracket string) {
+	if p.multiline && n > 0 {
+		p.newline(p.depth - 1)
+	}
+	p.out.WriteString(bracket)
+}
+
+func (p *printer) newline(depth int) {
+	p.out.WriteString("\n")
+	p.out.WriteString(strings.Repeat(p.indent, depth))
+}
@@ -0,0 +1,89 @@
+package object
+
+import (
+	"strings"
+	"sync"
+)
+
+// StructType is a type declared by a struct statement. It is called as the
+// constructor of its instances.
+type StructType struct {
+	Name    string
+	Fields  []string
+	Methods map[string]*Function
+}
+
+func (st *StructType) Type() ObjectType { return StructTypeObjectType }
+func (st *StructType) Inspect() string {
+	return "struct " + st.Name + " { " + strings.Join(st.Fields, ", ") + " }"
+}
+
+// New returns a new instance of st whose fields are initialized with args
+// in the order of declaration.
+func (st *StructType) New(args []Object) Object {
+	if len(args) != len(st.Fields) {
+		return NewError("wrong number of arguments: got=%d, want=%d", len(args), len(st.Fields))
+	}
+
+	fields := make(map[string]Object, len(st.Fields))
+	for i, name := range st.Fields {
+		fields[name] = args[i]
+	}
+	return &Struct{StructType: st, Fields: fields}
+}
+
+// Struct is an instance of a struct type. Its fields are safe for
+// concurrent use.
+type Struct struct {
+	StructType *StructType
+
+	mu     sync.RWMutex // guards Fields
+	Fields map[string]Object
+}
+
+func (s *Struct) Type() ObjectType { return StructObjectType }
+func (s *Struct) Inspect() string  { return inspect(s) }
+
+// Member returns the field or the method named name. A method is returned
+// as a function whose environment binds `self` to s, or which passes s to
+// the native method.
+func (s *Struct) Member(name string) (Object, bool) {
+	if val, ok := s.field(name); ok {
+		return val, true
+	}
+
+	method, ok := s.StructType.Methods[name]
+	if !ok {
+		return nil, false
+	}
+	bound := *method
+	if method.Native != nil {
+		bound.Native = func(args []Object) Object {
+			return method.Native(append([]Object{s}, args...))
+		}
+		return &bound, true
+	}
+	env := method.Env.NewEnclosedEnvironment()
+	env.Set("self", s)
+	bound.Env = env
+	return &bound, true
+}
+
+// SetField sets the field named name to val. It returns false if s has no
+// such field.
+func (s *Struct) SetField(name string, val Object) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Fields[name]; !ok {
+		return false
+	}
+	s.Fields[name] = val
+	return true
+}
+
+func (s *Struct) field(name string) (Object, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	val, ok := s.Fields[name]
+	return val, ok
+}
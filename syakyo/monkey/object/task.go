@@ -0,0 +1,153 @@
+package object
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Task is a function call running on its own goroutine.
+type Task struct {
+	done   chan struct{}
+	result Object
+}
+
+// Spawn calls fn on a new goroutine and returns the task running it.
+func Spawn(fn func() Object) *Task {
+	t := &Task{done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		t.result = fn()
+	}()
+	return t
+}
+
+func (t *Task) Type() ObjectType { return TaskObjectType }
+func (t *Task) Inspect() string  { return "task" }
+
+// Wait blocks until the task finishes and returns the result of the call.
+func (t *Task) Wait() Object {
+	<-t.done
+	return t.result
+}
+
+// Channel is a queue of objects shared by tasks. Closing a channel does not
+// discard the queued objects; receiving from a closed channel returns them
+// before reporting the close.
+type Channel struct {
+	queue chan Object
+	done  chan struct{} // closed when the channel is closed
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewChannel returns a channel queueing up to capacity objects. A channel
+// with zero capacity is unbuffered.
+func NewChannel(capacity int) *Channel {
+	return &Channel{
+		queue: make(chan Object, capacity),
+		done:  make(chan struct{}),
+	}
+}
+
+func (c *Channel) Type() ObjectType { return ChannelObjectType }
+func (c *Channel) Inspect() string  { return "channel" }
+
+// Send blocks until val is queued. It returns an error if the channel is
+// closed.
+func (c *Channel) Send(val Object) *Error {
+	if c.isClosed() {
+		return NewError("send on closed channel")
+	}
+	select {
+	case c.queue <- val:
+		return nil
+	case <-c.done:
+		return NewError("send on closed channel")
+	}
+}
+
+// Recv blocks until an object is queued and returns it and true. It returns
+// nil and false if the channel is closed and empty.
+func (c *Channel) Recv() (Object, bool) {
+	select {
+	case val := <-c.queue:
+		return val, true
+	case <-c.done:
+		return c.drain()
+	}
+}
+
+// Close closes the channel. The tasks blocked on it are woken up.
+func (c *Channel) Close() *Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return NewError("close of closed channel")
+	}
+	c.closed = true
+	close(c.done)
+	return nil
+}
+
+// Iterate returns an iterator receiving from c until it is closed.
+func (c *Channel) Iterate() Iterator {
+	return &LazyIterator{NextFunc: c.Recv}
+}
+
+func (c *Channel) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// drain returns an object left in the closed channel.
+func (c *Channel) drain() (Object, bool) {
+	select {
+	case val := <-c.queue:
+		return val, true
+	default:
+		return nil, false
+	}
+}
+
+// SelectCase is an operation waited for by Select. Value is sent to Channel
+// if Send is true, and received from Channel otherwise.
+type SelectCase struct {
+	Channel *Channel
+	Send    bool
+	Value   Object
+}
+
+// Select blocks until one of cases can proceed and performs it. It returns
+// the index of the case and the received object, which is nil if the
+// channel is closed. Sending to a closed channel is an error.
+func Select(cases []SelectCase) (int, Object, *Error) {
+	// Each case is waited for with a pair of a send or receive on the queue
+	// and a receive on the done channel, which fires when it is closed.
+	var selects []reflect.SelectCase
+	for _, c := range cases {
+		if c.Send && c.Channel.isClosed() {
+			return 0, nil, NewError("send on closed channel")
+		}
+		op := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c.Channel.queue)}
+		if c.Send {
+			op = reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(c.Channel.queue), Send: reflect.ValueOf(&c.Value).Elem()}
+		}
+		selects = append(selects, op, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c.Channel.done)})
+	}
+
+	chosen, recv, _ := reflect.Select(selects)
+	i := chosen / 2
+	switch {
+	case chosen%2 == 1 && cases[i].Send:
+		return i, nil, NewError("send on closed channel")
+	case chosen%2 == 1:
+		val, _ := cases[i].Channel.drain()
+		return i, val, nil
+	case cases[i].Send:
+		return i, nil, nil
+	default:
+		return i, recv.Interface().(Object), nil
+	}
+}
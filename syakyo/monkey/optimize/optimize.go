@@ -0,0 +1,437 @@
+// Package optimize rewrites Monkey programs into equivalent ones which are
+// evaluated faster.
+package optimize
+
+import (
+	"strconv"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/token"
+)
+
+// Program optimizes program in place and returns it. It folds the constant
+// prefix and infix expressions, eliminates the branches of if expressions
+// which are never taken and inlines the calls to trivial functions.
+//
+// The optimized program behaves the same as the original one provided that
+// it refers to no undefined variables, since the references in eliminated
+// branches are gone.
+func Program(program *ast.Program) *ast.Program {
+	o := &optimizer{
+		decls:     declarations(program),
+		inlinable: map[string]*inlinable{},
+	}
+	// A call is inlined only after the function is bound, which is the case
+	// for the statements following the binding.
+	for i, stmt := range program.Statements {
+		if stmt == nil {
+			continue
+		}
+		if modified, ok := ast.Modify(stmt, o.optimize).(ast.Statement); ok {
+			program.Statements[i] = modified
+		}
+		o.register(program.Statements[i])
+	}
+	program.Statements = eliminateDeadStatements(program.Statements)
+	return program
+}
+
+type optimizer struct {
+	decls     map[string]int // the number of declarations of each name in the program
+	inlinable map[string]*inlinable
+}
+
+// inlinable is a trivial function, which consists of an expression made of
+// literals, prefix and infix expressions and its parameters.
+type inlinable struct {
+	params []string
+	used   []bool // whether the body refers to each parameter
+	body   ast.Expression
+}
+
+func (o *optimizer) optimize(node ast.Node) ast.Node {
+	switch node := node.(type) {
+	case *ast.PrefixExpression:
+		return foldPrefix(node)
+	case *ast.InfixExpression:
+		return foldInfix(node)
+	case *ast.IfExpression:
+		return pruneIf(node)
+	case *ast.CallExpression:
+		return o.inline(node)
+	case *ast.BlockStatement:
+		node.Statements = eliminateDeadStatements(node.Statements)
+	}
+	return node
+}
+
+// register makes the function bound by stmt inlinable if it is trivial and
+// its name is never rebound nor shadowed anywhere in the program.
+func (o *optimizer) register(stmt ast.Statement) {
+	let, ok := stmt.(*ast.LetStatement)
+	if !ok || let.Name == nil || o.decls[let.Name.Value] != 1 {
+		return
+	}
+	lit, ok := let.Value.(*ast.FunctionLiteral)
+	if !ok || lit.Generator || lit.Rest != nil || lit.Body == nil || len(lit.Body.Statements) != 1 {
+		return
+	}
+
+	fn := &inlinable{}
+	index := map[string]int{}
+	for i, param := range lit.Parameters {
+		if param == nil || (i < len(lit.Defaults) && lit.Defaults[i] != nil) {
+			return
+		}
+		if _, ok := index[param.Value]; ok {
+			return
+		}
+		index[param.Value] = i
+		fn.params = append(fn.params, param.Value)
+	}
+	fn.used = make([]bool, len(fn.params))
+
+	switch stmt := lit.Body.Statements[0].(type) {
+	case *ast.ExpressionStatement:
+		fn.body = stmt.Expression
+	case *ast.ReturnStatement:
+		fn.body = stmt.ReturnValue
+	}
+	if fn.body == nil {
+		return
+	}
+
+	trivial := true
+	ast.Inspect(fn.body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case nil, *ast.PrefixExpression, *ast.InfixExpression, *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		case *ast.Identifier:
+			i, ok := index[node.Value]
+			if !ok {
+				trivial = false
+				break
+			}
+			fn.used[i] = true
+		default:
+			trivial = false
+		}
+		return trivial
+	})
+	if trivial {
+		o.inlinable[let.Name.Value] = fn
+	}
+}
+
+// inline replaces call with the body of the function if the function is
+// inlinable and the arguments are literals or variables, which can be
+// evaluated any number of times with the same result. An argument for an
+// unused parameter must be a literal since the variable may not be defined
+// yet, which is an error.
+func (o *optimizer) inline(call *ast.CallExpression) ast.Node {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return call
+	}
+	fn, ok := o.inlinable[ident.Value]
+	if !ok || len(call.Arguments) != len(fn.params) {
+		return call
+	}
+
+	args := map[string]ast.Expression{}
+	for i, arg := range call.Arguments {
+		switch arg.(type) {
+		case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		case *ast.Identifier:
+			if !fn.used[i] {
+				return call
+			}
+		default:
+			return call
+		}
+		args[fn.params[i]] = arg
+	}
+	return ast.Modify(substitute(fn.body, args), o.optimize)
+}
+
+// substitute returns a copy of expr, the body of an inlinable function, in
+// which the parameters are replaced with args.
+func substitute(expr ast.Expression, args map[string]ast.Expression) ast.Expression {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		if ident, ok := args[expr.Value].(*ast.Identifier); ok {
+			return &ast.Identifier{Token: ident.Token, Value: ident.Value}
+		}
+		return args[expr.Value]
+	case *ast.PrefixExpression:
+		return &ast.PrefixExpression{
+			Token:    expr.Token,
+			Operator: expr.Operator,
+			Right:    substitute(expr.Right, args),
+		}
+	case *ast.InfixExpression:
+		return &ast.InfixExpression{
+			Token:    expr.Token,
+			Left:     substitute(expr.Left, args),
+			Operator: expr.Operator,
+			Right:    substitute(expr.Right, args),
+		}
+	default:
+		// Literals are never modified, so they can be shared.
+		return expr
+	}
+}
+
+func foldPrefix(node *ast.PrefixExpression) ast.Node {
+	switch node.Operator {
+	case "!":
+		if truthy, ok := constantTruthiness(node.Right); ok {
+			return newBoolean(!truthy, node.Pos())
+		}
+	case "-":
+		if right, ok := node.Right.(*ast.IntegerLiteral); ok {
+			return newInteger(-right.Value, node.Pos())
+		}
+	}
+	return node
+}
+
+// foldInfix folds node unless its evaluation is an error, which is left to
+// be reported at runtime.
+func foldInfix(node *ast.InfixExpression) ast.Node {
+	if node.Operator == "==" || node.Operator == "!=" {
+		if equal, ok := equalLiterals(node.Left, node.Right); ok {
+			return newBoolean(equal == (node.Operator == "=="), node.Pos())
+		}
+	}
+	switch left := node.Left.(type) {
+	case *ast.IntegerLiteral:
+		right, ok := node.Right.(*ast.IntegerLiteral)
+		if !ok {
+			break
+		}
+		switch node.Operator {
+		case "+":
+			return newInteger(left.Value+right.Value, node.Pos())
+		case "-":
+			return newInteger(left.Value-right.Value, node.Pos())
+		case "*":
+			return newInteger(left.Value*right.Value, node.Pos())
+		case "/":
+			if right.Value != 0 {
+				return newInteger(left.Value/right.Value, node.Pos())
+			}
+		case "==":
+			return newBoolean(left.Value == right.Value, node.Pos())
+		case "!=":
+			return newBoolean(left.Value != right.Value, node.Pos())
+		case "<":
+			return newBoolean(left.Value < right.Value, node.Pos())
+		case ">":
+			return newBoolean(left.Value > right.Value, node.Pos())
+		}
+	case *ast.StringLiteral:
+		right, ok := node.Right.(*ast.StringLiteral)
+		if !ok {
+			break
+		}
+		switch node.Operator {
+		case "+":
+			return newString(left.Value+right.Value, node.Pos())
+		case "<":
+			return newBoolean(left.Value < right.Value, node.Pos())
+		case ">":
+			return newBoolean(left.Value > right.Value, node.Pos())
+		}
+	}
+	return node
+}
+
+// equalLiterals reports whether a and b are literals and whether they are
+// equal if so. Literals of different types are never equal.
+func equalLiterals(a, b ast.Expression) (equal bool, ok bool) {
+	switch b.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean, *ast.Null:
+	default:
+		return false, false
+	}
+	switch a := a.(type) {
+	case *ast.IntegerLiteral:
+		b, ok := b.(*ast.IntegerLiteral)
+		return ok && a.Value == b.Value, true
+	case *ast.StringLiteral:
+		b, ok := b.(*ast.StringLiteral)
+		return ok && a.Value == b.Value, true
+	case *ast.Boolean:
+		b, ok := b.(*ast.Boolean)
+		return ok && a.Value == b.Value, true
+	case *ast.Null:
+		_, ok := b.(*ast.Null)
+		return ok, true
+	default:
+		return false, false
+	}
+}
+
+// pruneIf eliminates the branch of node which is never taken. The taken
+// branch is kept as a block since it has its own scope.
+func pruneIf(node *ast.IfExpression) ast.Node {
+	truthy, ok := constantTruthiness(node.Condition)
+	if !ok {
+		return node
+	}
+	pos := node.Condition.Pos()
+	switch {
+	case truthy:
+		node.Alternative = nil
+	case node.Alternative != nil:
+		truthy = true
+		node.Consequence, node.Alternative = node.Alternative, nil
+	default:
+		// The if expression results in null.
+		node.Consequence = &ast.BlockStatement{Token: node.Consequence.Token}
+	}
+	node.Condition = newBoolean(truthy, pos)
+	return node
+}
+
+// eliminateDeadStatements removes the if expressions whose branch is never
+// taken from stmts and replaces those whose branch is always taken with the
+// statements of the branch if it declares no variables. The last statement
+// is kept as is since its value is the value of stmts.
+func eliminateDeadStatements(stmts []ast.Statement) []ast.Statement {
+	var result []ast.Statement
+	for i, stmt := range stmts {
+		ie := constantIf(stmt)
+		if ie == nil || i == len(stmts)-1 {
+			result = append(result, stmt)
+			continue
+		}
+		if !ie.Condition.(*ast.Boolean).Value {
+			continue
+		}
+		if declares(ie.Consequence.Statements) {
+			result = append(result, stmt)
+			continue
+		}
+		result = append(result, ie.Consequence.Statements...)
+	}
+	return result
+}
+
+// constantIf returns the if expression of stmt if it is pruned by pruneIf.
+func constantIf(stmt ast.Statement) *ast.IfExpression {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return nil
+	}
+	ie, ok := es.Expression.(*ast.IfExpression)
+	if !ok || ie.Alternative != nil {
+		return nil
+	}
+	if _, ok := ie.Condition.(*ast.Boolean); !ok {
+		return nil
+	}
+	return ie
+}
+
+func declares(stmts []ast.Statement) bool {
+	for _, stmt := range stmts {
+		switch stmt.(type) {
+		case *ast.LetStatement, *ast.StructStatement:
+			return true
+		}
+	}
+	return false
+}
+
+// constantTruthiness reports whether expr is a literal and whether it is
+// truthy if so.
+func constantTruthiness(expr ast.Expression) (truthy bool, ok bool) {
+	switch expr := expr.(type) {
+	case *ast.Boolean:
+		return expr.Value, true
+	case *ast.IntegerLiteral, *ast.StringLiteral:
+		return true, true
+	case *ast.Null:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// declarations counts the declarations of each name in program, including
+// the parameters and the names bound by patterns.
+func declarations(program *ast.Program) map[string]int {
+	decls := map[string]int{}
+	declare := func(ident *ast.Identifier) {
+		if ident != nil {
+			decls[ident.Value]++
+		}
+	}
+	declarePattern := func(pattern ast.Expression) {
+		if pattern == nil {
+			return
+		}
+		ast.Inspect(pattern, func(node ast.Node) bool {
+			if ident, ok := node.(*ast.Identifier); ok {
+				declare(ident)
+			}
+			return true
+		})
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			declare(node.Name)
+			declarePattern(node.Pattern)
+		case *ast.FunctionLiteral:
+			declare(node.Name)
+			for _, param := range node.Parameters {
+				declare(param)
+			}
+			for _, pattern := range node.Patterns {
+				declarePattern(pattern)
+			}
+			declare(node.Rest)
+		case *ast.StructStatement:
+			declare(node.Name)
+			if len(node.Methods) > 0 {
+				decls["self"]++
+			}
+		case *ast.ForStatement:
+			declarePattern(node.Pattern)
+		case *ast.MatchExpression:
+			for _, arm := range node.Arms {
+				declarePattern(arm.Pattern)
+			}
+		}
+		return true
+	})
+	return decls
+}
+
+func newInteger(value int64, pos token.Position) *ast.IntegerLiteral {
+	return &ast.IntegerLiteral{
+		Token: token.Token{Type: token.TypeInt, Literal: strconv.FormatInt(value, 10), Pos: pos},
+		Value: value,
+	}
+}
+
+func newString(value string, pos token.Position) *ast.StringLiteral {
+	return &ast.StringLiteral{
+		Token: token.Token{Type: token.TypeString, Literal: value, Pos: pos},
+		Value: value,
+	}
+}
+
+func newBoolean(value bool, pos token.Position) *ast.Boolean {
+	typ := token.TypeFalse
+	if value {
+		typ = token.TypeTrue
+	}
+	return &ast.Boolean{
+		Token: token.Token{Type: typ, Literal: strconv.FormatBool(value), Pos: pos},
+		Value: value,
+	}
+}
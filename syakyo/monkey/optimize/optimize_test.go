@@ -0,0 +1,79 @@
+package optimize_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/optimize"
+	"github.com/daichimukai/x/syakyo/monkey/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgram(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect []string
+	}{
+		// constant folding
+		{`2 * 60 * 60`, []string{"7200"}},
+		{`-(1 + 2) * 3 / 2`, []string{"-4"}},
+		{`1 < 2; 1 > 2; 1 == 1; 1 != 1`, []string{"true", "false", "true", "false"}},
+		{`!true; !!false; !0; !"a"; !null`, []string{"false", "false", "false", "false", "true"}},
+		{`true == false; true != false; null == null; null != false`, []string{"false", "true", "true", "true"}},
+		{`"a" == "a"; "a" != "b"; "a" < "b"; "b" > "ab"`, []string{"true", "true", "true", "true"}},
+		{`1 == "1"; 1 != true; "a" == null`, []string{"false", "true", "false"}},
+		{`x == 1; [1] == [1]; 1 < x`, []string{"(x == 1)", "([1] == [1])", "(1 < x)"}},
+		{`"foo" + "bar" + "baz"`, []string{`"foobarbaz"`}},
+		{`x * 60 * 60`, []string{"((x * 60) * 60)"}},
+		{`fn(x) { x + 2 * 3 }`, []string{"fn(x) { (x + 6) }"}},
+		{`[1 + 1, {"a" + "b": -(2)}[1 - 1]]`, []string{`[2, ({"ab": -2}[0])]`}},
+		// errors are left to the evaluation
+		{`1 / 0; -true; "a" - "b"; true < false; 1 + "a"; 1 < "a"`, []string{"(1 / 0)", "(-true)", `("a" - "b")`, "(true < false)", `(1 + "a")`, `(1 < "a")`}},
+		// dead branch elimination
+		{`if (1 < 2) { 1 } else { 2 }`, []string{"if (true) { 1 }"}},
+		{`if (0) { 1 }`, []string{"if (true) { 1 }"}},
+		{`if (!true) { 1 } else { 2 }`, []string{"if (true) { 2 }"}},
+		{`if (false) { 1 }`, []string{"if (false) {}"}},
+		{`if (x) { 1 } else { 2 }`, []string{"if (x) { 1 } else { 2 }"}},
+		{`if (false) { puts(1) }; puts(2)`, []string{"puts(2)"}},
+		{`if (true) { puts(1); puts(2) }; puts(3)`, []string{"puts(1)", "puts(2)", "puts(3)"}},
+		{`if (true) { let x = 1; puts(x) }; puts(3)`, []string{"if (true) { let x = 1; puts(x) }", "puts(3)"}},
+		{`fn() { if (true) { puts(1) }; 2 }`, []string{"fn() { puts(1); 2 }"}},
+		// inlining
+		{
+			`let double = fn(x) { x * 2 }; double(3); double(y); double(1 + 2); double(f(1))`,
+			[]string{"let double = fn(x) { (x * 2) };", "6", "(y * 2)", "6", "double(f(1))"},
+		},
+		{
+			`const sec = fn(h) { return h * 60 * 60; }; sec(2)`,
+			[]string{"const sec = fn(h) { return ((h * 60) * 60); };", "7200"},
+		},
+		{
+			`let sub = fn(a, b) { a - b }; sub(b, a); sub(1)`,
+			[]string{"let sub = fn(a, b) { (a - b) };", "(b - a)", "sub(1)"},
+		},
+		{`let k = fn(x) { 1 }; k(2); k(y)`, []string{"let k = fn(x) { 1 };", "1", "k(y)"}},
+		{`let f = fn(x) { x }; f(f(1))`, []string{"let f = fn(x) { x };", "1"}},
+		{`f(1); let f = fn(x) { x }; f(1)`, []string{"f(1)", "let f = fn(x) { x };", "1"}},
+		{`let f = fn(x) { x }; let f = fn(x) { 2 }; f(1)`, []string{"let f = fn(x) { x };", "let f = fn(x) { 2 };", "f(1)"}},
+		{`let f = fn(x) { x }; let g = fn(f) { f(1) }; g(f)`, []string{"let f = fn(x) { x };", "let g = fn(f) { f(1) };", "g(f)"}},
+		{`let f = fn(x) { x }; f(...[1])`, []string{"let f = fn(x) { x };", "f(...[1])"}},
+		{`let f = fn(x) { y }; f(1)`, []string{"let f = fn(x) { y };", "f(1)"}},
+		{`let f = fn(x) { len(x) }; f(1)`, []string{"let f = fn(x) { len(x) };", "f(1)"}},
+		{`let f = fn(x, y = 1) { x }; f(1, 2)`, []string{"let f = fn(x, y = 1) { x };", "f(1, 2)"}},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := parser.New(lexer.New(tt.input)).ParseProgram()
+			require.NoError(t, err)
+
+			var got []string
+			for _, stmt := range optimize.Program(program).Statements {
+				got = append(got, stmt.String())
+			}
+			require.Equal(t, strings.Join(tt.expect, "\n"), strings.Join(got, "\n"))
+		})
+	}
+}
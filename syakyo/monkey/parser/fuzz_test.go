@@ -0,0 +1,35 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func FuzzParseProgram(f *testing.F) {
+	for _, seed := range []string{
+		"let x = 1 + 2 * -3; x",
+		`let f = fn(a, [b, c], {d} = {"d": 1}, ...rest) { return a; }; f(...[1, 2]);`,
+		`if (x < y) { "a\"b" } else { "${x}\n" }`,
+		`match (x) { [a, ...r] if a > 1 => r, {k} => k[1:], _ => null }`,
+		"struct Point { x, y, fn norm() { self.x * self.x } }\np.x = 1;",
+		"for ([k, v] in h) { yield k; };\nconst c = fn() { yield 1 }",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		program, err := parser.New(lexer.New(input)).ParseProgram()
+		if err != nil {
+			return
+		}
+
+		// The string form of a program is parsed back into the same program.
+		src := program.String()
+		reparsed, err := parser.New(lexer.New(src)).ParseProgram()
+		require.NoError(t, err, "source: %q", src)
+		require.Equal(t, src, reparsed.String())
+	})
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/token"
+)
+
+// parseForStatement parses `for (pattern in iterable) { body }`.
+func (p *Parser) parseForStatement() (*ast.ForStatement, error) {
+	stmt := &ast.ForStatement{
+		Token: p.curToken,
+	}
+	if !p.expectPeek(token.TypeLeftParen) {
+		return nil, fmt.Errorf("expected (, got %s", p.peekToken.Literal)
+	}
+
+	p.nextToken()
+	switch p.curToken.Type {
+	case token.TypeIdent, token.TypeLeftBraket, token.TypeLeftBrace:
+		stmt.Pattern = p.parsePattern()
+	}
+	if stmt.Pattern == nil {
+		return nil, fmt.Errorf("malformed loop variable at %s", p.curToken.Pos)
+	}
+
+	if !p.expectPeek(token.TypeIn) {
+		return nil, fmt.Errorf("expected in, got %s", p.peekToken.Literal)
+	}
+	p.nextToken()
+	if stmt.Iterable = p.parseExpression(priorityLowest); stmt.Iterable == nil {
+		return nil, fmt.Errorf("malformed iterable at %s", p.curToken.Pos)
+	}
+	if !p.expectPeek(token.TypeRightParen) {
+		return nil, fmt.Errorf("expected ), got %s", p.peekToken.Literal)
+	}
+	if !p.expectPeek(token.TypeLeftBrace) {
+		return nil, fmt.Errorf("expected {, got %s", p.peekToken.Literal)
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekToken.Type == token.TypeSemicolon {
+		p.nextToken()
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseYieldExpression() ast.Expression {
+	expr := &ast.YieldExpression{
+		Token: p.curToken,
+	}
+
+	p.nextToken()
+	if expr.Value = p.parseExpression(priorityLowest); expr.Value == nil {
+		return nil
+	}
+
+	return expr
+}
+
+// containsYield reports whether body contains yield expressions, not
+// counting the ones in nested function literals.
+func containsYield(body *ast.BlockStatement) bool {
+	found := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.YieldExpression:
+			found = true
+		case *ast.FunctionLiteral:
+			return false
+		}
+		return !found
+	})
+	return found
+}
@@ -12,15 +12,17 @@ import (
 
 const (
 	priorityLowest      int = iota
+	priorityAssign          // X.Y = Z
 	priorityEquals          // ==
 	priorityLessGreater     // > or <
 	prioritySum             // +
 	priorityProduct         // *
 	priorityPrefix          // -X or !X
-	priorityCall            // X(Y)
+	priorityCall            // X(Y), X[Y] or X.Y
 )
 
 var precedences = map[token.TokenType]int{
+	token.TypeAssign:     priorityAssign,
 	token.TypeEq:         priorityEquals,
 	token.TypeNotEq:      priorityEquals,
 	token.TypeLt:         priorityLessGreater,
@@ -31,6 +33,7 @@ var precedences = map[token.TokenType]int{
 	token.TypeSlash:      priorityProduct,
 	token.TypeLeftParen:  priorityCall,
 	token.TypeLeftBraket: priorityCall,
+	token.TypeDot:        priorityCall,
 }
 
 type (
@@ -45,6 +48,10 @@ type Parser struct {
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
+
+	// err is the first error found inside an expression, which is more
+	// specific than the error of the statement containing it.
+	err error
 }
 
 func New(l *lexer.Lexer) *Parser {
@@ -56,15 +63,20 @@ func New(l *lexer.Lexer) *Parser {
 
 	p.registerPrefix(token.TypeTrue, p.parseBoolean)
 	p.registerPrefix(token.TypeFalse, p.parseBoolean)
+	p.registerPrefix(token.TypeNull, p.parseNull)
 	p.registerPrefix(token.TypeIdent, p.parseIdentifier)
 	p.registerPrefix(token.TypeInt, p.parseIntegerLiteral)
 	p.registerPrefix(token.TypeString, p.parseStringLiteral)
+	p.registerPrefix(token.TypeTemplate, p.parseInterpolatedString)
 	p.registerPrefix(token.TypeMinus, p.parsePrefixExpression)
 	p.registerPrefix(token.TypeBang, p.parsePrefixExpression)
 	p.registerPrefix(token.TypeLeftParen, p.parseGroupedExpression)
 	p.registerPrefix(token.TypeLeftBraket, p.parseArrayExpression)
+	p.registerPrefix(token.TypeLeftBrace, p.parseHashLiteral)
 	p.registerPrefix(token.TypeIf, p.parseIfExpression)
 	p.registerPrefix(token.TypeFunction, p.parseFunctionLiteral)
+	p.registerPrefix(token.TypeEllipsis, p.parseSpreadExpression)
+	p.registerPrefix(token.TypeYield, p.parseYieldExpression)
 
 	p.registerInfix(token.TypePlus, p.parseInfixExpression)
 	p.registerInfix(token.TypeMinus, p.parseInfixExpression)
@@ -76,6 +88,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.TypeGt, p.parseInfixExpression)
 	p.registerInfix(token.TypeLeftParen, p.parseCallExpression)
 	p.registerInfix(token.TypeLeftBraket, p.parseIndexExpression)
+	p.registerInfix(token.TypeDot, p.parseMemberExpression)
+	p.registerInfix(token.TypeAssign, p.parseAssignExpression)
 
 	// Set curToken and peekToken
 	p.nextToken()
@@ -115,7 +129,15 @@ func (p *Parser) ParseProgram() (*ast.Program, error) {
 	program := &ast.Program{}
 
 	for p.curToken.Type != token.TypeEof {
+		if p.curToken.Type == token.TypeSemicolon {
+			// an empty statement
+			p.nextToken()
+			continue
+		}
 		stmt, err := p.parseStatement()
+		if p.err != nil {
+			return nil, p.err
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -125,12 +147,24 @@ func (p *Parser) ParseProgram() (*ast.Program, error) {
 	return program, nil
 }
 
+// errorf records an error found inside an expression unless one is already
+// recorded.
+func (p *Parser) errorf(format string, a ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf(format, a...)
+	}
+}
+
 func (p *Parser) parseStatement() (ast.Statement, error) {
 	switch p.curToken.Type {
-	case token.TypeLet:
+	case token.TypeLet, token.TypeConst:
 		return p.parseLetStatement()
 	case token.TypeReturn:
 		return p.parseReturnStatement()
+	case token.TypeStruct:
+		return p.parseStructStatement()
+	case token.TypeFor:
+		return p.parseForStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -139,17 +173,28 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 func (p *Parser) parseLetStatement() (*ast.LetStatement, error) {
 	stmt := &ast.LetStatement{
 		Token: p.curToken,
+		Const: p.curToken.Type == token.TypeConst,
 	}
-	if ok := p.expectPeek(token.TypeIdent); !ok {
-		return nil, fmt.Errorf("expected identifier, got %s", p.peekToken.Literal)
+	switch p.peekToken.Type {
+	case token.TypeLeftBraket, token.TypeLeftBrace:
+		p.nextToken()
+		if stmt.Pattern = p.parsePattern(); stmt.Pattern == nil {
+			return nil, fmt.Errorf("malformed pattern at %s", stmt.Token.Pos)
+		}
+	case token.TypeIdent:
+		p.nextToken()
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	default:
+		return nil, fmt.Errorf("expected identifier or pattern, got %s", p.peekToken.Literal)
 	}
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	if ok := p.expectPeek(token.TypeAssign); !ok {
 		return nil, fmt.Errorf("expected =, got %s", p.peekToken.Literal)
 	}
 	p.nextToken()
 
-	stmt.Value = p.parseExpression(priorityLowest)
+	if stmt.Value = p.parseExpression(priorityLowest); stmt.Value == nil {
+		return nil, fmt.Errorf("malformed value at %s", p.curToken.Pos)
+	}
 	if !p.expectPeek(token.TypeSemicolon) {
 		return nil, fmt.Errorf("expected ;, got %s", p.peekToken.Literal)
 	}
@@ -161,7 +206,9 @@ func (p *Parser) parseReturnStatement() (*ast.ReturnStatement, error) {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 	p.nextToken()
 
-	stmt.ReturnValue = p.parseExpression(priorityLowest)
+	if stmt.ReturnValue = p.parseExpression(priorityLowest); stmt.ReturnValue == nil {
+		return nil, fmt.Errorf("malformed return value at %s", p.curToken.Pos)
+	}
 	if !p.expectPeek(token.TypeSemicolon) {
 		return nil, fmt.Errorf("expected ;, got %s", p.peekToken.Literal)
 	}
@@ -171,8 +218,10 @@ func (p *Parser) parseReturnStatement() (*ast.ReturnStatement, error) {
 
 func (p *Parser) parseExpressionStatement() (*ast.ExpressionStatement, error) {
 	stmt := &ast.ExpressionStatement{
-		Token:      p.curToken,
-		Expression: p.parseExpression(priorityLowest),
+		Token: p.curToken,
+	}
+	if stmt.Expression = p.parseExpression(priorityLowest); stmt.Expression == nil {
+		return nil, fmt.Errorf("malformed expression at %s", stmt.Token.Pos)
 	}
 
 	if p.peekToken.Type == token.TypeSemicolon {
@@ -189,7 +238,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	}
 	expr := prefix()
 
-	for p.peekToken.Type != token.TypeSemicolon && precedence < p.peekPrecedence() {
+	for expr != nil && p.peekToken.Type != token.TypeSemicolon && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			break
@@ -208,7 +257,14 @@ func (p *Parser) parseBoolean() ast.Expression {
 	}
 }
 
+func (p *Parser) parseNull() ast.Expression {
+	return &ast.Null{Token: p.curToken}
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
+	if p.curToken.Literal == "match" && p.peekToken.Type == token.TypeLeftParen {
+		return p.parseMatchExpression()
+	}
 	return &ast.Identifier{
 		Token: p.curToken,
 		Value: p.curToken.Literal,
@@ -234,6 +290,35 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	}
 }
 
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	parts := lexer.SplitTemplate(p.curToken.Literal, p.curToken.Pos)
+	if parts == nil {
+		return nil
+	}
+	for _, part := range parts {
+		if !part.Interpolation {
+			str.Parts = append(str.Parts, &ast.StringLiteral{
+				Token: token.Token{Type: token.TypeString, Literal: part.Value, Pos: part.Pos},
+				Value: part.Value,
+			})
+			continue
+		}
+
+		// An embedded expression must be exactly one expression.
+		sub := New(lexer.NewAt(part.Value, part.Pos))
+		if sub.curToken.Type == token.TypeEof {
+			return nil
+		}
+		expr := sub.parseExpression(priorityLowest)
+		if expr == nil || !sub.expectPeek(token.TypeEof) {
+			return nil
+		}
+		str.Parts = append(str.Parts, expr)
+	}
+	return str
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -241,16 +326,32 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	}
 
 	p.nextToken()
-	expression.Right = p.parseExpression(priorityPrefix)
+	if expression.Right = p.parseExpression(priorityPrefix); expression.Right == nil {
+		return nil
+	}
 
 	return expression
 }
 
+func (p *Parser) parseSpreadExpression() ast.Expression {
+	expr := &ast.SpreadExpression{
+		Token: p.curToken,
+	}
+
+	p.nextToken()
+	expr.Value = p.parseExpression(priorityPrefix)
+	if expr.Value == nil {
+		return nil
+	}
+
+	return expr
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken()
 
 	exp := p.parseExpression(priorityLowest)
-	if !p.expectPeek(token.TypeRightParen) {
+	if exp == nil || !p.expectPeek(token.TypeRightParen) {
 		return nil
 	}
 
@@ -258,50 +359,123 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 }
 
 func (p *Parser) parseArrayExpression() ast.Expression {
-	return &ast.ArrayLiteral{
-		Token:    p.curToken,
-		Elements: p.parseExpressionList(token.TypeRightBraket),
+	array := &ast.ArrayLiteral{
+		Token: p.curToken,
+	}
+	var ok bool
+	if array.Elements, ok = p.parseExpressionList(token.TypeRightBraket); !ok {
+		return nil
+	}
+	return array
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{
+		Token: p.curToken,
+	}
+
+	for p.peekToken.Type != token.TypeRightBrace {
+		p.nextToken()
+		key := p.parseExpression(priorityLowest)
+		if key == nil {
+			return nil
+		}
+		if !p.expectPeek(token.TypeColon) {
+			return nil
+		}
+		p.nextToken()
+		value := p.parseExpression(priorityLowest)
+		if value == nil {
+			return nil
+		}
+		hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{Key: key, Value: value})
+
+		if p.peekToken.Type != token.TypeRightBrace && !p.expectPeek(token.TypeComma) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.TypeRightBrace) {
+		return nil
 	}
+
+	return hash
 }
 
-func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+// parseExpressionList parses the comma-separated expressions up to end. It
+// reports whether the list is well-formed.
+func (p *Parser) parseExpressionList(end token.TokenType) ([]ast.Expression, bool) {
 	var list []ast.Expression
 
 	if p.peekToken.Type == end {
 		p.nextToken()
-		return list
+		return list, true
 	}
 
-	p.nextToken()
-	list = append(list, p.parseExpression(priorityLowest))
-
-	for p.peekToken.Type == token.TypeComma {
+	for {
 		p.nextToken()
+		expr := p.parseExpression(priorityLowest)
+		if expr == nil {
+			return nil, false
+		}
+		list = append(list, expr)
+
+		if p.peekToken.Type != token.TypeComma {
+			break
+		}
 		p.nextToken()
-		list = append(list, p.parseExpression(priorityLowest))
 	}
 
-	if p.peekToken.Type != end {
+	return list, p.expectPeek(end)
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	tok := p.curToken
+	p.nextToken()
+
+	var index ast.Expression
+	if p.curToken.Type != token.TypeColon {
+		if index = p.parseExpression(priorityLowest); index == nil {
+			return nil
+		}
+		if p.peekToken.Type == token.TypeColon {
+			p.nextToken()
+		}
+	}
+	if p.curToken.Type == token.TypeColon {
+		return p.parseSliceExpression(tok, left, index)
+	}
+
+	if p.peekToken.Type != token.TypeRightBraket {
 		return nil
 	}
 
 	p.nextToken()
-	return list
+	return &ast.IndexExpression{
+		Token: tok,
+		Left:  left,
+		Index: index,
+	}
 }
 
-func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	expr := &ast.IndexExpression{
-		Token: p.curToken,
+// parseSliceExpression parses the rest of a slice expression after the colon.
+func (p *Parser) parseSliceExpression(tok token.Token, left, low ast.Expression) ast.Expression {
+	expr := &ast.SliceExpression{
+		Token: tok,
 		Left:  left,
+		Low:   low,
 	}
-	p.nextToken()
-	expr.Index = p.parseExpression(priorityLowest)
 
 	if p.peekToken.Type != token.TypeRightBraket {
-		return nil
+		p.nextToken()
+		if expr.High = p.parseExpression(priorityLowest); expr.High == nil {
+			return nil
+		}
 	}
 
-	p.nextToken()
+	if !p.expectPeek(token.TypeRightBraket) {
+		return nil
+	}
 	return expr
 }
 
@@ -315,7 +489,7 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	}
 	p.nextToken()
 	expr.Condition = p.parseExpression(priorityLowest)
-	if !p.expectPeek(token.TypeRightParen) {
+	if expr.Condition == nil || !p.expectPeek(token.TypeRightParen) {
 		return nil
 	}
 
@@ -341,6 +515,10 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 
 	for p.curToken.Type != token.TypeRightBrace && p.curToken.Type != token.TypeEof {
+		if p.curToken.Type == token.TypeSemicolon {
+			p.nextToken()
+			continue
+		}
 		if stmt, err := p.parseStatement(); err == nil {
 			block.Statements = append(block.Statements, stmt)
 		}
@@ -353,50 +531,97 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{
 		Token: p.curToken,
 	}
+	if p.peekToken.Type == token.TypeIdent {
+		p.nextToken()
+		lit.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
 	if !p.expectPeek(token.TypeLeftParen) {
 		return nil
 	}
-	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(lit) {
+		return nil
+	}
 
 	if !p.expectPeek(token.TypeLeftBrace) {
 		return nil
 	}
 	lit.Body = p.parseBlockStatement()
+	lit.Generator = containsYield(lit.Body)
 
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	var identifiers []*ast.Identifier
-
+// parseFunctionParameters parses the parameter list of lit, i.e.
+// `a, [b, c], d = default, ...rest)`. It reports whether the list is well-formed.
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
 	if p.peekToken.Type == token.TypeRightParen {
 		p.nextToken()
-		return identifiers
+		return true
 	}
 
-	p.nextToken()
+	hasDefault, hasPattern := false, false
+	for {
+		p.nextToken()
 
-	ident := &ast.Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
-	}
-	identifiers = append(identifiers, ident)
+		if p.curToken.Type == token.TypeEllipsis {
+			if !p.expectPeek(token.TypeIdent) {
+				p.errorf("expected parameter name, got %s at %s", p.peekToken.Literal, p.peekToken.Pos)
+				return false
+			}
+			lit.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			// A rest parameter must be the last one.
+			break
+		}
 
-	for p.peekToken.Type == token.TypeComma {
-		p.nextToken()
-		p.nextToken()
-		ident := &ast.Identifier{
-			Token: p.curToken,
-			Value: p.curToken.Literal,
+		start := p.curToken
+		var ident *ast.Identifier
+		var pattern ast.Expression
+		switch p.curToken.Type {
+		case token.TypeIdent:
+			ident = &ast.Identifier{
+				Token: p.curToken,
+				Value: p.curToken.Literal,
+			}
+		case token.TypeLeftBraket, token.TypeLeftBrace:
+			if pattern = p.parsePattern(); pattern == nil {
+				return false
+			}
+			hasPattern = true
+		default:
+			p.errorf("expected parameter name, got %s at %s", p.curToken.Literal, p.curToken.Pos)
+			return false
+		}
+		var def ast.Expression
+		if p.peekToken.Type == token.TypeAssign {
+			p.nextToken()
+			p.nextToken()
+			def = p.parseExpression(priorityLowest)
+			if def == nil {
+				return false
+			}
+			hasDefault = true
+		} else if hasDefault {
+			p.errorf("parameter without default follows parameter with default at %s", start.Pos)
+			return false
 		}
-		identifiers = append(identifiers, ident)
+		lit.Parameters = append(lit.Parameters, ident)
+		lit.Defaults = append(lit.Defaults, def)
+		lit.Patterns = append(lit.Patterns, pattern)
+
+		if p.peekToken.Type != token.TypeComma {
+			break
+		}
+		p.nextToken()
 	}
 
-	if !p.expectPeek(token.TypeRightParen) {
-		return nil
+	if !hasDefault {
+		lit.Defaults = nil
+	}
+	if !hasPattern {
+		lit.Patterns = nil
 	}
 
-	return identifiers
+	return p.expectPeek(token.TypeRightParen)
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
@@ -408,7 +633,9 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 
 	precedence := p.curPrecedence()
 	p.nextToken()
-	expression.Right = p.parseExpression(precedence)
+	if expression.Right = p.parseExpression(precedence); expression.Right == nil {
+		return nil
+	}
 
 	return expression
 }
@@ -418,33 +645,11 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 		Token:    p.curToken,
 		Function: function,
 	}
-	exp.Arguments = p.parseCallArguments()
-	return exp
-
-}
-
-func (p *Parser) parseCallArguments() []ast.Expression {
-	var args []ast.Expression
-
-	if p.peekToken.Type == token.TypeRightParen {
-		p.nextToken()
-		return args
-	}
-
-	p.nextToken()
-	args = append(args, p.parseExpression(priorityLowest))
-
-	for p.peekToken.Type == token.TypeComma {
-		p.nextToken()
-		p.nextToken()
-		args = append(args, p.parseExpression(priorityLowest))
-	}
-
-	if !p.expectPeek(token.TypeRightParen) {
+	var ok bool
+	if exp.Arguments, ok = p.parseExpressionList(token.TypeRightParen); !ok {
 		return nil
 	}
-
-	return args
+	return exp
 }
 
 func (p *Parser) expectPeek(typ token.TokenType) bool {
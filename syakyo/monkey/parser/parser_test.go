@@ -49,6 +49,91 @@ func TestLetStatement(t *testing.T) {
 	}
 }
 
+func TestLetStatementWithPattern(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`let [a, b, ...rest] = arr;`, `let [a, b, ...rest] = arr;`},
+		{`let {name, age} = h;`, `let {"name": name, "age": age} = h;`},
+		{`let [{x}, [_, y]] = f();`, `let [{"x": x}, [_, y]] = f();`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parseProgram(t, tt.input)
+			require.Equal(t, 1, len(program.Statements))
+			letStmt, ok := program.Statements[0].(*ast.LetStatement)
+			require.True(t, ok)
+			require.Nil(t, letStmt.Name)
+			require.NotNil(t, letStmt.Pattern)
+			require.Equal(t, tt.expect, letStmt.String())
+		})
+	}
+}
+
+func TestConstStatement(t *testing.T) {
+	program := parseProgram(t, `const x = 1; let y = 2; const [a, b] = c;`)
+	require.Equal(t, 3, len(program.Statements))
+
+	for i, expect := range []bool{true, false, true} {
+		letStmt, ok := program.Statements[i].(*ast.LetStatement)
+		require.True(t, ok)
+		require.Equal(t, expect, letStmt.Const)
+	}
+	require.Equal(t, "const x = 1; let y = 2; const [a, b] = c;", program.String())
+}
+
+func TestMalformedLetStatement(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`let 1 = 1;`, "expected identifier or pattern, got 1"},
+		{`let [a + b] = 1;`, "malformed pattern at 1:1"},
+		{`let {a b} = 1;`, "malformed pattern at 1:1"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := parser.New(lexer.New(tt.input)).ParseProgram()
+			require.EqualError(t, err, tt.expect)
+		})
+	}
+}
+
+func TestMalformedExpression(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`-`, "malformed expression at 1:1"},
+		{`1 +`, "malformed expression at 1:1"},
+		{`0!`, "malformed expression at 1:2"},
+		{`@`, "malformed expression at 1:1"},
+		{`f(1,`, "malformed expression at 1:1"},
+		{`[1, 2`, "malformed expression at 1:1"},
+		{`a[]`, "malformed expression at 1:1"},
+		{`if () { 1 }`, "malformed expression at 1:1"},
+		{`"${"`, "malformed expression at 1:1"},
+		{`let x = ;`, "malformed value at 1:9"},
+		{`return @;`, "malformed return value at 1:8"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := parser.New(lexer.New(tt.input)).ParseProgram()
+			require.EqualError(t, err, tt.expect)
+		})
+	}
+}
+
+func TestEmptyStatement(t *testing.T) {
+	program := parseProgram(t, ";; 1;; fn() { ; 2; };")
+	require.Len(t, program.Statements, 2)
+	require.Equal(t, "1; fn() { 2 }", program.String())
+}
+
 func TestReturnStatement(t *testing.T) {
 	testcases := []struct {
 		input  string
@@ -161,6 +246,63 @@ func TestStringLiteralExpression(t *testing.T) {
 	require.Equal(t, "hello world", literal.Value)
 }
 
+func TestStringLiteralString(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`"a\"b\\c\n\t\r"`, `"a\"b\\c\n\t\r"`},
+		{`"\${a} $ $$"`, `"\${a} $ $$"`},
+		{"`${a}\\q`", `"\${a}\\q"`},
+		// an embedded string literal is the same as the text
+		{`"a\"${"b\n"}\${c}${d}"`, `"a\"b\n\${c}${d}"`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parseProgram(t, tt.input)
+			require.Equal(t, tt.expect, program.String())
+			reparsed := parseProgram(t, tt.expect)
+			require.Equal(t, tt.expect, reparsed.String())
+		})
+	}
+}
+
+func TestInterpolatedStringExpression(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`"a ${b} c"`, `"a ${b} c"`},
+		{`"${a + b * c}"`, `"${(a + (b * c))}"`},
+		{`"${"x${y}"}!"`, `"${"x${y}"}!"`},
+		{`"${{1: 2}[1]}"`, `"${({1: 2}[1])}"`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := parser.New(lexer.New(tt.input)).ParseProgram()
+			require.NoError(t, err)
+
+			require.Equal(t, 1, len(program.Statements))
+			stmt := program.Statements[0].(*ast.ExpressionStatement)
+			str, ok := stmt.Expression.(*ast.InterpolatedString)
+			require.True(t, ok)
+			require.Equal(t, tt.expect, str.String())
+		})
+	}
+}
+
+func TestInterpolatedStringPosition(t *testing.T) {
+	program, err := parser.New(lexer.New("let s = \"a\n${b}\";")).ParseProgram()
+	require.NoError(t, err)
+
+	str := program.Statements[0].(*ast.LetStatement).Value.(*ast.InterpolatedString)
+	require.Len(t, str.Parts, 2)
+	require.Equal(t, "1:10", str.Parts[0].Pos().String())
+	require.Equal(t, "2:3", str.Parts[1].Pos().String())
+}
+
 func TestArrayLiteralExpression(t *testing.T) {
 	input := `[1, 2 * 2, 3 + 3]`
 
@@ -182,6 +324,39 @@ func TestArrayLiteralExpression(t *testing.T) {
 	testInfixExpression(t, 3, "+", 3, array.Elements[2])
 }
 
+func TestHashLiteralExpression(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{
+			input:  `{}`,
+			expect: `{}`,
+		},
+		{
+			input:  `{"one": 1, "two": 2}`,
+			expect: `{"one": 1, "two": 2}`,
+		},
+		{
+			input:  `{1: 0 + 1, true: 2 * 3,}`,
+			expect: `{1: (0 + 1), true: (2 * 3)}`,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parseProgram(t, tt.input)
+			require.Equal(t, 1, len(program.Statements))
+			stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+			require.True(t, ok)
+
+			_, ok = stmt.Expression.(*ast.HashLiteral)
+			require.True(t, ok)
+			require.Equal(t, tt.expect, program.String())
+		})
+	}
+}
+
 func TestParsingIndexExpression(t *testing.T) {
 	input := `myArray[1 + 1];`
 
@@ -200,6 +375,60 @@ func TestParsingIndexExpression(t *testing.T) {
 	testInfixExpression(t, 1, "+", 1, indexExpr.Index)
 }
 
+func TestParsingSliceExpression(t *testing.T) {
+	testcases := []struct {
+		input  string
+		low    interface{}
+		high   interface{}
+		expect string
+	}{
+		{
+			input:  `a[1:2]`,
+			low:    1,
+			high:   2,
+			expect: `(a[1:2])`,
+		},
+		{
+			input:  `a[:n]`,
+			high:   "n",
+			expect: `(a[:n])`,
+		},
+		{
+			input:  `a[1:]`,
+			low:    1,
+			expect: `(a[1:])`,
+		},
+		{
+			input:  `a[:]`,
+			expect: `(a[:])`,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parseProgram(t, tt.input)
+			require.Len(t, program.Statements, 1)
+			stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+			require.True(t, ok)
+
+			sliceExpr, ok := stmt.Expression.(*ast.SliceExpression)
+			require.True(t, ok)
+			testIdentifier(t, "a", sliceExpr.Left)
+			if tt.low == nil {
+				require.Nil(t, sliceExpr.Low)
+			} else {
+				testLiteralExpression(t, tt.low, sliceExpr.Low)
+			}
+			if tt.high == nil {
+				require.Nil(t, sliceExpr.High)
+			} else {
+				testLiteralExpression(t, tt.high, sliceExpr.High)
+			}
+			require.Equal(t, tt.expect, program.String())
+		})
+	}
+}
+
 func testBoolean(t *testing.T, expected bool, exp ast.Expression) {
 	t.Helper()
 
@@ -332,6 +561,342 @@ func TestFunctionParameters(t *testing.T) {
 	}
 }
 
+func TestFunctionLiteralExtendedParameters(t *testing.T) {
+	testcases := []struct {
+		input    string
+		name     string
+		params   []string
+		defaults []interface{}
+		rest     string
+	}{
+		{
+			input:  `fn fact(n) { n };`,
+			name:   "fact",
+			params: []string{"n"},
+		},
+		{
+			input:    `fn(x, y = 2) {};`,
+			params:   []string{"x", "y"},
+			defaults: []interface{}{nil, 2},
+		},
+		{
+			input:  `fn(x, ...rest) {};`,
+			params: []string{"x"},
+			rest:   "rest",
+		},
+		{
+			input: `fn(...rest) {};`,
+			rest:  "rest",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parseProgram(t, tt.input)
+			require.Equal(t, 1, len(program.Statements))
+			stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+			require.True(t, ok)
+			function, ok := stmt.Expression.(*ast.FunctionLiteral)
+			require.True(t, ok)
+
+			if tt.name == "" {
+				require.Nil(t, function.Name)
+			} else {
+				testIdentifier(t, tt.name, function.Name)
+			}
+
+			require.Equal(t, len(tt.params), len(function.Parameters))
+			for i := range tt.params {
+				testLiteralExpression(t, tt.params[i], function.Parameters[i])
+			}
+
+			require.Equal(t, len(tt.defaults), len(function.Defaults))
+			for i, def := range tt.defaults {
+				if def == nil {
+					require.Nil(t, function.Defaults[i])
+				} else {
+					testLiteralExpression(t, def, function.Defaults[i])
+				}
+			}
+
+			if tt.rest == "" {
+				require.Nil(t, function.Rest)
+			} else {
+				testIdentifier(t, tt.rest, function.Rest)
+			}
+		})
+	}
+}
+
+func TestFunctionLiteralString(t *testing.T) {
+	input := `fn f(x, y = 2, ...rest) { x }`
+
+	program := parseProgram(t, input)
+	require.Equal(t, "fn f(x, y = 2, ...rest) { x }", program.String())
+}
+
+func TestFunctionLiteralPatternParameters(t *testing.T) {
+	program := parseProgram(t, `fn(a, [b, c], {d} = {"d": 1}, ...rest) { a }`)
+	function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+	require.Len(t, function.Parameters, 3)
+	require.Len(t, function.Patterns, 3)
+	testIdentifier(t, "a", function.Parameters[0])
+	require.Nil(t, function.Patterns[0])
+	require.Nil(t, function.Parameters[1])
+	require.Equal(t, "[b, c]", function.Patterns[1].String())
+	require.Nil(t, function.Parameters[2])
+	require.Equal(t, `{"d": d}`, function.Patterns[2].String())
+	require.Equal(t, `fn(a, [b, c], {"d": d} = {"d": 1}, ...rest) { a }`, function.String())
+
+	program = parseProgram(t, `fn(a, b) { a }`)
+	function = program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	require.Nil(t, function.Patterns)
+}
+
+func TestStructStatement(t *testing.T) {
+	program := parseProgram(t, `struct Point { x, y, fn norm() { self.x * self.x + self.y * self.y }, }; Point(1, 2);`)
+	require.Equal(t, 2, len(program.Statements))
+
+	stmt, ok := program.Statements[0].(*ast.StructStatement)
+	require.True(t, ok)
+	testIdentifier(t, "Point", stmt.Name)
+	require.Len(t, stmt.Fields, 2)
+	testIdentifier(t, "x", stmt.Fields[0])
+	testIdentifier(t, "y", stmt.Fields[1])
+	require.Len(t, stmt.Methods, 1)
+	testIdentifier(t, "norm", stmt.Methods[0].Name)
+	require.Equal(t, "struct Point { x, y, fn norm() { (((self.x) * (self.x)) + ((self.y) * (self.y))) } }", stmt.String())
+}
+
+func TestMalformedStructStatement(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`struct { x }`, "expected identifier, got {"},
+		{`struct P x`, "expected {, got x"},
+		{`struct P { 1 }`, "expected field or method, got 1"},
+		{`struct P { x y }`, "expected , or }, got y"},
+		{`struct P { x, x }`, "duplicate member x of struct P"},
+		{`struct P { x, fn x() {} }`, "duplicate member x of struct P"},
+		{`struct P { fn() {} }`, "malformed method of struct P at 1:12"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := parser.New(lexer.New(tt.input)).ParseProgram()
+			require.EqualError(t, err, tt.expect)
+		})
+	}
+}
+
+func TestMemberAndAssignExpression(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`p.x`, `(p.x)`},
+		{`p.x.y`, `((p.x).y)`},
+		{`p.norm()`, `(p.norm)()`},
+		{`-p.x * 2`, `((-(p.x)) * 2)`},
+		{`a[0].x`, `((a[0]).x)`},
+		{`f().x`, `(f().x)`},
+		{`p.x = 1 + 2`, `((p.x) = (1 + 2))`},
+		{`p.x = q.y = 1`, `((p.x) = ((q.y) = 1))`},
+		{`let a = p.x = 1;`, `let a = ((p.x) = 1);`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parseProgram(t, tt.input)
+			require.Equal(t, tt.expect, program.String())
+		})
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`for (x in xs) { puts(x) }`, "for (x in xs) { puts(x) }"},
+		{`for ([k, v] in h) { k };`, "for ([k, v] in h) { k }"},
+		{`for ({a} in range(1, 10)) { a }`, `for ({"a": a} in range(1, 10)) { a }`},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parseProgram(t, tt.input)
+			require.Equal(t, 1, len(program.Statements))
+			_, ok := program.Statements[0].(*ast.ForStatement)
+			require.True(t, ok)
+			require.Equal(t, tt.expect, program.String())
+		})
+	}
+}
+
+func TestMalformedForStatement(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`for x in xs { x }`, "expected (, got x"},
+		{`for (1 in xs) { x }`, "malformed loop variable at 1:6"},
+		{`for (x of xs) { x }`, "expected in, got of"},
+		{`for (x in ) { x }`, "malformed iterable at 1:11"},
+		{`for (x in xs { x }`, "expected ), got {"},
+		{`for (x in xs) x`, "expected {, got x"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := parser.New(lexer.New(tt.input)).ParseProgram()
+			require.EqualError(t, err, tt.expect)
+		})
+	}
+}
+
+func TestMalformedFunctionParameters(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{`fn(1) {}`, "expected parameter name, got 1 at 1:4"},
+		{`let f = fn(a, "b") { a };`, "expected parameter name, got b at 1:15"},
+		{`fn(a, ...1) {}`, "expected parameter name, got 1 at 1:10"},
+		{`fn(a = 1, b) {}`, "parameter without default follows parameter with default at 1:11"},
+		{`let f = fn() { fn(a = 1, [b]) { b } };`, "parameter without default follows parameter with default at 1:26"},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := parser.New(lexer.New(tt.input)).ParseProgram()
+			require.EqualError(t, err, tt.expect)
+		})
+	}
+}
+
+func TestGeneratorFunctionLiteral(t *testing.T) {
+	testcases := []struct {
+		input     string
+		generator bool
+	}{
+		{`fn() { 1 }`, false},
+		{`fn() { yield 1 + 2 }`, true},
+		{`fn() { if (x) { for (i in xs) { yield i } } }`, true},
+		{`fn() { fn() { yield 1 } }`, false},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parseProgram(t, tt.input)
+			function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+			require.Equal(t, tt.generator, function.Generator)
+		})
+	}
+
+	program := parseProgram(t, `yield a + 1`)
+	require.Equal(t, "(yield (a + 1))", program.String())
+}
+
+func TestCallExpressionWithSpread(t *testing.T) {
+	input := `f(1, ...args);`
+	program := parseProgram(t, input)
+	require.Equal(t, 1, len(program.Statements))
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	require.True(t, ok)
+
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	require.True(t, ok)
+
+	require.Equal(t, 2, len(exp.Arguments))
+	testLiteralExpression(t, 1, exp.Arguments[0])
+	spread, ok := exp.Arguments[1].(*ast.SpreadExpression)
+	require.True(t, ok)
+	testIdentifier(t, "args", spread.Value)
+}
+
+func TestMatchExpression(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{
+			input:  `match (x) { 1 => "one", -1 => "minus one", _ => "other" }`,
+			expect: `match (x) { 1 => "one", -1 => "minus one", _ => "other" }`,
+		},
+		{
+			input:  `match (x + 1) { n if n > 2 => n, }`,
+			expect: `match ((x + 1)) { n if (n > 2) => n }`,
+		},
+		{
+			input:  `match (x) { [a, [b], ...rest] => rest, [] => 0 }`,
+			expect: `match (x) { [a, [b], ...rest] => rest, [] => 0 }`,
+		},
+		{
+			input:  `match (x) { {a, b: [c], "d": true, 1: e, ...rest} => a }`,
+			expect: `match (x) { {"a": a, "b": [c], "d": true, 1: e, ...rest} => a }`,
+		},
+		{
+			input:  `match (x) { null => 1, [null, a] => a, _ => 2 }`,
+			expect: `match (x) { null => 1, [null, a] => a, _ => 2 }`,
+		},
+		{
+			input:  `match (x) {}`,
+			expect: `match (x) {  }`,
+		},
+		{
+			input:  `match(s) { "a" => 1 }`,
+			expect: `match (s) { "a" => 1 }`,
+		},
+		// match is not a keyword
+		{
+			input:  `match(re, s)`,
+			expect: `match(re, s)`,
+		},
+		{
+			input:  `if (match(re, s)) { 1 }`,
+			expect: `if (match(re, s)) { 1 }`,
+		},
+		{
+			input:  `match(x)(y)`,
+			expect: `match(x)(y)`,
+		},
+		{
+			input:  `let match = 1; match + 1`,
+			expect: "let match = 1; (match + 1)",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parseProgram(t, tt.input)
+			require.Equal(t, tt.expect, program.String())
+		})
+	}
+}
+
+func TestMalformedMatchExpression(t *testing.T) {
+	testcases := []string{
+		`match (x) { 1 }`,
+		`match (x) { 1 => }`,
+		`match (x) { a + b => 1 }`,
+		`match (x) { [...a, b] => 1 }`,
+		`match (x) { {...a, b} => 1 }`,
+		`match (x) { {f(): a} => 1 }`,
+		`match (x) { 1 => 2 3 => 4 }`,
+		`match (x, y) { _ => 1 }`,
+	}
+
+	for _, input := range testcases {
+		t.Run(input, func(t *testing.T) {
+			_, err := parser.New(lexer.New(input)).ParseProgram()
+			require.Error(t, err)
+		})
+	}
+}
+
 func TestCallExpression(t *testing.T) {
 	input := `add(1, 2 * 3, 4 + 5);`
 	program := parseProgram(t, input)
@@ -506,7 +1071,7 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		},
 		{
 			input:  "3 + 4; -5 * 5",
-			expect: "(3 + 4)((-5) * 5)",
+			expect: "(3 + 4); ((-5) * 5)",
 		},
 		{
 			input:  "5 > 4 == 3 < 4",
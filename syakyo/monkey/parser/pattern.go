@@ -0,0 +1,194 @@
+package parser
+
+import (
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/token"
+)
+
+// parseMatchExpression parses `match (subject) { arms }`. Since match is
+// not a keyword, `match(x)` which is not followed by a left brace is parsed
+// as a call expression, e.g. a call of the builtin function `match`.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	p.nextToken()
+	call, ok := p.parseCallExpression(ident).(*ast.CallExpression)
+	if !ok {
+		return nil
+	}
+	if len(call.Arguments) != 1 || p.peekToken.Type != token.TypeLeftBrace {
+		return call
+	}
+	if _, ok := call.Arguments[0].(*ast.SpreadExpression); ok {
+		return call
+	}
+
+	expr := &ast.MatchExpression{
+		Token:   ident.Token,
+		Subject: call.Arguments[0],
+	}
+	p.nextToken()
+
+	for p.peekToken.Type != token.TypeRightBrace {
+		p.nextToken()
+		arm := ast.MatchArm{Pattern: p.parsePattern()}
+		if arm.Pattern == nil {
+			return nil
+		}
+		if p.peekToken.Type == token.TypeIf {
+			p.nextToken()
+			p.nextToken()
+			if arm.Guard = p.parseExpression(priorityLowest); arm.Guard == nil {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.TypeArrow) {
+			return nil
+		}
+		p.nextToken()
+		if arm.Value = p.parseExpression(priorityLowest); arm.Value == nil {
+			return nil
+		}
+		expr.Arms = append(expr.Arms, arm)
+
+		if p.peekToken.Type != token.TypeRightBrace && !p.expectPeek(token.TypeComma) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.TypeRightBrace) {
+		return nil
+	}
+
+	return expr
+}
+
+// parsePattern parses a pattern starting at the current token. It returns
+// nil if the pattern is malformed.
+func (p *Parser) parsePattern() ast.Expression {
+	switch p.curToken.Type {
+	case token.TypeIdent:
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.TypeInt:
+		return p.parseIntegerLiteral()
+	case token.TypeString:
+		return p.parseStringLiteral()
+	case token.TypeTrue, token.TypeFalse:
+		return p.parseBoolean()
+	case token.TypeNull:
+		return p.parseNull()
+	case token.TypeMinus:
+		expr := &ast.PrefixExpression{Token: p.curToken, Operator: p.curToken.Literal}
+		if !p.expectPeek(token.TypeInt) {
+			return nil
+		}
+		if expr.Right = p.parseIntegerLiteral(); expr.Right == nil {
+			return nil
+		}
+		return expr
+	case token.TypeLeftBraket:
+		return p.parseArrayPattern()
+	case token.TypeLeftBrace:
+		return p.parseHashPattern()
+	default:
+		return nil
+	}
+}
+
+func (p *Parser) parseArrayPattern() ast.Expression {
+	pattern := &ast.ArrayPattern{
+		Token: p.curToken,
+	}
+
+	for p.peekToken.Type != token.TypeRightBraket {
+		p.nextToken()
+		if p.curToken.Type == token.TypeEllipsis {
+			if pattern.Rest = p.parseRestPattern(); pattern.Rest == nil {
+				return nil
+			}
+			// A rest pattern must be the last one.
+			break
+		}
+
+		elem := p.parsePattern()
+		if elem == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, elem)
+
+		if p.peekToken.Type != token.TypeRightBraket && !p.expectPeek(token.TypeComma) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.TypeRightBraket) {
+		return nil
+	}
+
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Expression {
+	pattern := &ast.HashPattern{
+		Token: p.curToken,
+	}
+
+	for p.peekToken.Type != token.TypeRightBrace {
+		p.nextToken()
+
+		var pair ast.HashLiteralPair
+		switch p.curToken.Type {
+		case token.TypeEllipsis:
+			if pattern.Rest = p.parseRestPattern(); pattern.Rest == nil {
+				return nil
+			}
+		case token.TypeIdent:
+			// An identifier key is the name of the key, not a variable.
+			tok := p.curToken
+			tok.Type = token.TypeString
+			pair.Key = &ast.StringLiteral{Token: tok, Value: tok.Literal}
+			if p.peekToken.Type == token.TypeColon {
+				p.nextToken()
+				p.nextToken()
+				pair.Value = p.parsePattern()
+			} else {
+				pair.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			}
+		case token.TypeString, token.TypeInt, token.TypeTrue, token.TypeFalse:
+			pair.Key = p.parsePattern()
+			if pair.Key == nil || !p.expectPeek(token.TypeColon) {
+				return nil
+			}
+			p.nextToken()
+			pair.Value = p.parsePattern()
+		default:
+			return nil
+		}
+		if pattern.Rest != nil {
+			// A rest pattern must be the last one.
+			break
+		}
+
+		if pair.Value == nil {
+			return nil
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if p.peekToken.Type != token.TypeRightBrace && !p.expectPeek(token.TypeComma) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.TypeRightBrace) {
+		return nil
+	}
+
+	return pattern
+}
+
+// parseRestPattern parses `...name` at the current token.
+func (p *Parser) parseRestPattern() *ast.Identifier {
+	if !p.expectPeek(token.TypeIdent) {
+		return nil
+	}
+	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/token"
+)
+
+// parseStructStatement parses `struct Name { field, ..., fn method() {} }`.
+// The members are separated by commas.
+func (p *Parser) parseStructStatement() (*ast.StructStatement, error) {
+	stmt := &ast.StructStatement{
+		Token: p.curToken,
+	}
+	if !p.expectPeek(token.TypeIdent) {
+		return nil, fmt.Errorf("expected identifier, got %s", p.peekToken.Literal)
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.TypeLeftBrace) {
+		return nil, fmt.Errorf("expected {, got %s", p.peekToken.Literal)
+	}
+
+	members := map[string]bool{}
+	for p.peekToken.Type != token.TypeRightBrace {
+		p.nextToken()
+
+		var name *ast.Identifier
+		switch p.curToken.Type {
+		case token.TypeIdent:
+			name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			stmt.Fields = append(stmt.Fields, name)
+		case token.TypeFunction:
+			pos := p.curToken.Pos
+			method, ok := p.parseFunctionLiteral().(*ast.FunctionLiteral)
+			if !ok || method.Name == nil {
+				return nil, fmt.Errorf("malformed method of struct %s at %s", stmt.Name, pos)
+			}
+			name = method.Name
+			stmt.Methods = append(stmt.Methods, method)
+		default:
+			return nil, fmt.Errorf("expected field or method, got %s", p.curToken.Literal)
+		}
+		if members[name.Value] {
+			return nil, fmt.Errorf("duplicate member %s of struct %s", name, stmt.Name)
+		}
+		members[name.Value] = true
+
+		if p.peekToken.Type != token.TypeRightBrace && !p.expectPeek(token.TypeComma) {
+			return nil, fmt.Errorf("expected , or }, got %s", p.peekToken.Literal)
+		}
+	}
+	p.nextToken()
+
+	if p.peekToken.Type == token.TypeSemicolon {
+		p.nextToken()
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	expr := &ast.MemberExpression{
+		Token:  p.curToken,
+		Object: object,
+	}
+	if !p.expectPeek(token.TypeIdent) {
+		return nil
+	}
+	expr.Member = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return expr
+}
+
+// parseAssignExpression parses `target = value`, where target must be a
+// member expression. Assignments are right-associative.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	if _, ok := target.(*ast.MemberExpression); !ok {
+		return nil
+	}
+	expr := &ast.AssignExpression{
+		Token:  p.curToken,
+		Target: target,
+	}
+
+	p.nextToken()
+	if expr.Value = p.parseExpression(priorityLowest); expr.Value == nil {
+		return nil
+	}
+
+	return expr
+}
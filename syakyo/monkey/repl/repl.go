@@ -7,6 +7,7 @@ import (
 
 	"github.com/daichimukai/x/syakyo/monkey/eval"
 	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/object"
 	"github.com/daichimukai/x/syakyo/monkey/parser"
 )
 
@@ -14,7 +15,10 @@ const prompt = "monkey> "
 
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
-	env := eval.NewEnvironment()
+	env := eval.NewEnvironment(
+		eval.WithCapabilities(eval.CapabilityAll),
+		eval.WithStdout(out),
+	)
 
 	for {
 		fmt.Print(prompt)
@@ -34,7 +38,7 @@ func Start(in io.Reader, out io.Writer) {
 		}
 
 		if evaluated := env.Eval(program); evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
+			io.WriteString(out, object.Repr(evaluated))
 			io.WriteString(out, "\n")
 		}
 	}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/daichimukai/x/syakyo/monkey/eval"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+	"github.com/daichimukai/x/syakyo/monkey/optimize"
+)
+
+var capabilityFlagValues = map[string]eval.Capability{
+	"fs":      eval.CapabilityFS,
+	"env":     eval.CapabilityEnv,
+	"process": eval.CapabilityProcess,
+	"time":    eval.CapabilityTime,
+}
+
+// runMain implements `monkey run [flags] file [arg...]`. All the capabilities
+// are granted to the script unless denied by -deny. The file may be a
+// program compiled by `monkey compile`.
+func runMain(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	deny := flags.String("deny", "", "comma-separated list of capabilities to deny: fs, env, process, time")
+	memprofile := flags.String("memprofile", "", "write the memory allocated by each function to `file`")
+	profile := flags.String("profile", "", "write the calls and the time of each function to `file`")
+	pprof := flags.String("pprof", "", "write the calls and the time by call stack to `file` in the pprof format")
+	trace := flags.Bool("trace", false, "print each evaluated node with its result to the standard error")
+	optimizeProgram := flags.Bool("optimize", false, "optimize the program before running it")
+	dumpAST := flags.Bool("dump-ast", false, "print the program as it would be run instead of running it")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey run [flags] file [arg...]")
+		return 2
+	}
+
+	caps := eval.CapabilityAll
+	for _, name := range splitList(*deny) {
+		c, ok := capabilityFlagValues[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown capability: %s\n", name)
+			return 2
+		}
+		caps &^= c
+	}
+
+	filename := flags.Arg(0)
+	program, err := loadProgram(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *dumpAST {
+		if *optimizeProgram {
+			optimize.Program(program)
+		}
+		for _, stmt := range program.Statements {
+			fmt.Println(stmt)
+		}
+		return 0
+	}
+
+	opts := []eval.Option{
+		eval.WithCapabilities(caps),
+		eval.WithArgs(flags.Args()[1:]),
+	}
+	if *optimizeProgram {
+		opts = append(opts, eval.WithOptimization())
+	}
+	if *trace {
+		opts = append(opts, eval.WithTrace(os.Stderr))
+	}
+
+	// reports write the profiles when the program finishes or exits.
+	var reports []func() bool
+	if *memprofile != "" {
+		p := eval.NewMemProfile()
+		opts = append(opts, eval.WithMemProfile(p))
+		reports = append(reports, func() bool { return writeFile(*memprofile, p.Report) })
+	}
+	if *profile != "" || *pprof != "" {
+		p := eval.NewProfile()
+		opts = append(opts, eval.WithProfile(p))
+		if *profile != "" {
+			reports = append(reports, func() bool { return writeFile(*profile, p.Report) })
+		}
+		if *pprof != "" {
+			reports = append(reports, func() bool {
+				return writeFile(*pprof, func(w io.Writer) error { return p.WritePprof(w, filename) })
+			})
+		}
+	}
+	writeReports := func() bool {
+		ok := true
+		for _, report := range reports {
+			ok = report() && ok
+		}
+		return ok
+	}
+	if len(reports) > 0 {
+		opts = append(opts, eval.WithExit(func(code int) {
+			if !writeReports() && code == 0 {
+				code = 1
+			}
+			os.Exit(code)
+		}))
+	}
+
+	env := eval.NewEnvironment(opts...)
+	code := 0
+	if errObj, ok := env.Eval(program).(*object.Error); ok {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", filename, errObj.Inspect())
+		code = 1
+	}
+	if !writeReports() {
+		code = 1
+	}
+	return code
+}
+
+// writeFile creates the file and writes to it by write. It reports an error
+// to the standard error and returns false on failure.
+func writeFile(filename string, write func(w io.Writer) error) bool {
+	f, err := os.Create(filename)
+	if err == nil {
+		err = write(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/daichimukai/x/syakyo/monkey/eval"
+	"github.com/daichimukai/x/syakyo/monkey/tester"
+)
+
+// testMain implements `monkey test [flags] [path...]`. It runs the tests in
+// the test files, searching directories recursively, and exits with 1 if
+// any test fails. The tests may use all the capabilities but the process.
+func testMain(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	verbose := flags.Bool("v", false, "print the passed tests and the output of all the tests")
+	pattern := flags.String("run", "", "run only the tests whose names match `regexp`")
+	junit := flags.String("junit", "", "write the results in the JUnit XML format to `file`")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var filter *regexp.Regexp
+	if *pattern != "" {
+		var err error
+		if filter, err = regexp.Compile(*pattern); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := testFiles(paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(files) == 0 {
+		fmt.Println("no test files")
+		return 0
+	}
+
+	opts := []eval.Option{eval.WithCapabilities(eval.CapabilityAll &^ eval.CapabilityProcess)}
+	var suites []tester.Suite
+	failed := false
+	for _, filename := range files {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		results, err := tester.Run(string(src), filter, opts...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: parse error: %s\n", filename, err)
+			return 2
+		}
+		suite := tester.Suite{File: filename, Results: results}
+		if !printSuite(suite, *verbose) {
+			failed = true
+		}
+		suites = append(suites, suite)
+	}
+
+	if *junit != "" {
+		if !writeFile(*junit, func(w io.Writer) error { return tester.WriteJUnit(w, suites) }) {
+			return 2
+		}
+	}
+	if failed {
+		fmt.Println("FAIL")
+		return 1
+	}
+	fmt.Println("PASS")
+	return 0
+}
+
+// testFiles returns the test files in paths. A path naming a file is
+// returned as is, and a directory is searched recursively, skipping the
+// directories whose names begin with . or _.
+func testFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case p == path && !d.IsDir():
+				files = append(files, p)
+			case d.IsDir() && p != path && (strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "_")):
+				return filepath.SkipDir
+			case !d.IsDir() && strings.HasSuffix(d.Name(), tester.FileSuffix):
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// printSuite prints the failed tests of suite, or all the tests if verbose,
+// followed by the summary of the file. It reports whether all the tests
+// passed.
+func printSuite(suite tester.Suite, verbose bool) bool {
+	var elapsed time.Duration
+	failures := 0
+	for _, r := range suite.Results {
+		elapsed += r.Duration
+		if r.Failed() {
+			failures++
+			fmt.Printf("--- FAIL: %s (%s:%s) (%.2fs)\n", r.Name, suite.File, r.Pos, r.Duration.Seconds())
+			fmt.Printf("    %s:%s: %s\n", suite.File, r.ErrPos(), r.Err.Message)
+		} else if verbose {
+			fmt.Printf("--- PASS: %s (%s:%s) (%.2fs)\n", r.Name, suite.File, r.Pos, r.Duration.Seconds())
+		}
+		if r.Output != "" && (r.Failed() || verbose) {
+			for _, line := range strings.Split(strings.TrimSuffix(r.Output, "\n"), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+
+	if failures > 0 {
+		fmt.Printf("FAIL\t%s\t%d of %d failed (%.3fs)\n", suite.File, failures, len(suite.Results), elapsed.Seconds())
+		return false
+	}
+	fmt.Printf("ok  \t%s\t%d passed (%.3fs)\n", suite.File, len(suite.Results), elapsed.Seconds())
+	return true
+}
@@ -0,0 +1,92 @@
+package tester
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Suite is the results of the tests in a file.
+type Suite struct {
+	File    string
+	Results []Result
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results to w in the JUnit XML format. A suite is
+// named after its file, and the failures are reported with their positions.
+func WriteJUnit(w io.Writer, suites []Suite) error {
+	var total time.Duration
+	out := junitTestSuites{}
+	for _, suite := range suites {
+		var elapsed time.Duration
+		s := junitTestSuite{Name: suite.File, Tests: len(suite.Results)}
+		for _, r := range suite.Results {
+			c := junitTestCase{
+				Name:      r.Name,
+				ClassName: suite.File,
+				Time:      seconds(r.Duration),
+				SystemOut: r.Output,
+			}
+			if r.Failed() {
+				s.Failures++
+				c.Failure = &junitFailure{
+					Message: r.Err.Message,
+					Text:    fmt.Sprintf("%s:%s: %s", suite.File, r.ErrPos(), r.Err.Message),
+				}
+			}
+			elapsed += r.Duration
+			s.TestCases = append(s.TestCases, c)
+		}
+		s.Time = seconds(elapsed)
+		out.Tests += s.Tests
+		out.Failures += s.Failures
+		out.Suites = append(out.Suites, s)
+		total += elapsed
+	}
+	out.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
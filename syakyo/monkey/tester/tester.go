@@ -0,0 +1,114 @@
+// Package tester runs tests written in Monkey. A test is a function bound to
+// a name starting with test_ by a top-level let statement. The tests are
+// put in files whose names end with _test.mk.
+package tester
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/eval"
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+	"github.com/daichimukai/x/syakyo/monkey/parser"
+	"github.com/daichimukai/x/syakyo/monkey/token"
+)
+
+// FileSuffix is the suffix of the names of test files.
+const FileSuffix = "_test.mk"
+
+const namePrefix = "test_"
+
+// Test is a test function in a program.
+type Test struct {
+	Name string
+	Pos  token.Position // position of the let statement binding the function
+}
+
+// Find returns the tests in program in the order of appearance.
+func Find(program *ast.Program) []Test {
+	var tests []Test
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || let.Name == nil || !strings.HasPrefix(let.Name.Value, namePrefix) {
+			continue
+		}
+		if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+			tests = append(tests, Test{Name: let.Name.Value, Pos: let.Pos()})
+		}
+	}
+	return tests
+}
+
+// Result is the result of a test.
+type Result struct {
+	Test
+	Err      *object.Error // nil if the test passed
+	Output   string        // written by the test with puts and print
+	Duration time.Duration
+}
+
+// Failed reports whether the test failed.
+func (r *Result) Failed() bool {
+	return r.Err != nil
+}
+
+// ErrPos returns the position of the failure, which is that of the call to
+// the builtin, such as assert, returning the error if known, or else that
+// of the test.
+func (r *Result) ErrPos() token.Position {
+	if r.Err != nil && r.Err.Pos.IsValid() {
+		return r.Err.Pos
+	}
+	return r.Pos
+}
+
+// Run runs the tests in src whose names match filter, or all the tests if
+// filter is nil. Each test runs in a fresh environment configured by opts,
+// in which the whole program is evaluated before the test function is
+// called with no arguments. A test fails if either returns an error.
+func Run(src string, filter *regexp.Regexp, opts ...eval.Option) ([]Result, error) {
+	program, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, test := range Find(program) {
+		if filter != nil && !filter.MatchString(test.Name) {
+			continue
+		}
+		results = append(results, run(program, test, opts))
+	}
+	return results, nil
+}
+
+func run(program *ast.Program, test Test, opts []eval.Option) Result {
+	var out bytes.Buffer
+	env := eval.NewEnvironment(append(opts[:len(opts):len(opts)], eval.WithStdout(&out))...)
+	start := time.Now()
+	result := env.Eval(program)
+	if !isError(result) {
+		if fn, ok := env.Get(test.Name); ok {
+			result = object.ApplyFunction(fn, nil)
+		} else {
+			result = object.NewError("identifier not found: %s", test.Name)
+		}
+	}
+
+	r := Result{Test: test, Output: out.String(), Duration: time.Since(start)}
+	r.Err, _ = result.(*object.Error)
+	return r
+}
+
+func parse(src string) (*ast.Program, error) {
+	return parser.New(lexer.New(src)).ParseProgram()
+}
+
+func isError(obj object.Object) bool {
+	_, ok := obj.(*object.Error)
+	return ok
+}
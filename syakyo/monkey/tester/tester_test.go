@@ -0,0 +1,135 @@
+package tester_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/daichimukai/x/syakyo/monkey/eval"
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+	"github.com/daichimukai/x/syakyo/monkey/parser"
+	"github.com/daichimukai/x/syakyo/monkey/tester"
+	"github.com/daichimukai/x/syakyo/monkey/token"
+	"github.com/stretchr/testify/require"
+)
+
+const src = `let counter = [0];
+let add = fn(a, b) { a + b };
+
+let test_add = fn() {
+  assert_eq(add(1, 2), 3);
+};
+let test_fail = fn() {
+  puts("add(1, 1) =", add(1, 1));
+  assert_eq(add(1, 1), 3, "one plus one");
+};
+let test_error = fn() { add(1, true) };
+let test_args = fn(x) { x };
+let helper = fn() { assert(false) };
+let test_value = 1;
+`
+
+func TestFind(t *testing.T) {
+	program, err := parser.New(lexer.New(src)).ParseProgram()
+	require.NoError(t, err)
+	require.Equal(t, []tester.Test{
+		{Name: "test_add", Pos: token.Position{Line: 4, Column: 1}},
+		{Name: "test_fail", Pos: token.Position{Line: 7, Column: 1}},
+		{Name: "test_error", Pos: token.Position{Line: 11, Column: 1}},
+		{Name: "test_args", Pos: token.Position{Line: 12, Column: 1}},
+	}, tester.Find(program))
+}
+
+func TestRun(t *testing.T) {
+	results, err := tester.Run(src, nil)
+	require.NoError(t, err)
+
+	type result struct {
+		name   string
+		err    string
+		errPos string
+		output string
+	}
+	var got []result
+	for _, r := range results {
+		res := result{name: r.Name, output: r.Output}
+		if r.Failed() {
+			res.err = r.Err.Message
+			res.errPos = r.ErrPos().String()
+		}
+		got = append(got, res)
+	}
+	require.Equal(t, []result{
+		{name: "test_add"},
+		{name: "test_fail", err: "assertion failed: one plus one: got 2, want 3", errPos: "9:3", output: "add(1, 1) =\n2\n"},
+		{name: "test_error", err: "type mismatch: INTEGER + BOOLEAN", errPos: "11:1"},
+		{name: "test_args", err: "wrong number of arguments: got=0, want=1", errPos: "12:1"},
+	}, got)
+}
+
+func TestRunFilter(t *testing.T) {
+	results, err := tester.Run(src, regexp.MustCompile("^test_(add|error)$"))
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "test_add", results[0].Name)
+	require.Equal(t, "test_error", results[1].Name)
+}
+
+func TestRunIsolation(t *testing.T) {
+	// Each test sees the program as evaluated afresh.
+	results, err := tester.Run(`
+struct Box { n }
+let box = Box(0);
+let test_first = fn() { box.n = box.n + 1; assert_eq(box.n, 1) };
+let test_second = fn() { box.n = box.n + 1; assert_eq(box.n, 1) };
+`, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, r := range results {
+		require.False(t, r.Failed(), r.Name)
+	}
+}
+
+func TestRunProgramError(t *testing.T) {
+	results, err := tester.Run("let test_a = fn() { 1 };\nread_file(\"x\");\n", nil, eval.WithCapabilities(eval.CapabilityNone))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "read_file: filesystem access is disabled", results[0].Err.Message)
+	require.Equal(t, "2:1", results[0].ErrPos().String())
+
+	_, err = tester.Run("let test_a = fn() {", nil)
+	require.Error(t, err)
+}
+
+func TestWriteJUnit(t *testing.T) {
+	suites := []tester.Suite{
+		{
+			File: "a_test.mk",
+			Results: []tester.Result{
+				{Test: tester.Test{Name: "test_ok", Pos: token.Position{Line: 1, Column: 1}}},
+				{
+					Test:   tester.Test{Name: "test_ng", Pos: token.Position{Line: 2, Column: 1}},
+					Err:    &object.Error{Message: "assertion failed: <&>", Pos: token.Position{Line: 3, Column: 5}},
+					Output: "out\n",
+				},
+			},
+		},
+		{File: "b_test.mk"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, tester.WriteJUnit(&buf, suites))
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="2" failures="1" time="0.000">
+  <testsuite name="a_test.mk" tests="2" failures="1" time="0.000">
+    <testcase name="test_ok" classname="a_test.mk" time="0.000"></testcase>
+    <testcase name="test_ng" classname="a_test.mk" time="0.000">
+      <failure message="assertion failed: &lt;&amp;&gt;">a_test.mk:3:5: assertion failed: &lt;&amp;&gt;</failure>
+      <system-out>out&#xA;</system-out>
+    </testcase>
+  </testsuite>
+  <testsuite name="b_test.mk" tests="0" failures="0" time="0.000"></testsuite>
+</testsuites>
+`, buf.String())
+}
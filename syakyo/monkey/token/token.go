@@ -1,14 +1,17 @@
 package token
 
+import "fmt"
+
 type TokenType uint
 
 const (
 	TypeIllegal TokenType = iota // token is illegal.
 	TypeEof                      // the input reached to the end.
 
-	TypeIdent  // identifier literal, e.g. x, foo.
-	TypeInt    // integer literal e.g. 0, 100, -1.
-	TypeString // string literal, e.g. "foo".
+	TypeIdent    // identifier literal, e.g. x, foo.
+	TypeInt      // integer literal e.g. 0, 100, -1.
+	TypeString   // string literal, e.g. "foo".
+	TypeTemplate // string literal with interpolations, e.g. "foo ${bar}".
 
 	TypeAssign   // =
 	TypePlus     // +
@@ -20,8 +23,10 @@ const (
 	TypeGt       // >
 	TypeEq       // ==
 	TypeNotEq    // !=
+	TypeArrow    // =>
 
 	TypeComma       // ,
+	TypeColon       // :
 	TypeSemicolon   // ;
 	TypeLeftParen   // (
 	TypeRightParen  // )
@@ -29,28 +34,59 @@ const (
 	TypeRightBrace  // }
 	TypeLeftBraket  // [
 	TypeRightBraket // ]
+	TypeEllipsis    // ...
+	TypeDot         // .
 
 	TypeFunction // keyword "funcion"
 	TypeLet      // keyword "let"
+	TypeConst    // keyword "const"
+	TypeStruct   // keyword "struct"
+	TypeYield    // keyword "yield"
+	TypeFor      // keyword "for"
+	TypeIn       // keyword "in"
 	TypeTrue     // keyword "true"
 	TypeFalse    // keyword "false"
+	TypeNull     // keyword "null"
 	TypeIf       // keyword "if"
 	TypeElse     // keyword "else"
 	TypeReturn   // keywork "return"
 )
 
+// Position is a location in a source code. Line and Column start from 1.
+// The zero value means the position is unknown.
+type Position struct {
+	Line   int
+	Column int // in bytes
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// IsValid reports whether the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
 // Token represents a token of the language.
 // The zero value is a illegal token.
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // position of the first char of the token
 }
 
 var keywords map[string]TokenType = map[string]TokenType{
 	"fn":     TypeFunction,
 	"let":    TypeLet,
+	"const":  TypeConst,
+	"struct": TypeStruct,
+	"yield":  TypeYield,
+	"for":    TypeFor,
+	"in":     TypeIn,
 	"true":   TypeTrue,
 	"false":  TypeFalse,
+	"null":   TypeNull,
 	"if":     TypeIf,
 	"else":   TypeElse,
 	"return": TypeReturn,
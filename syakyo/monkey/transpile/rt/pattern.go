@@ -0,0 +1,90 @@
+package rt
+
+import "github.com/daichimukai/x/syakyo/monkey/object"
+
+// PatternKind is the kind of a pattern.
+type PatternKind int
+
+const (
+	PatternBind    PatternKind = iota // an identifier, which binds the value
+	PatternLiteral                    // a literal, which matches an equal value
+	PatternArray                      // an array pattern
+	PatternHash                       // a hash pattern
+)
+
+// Pattern is a destructuring pattern in a compiled program. The variables
+// bound by a pattern are numbered in the order of appearance.
+type Pattern struct {
+	Kind PatternKind
+	Text string // the source of the pattern, used in errors
+
+	Bind     int           // the variable bound by PatternBind, or -1 for `_`
+	Literal  object.Object // the value of PatternLiteral
+	Elements []*Pattern    // the elements of PatternArray
+	Keys     []object.Hashable
+	Values   []*Pattern // the patterns of the values of Keys in PatternHash
+	Rest     int        // the variable bound by the rest of PatternArray or PatternHash, or -1
+}
+
+// Match stores the values bound by p to the variables in vars. It returns
+// an error if value does not match p.
+func Match(p *Pattern, value object.Object, vars []object.Object) *object.Error {
+	switch p.Kind {
+	case PatternBind:
+		if p.Bind >= 0 {
+			vars[p.Bind] = value
+		}
+		return nil
+	case PatternArray:
+		arr, ok := value.(*object.Array)
+		if !ok {
+			return object.NewError("pattern %s does not match %s", p.Text, value.Type())
+		}
+		if len(arr.Elements) < len(p.Elements) || p.Rest < 0 && len(arr.Elements) != len(p.Elements) {
+			return object.NewError("pattern %s does not match an array of %d elements", p.Text, len(arr.Elements))
+		}
+		for i, elem := range p.Elements {
+			if err := Match(elem, arr.Elements[i], vars); err != nil {
+				return err
+			}
+		}
+		if p.Rest >= 0 {
+			rest := make([]object.Object, len(arr.Elements)-len(p.Elements))
+			copy(rest, arr.Elements[len(p.Elements):])
+			vars[p.Rest] = &object.Array{Elements: rest}
+		}
+		return nil
+	case PatternHash:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return object.NewError("pattern %s does not match %s", p.Text, value.Type())
+		}
+		matched := map[object.HashKey]bool{}
+		for i, key := range p.Keys {
+			v, ok := hash.Get(key)
+			if !ok {
+				return object.NewError("pattern %s does not match a hash without key %s", p.Text, key.Inspect())
+			}
+			if err := Match(p.Values[i], v, vars); err != nil {
+				return err
+			}
+			matched[key.HashKey()] = true
+		}
+		if p.Rest >= 0 {
+			rest := object.NewHash()
+			for _, key := range hash.Keys {
+				if !matched[key] {
+					pair := hash.Pairs[key]
+					rest.Set(pair.Key.(object.Hashable), pair.Value)
+				}
+			}
+			vars[p.Rest] = rest
+		}
+		return nil
+	default:
+		if !object.Equal(value, p.Literal) {
+			return object.NewError("pattern %s does not match %s", p.Text, value.Inspect())
+		}
+		return nil
+	}
+}
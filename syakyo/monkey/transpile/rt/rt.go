@@ -0,0 +1,347 @@
+// Package rt is the runtime library of the Monkey programs compiled to Go
+// by the transpile package. The operations behave the same as those of the
+// evaluator, and the builtins are shared with it.
+package rt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/daichimukai/x/syakyo/monkey/eval"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// Runtime holds the builtins of a running program.
+type Runtime struct {
+	builtins map[string]*object.Builtin
+}
+
+// New returns a runtime whose builtins are configured by opts in the same
+// way as those of an environment.
+func New(opts ...eval.Option) *Runtime {
+	return &Runtime{builtins: eval.Builtins(opts...)}
+}
+
+// Main runs program, compiled from filename, with all the capabilities
+// granted and the command line arguments, like `monkey run`. It reports an
+// error to the standard error and returns the exit code.
+func Main(filename string, program func(r *Runtime) object.Object) int {
+	r := New(eval.WithCapabilities(eval.CapabilityAll), eval.WithArgs(os.Args[1:]))
+	if err, ok := program(r).(*object.Error); ok {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err.Inspect())
+		return 1
+	}
+	return 0
+}
+
+// Builtin returns the builtin named name.
+func (r *Runtime) Builtin(name string) object.Object {
+	return r.builtins[name]
+}
+
+// Lookup returns the first defined, i.e. non-nil, one of vars, which are
+// the variables named name from the innermost scope outward. It falls back
+// on the builtin or an error if none is defined yet.
+func (r *Runtime) Lookup(name string, vars ...object.Object) object.Object {
+	for _, v := range vars {
+		if v != nil {
+			return v
+		}
+	}
+	if builtin, ok := r.builtins[name]; ok {
+		return builtin
+	}
+	return object.NewError("identifier not found: %s", name)
+}
+
+// ErrGeneratorClosed unwinds the body of a closed generator.
+var ErrGeneratorClosed = object.NewError("generator is closed")
+
+func IsError(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ErrorObjectType
+	}
+	return false
+}
+
+func IsTruthy(obj object.Object) bool {
+	return !(obj == object.False || obj == object.Null)
+}
+
+// Call calls fn with args.
+func Call(fn object.Object, args ...object.Object) object.Object {
+	return object.ApplyFunction(fn, args)
+}
+
+// CheckArity returns an error if a function cannot be called with n
+// arguments. The function takes from min to max arguments, or any number
+// of arguments from min if rest is true.
+func CheckArity(n, min, max int, rest bool) *object.Error {
+	switch {
+	case rest && n < min:
+		return object.NewError("wrong number of arguments: got=%d, want>=%d", n, min)
+	case rest:
+		return nil
+	case min != max && (n < min || max < n):
+		return object.NewError("wrong number of arguments: got=%d, want=%d..%d", n, min, max)
+	case n < min || max < n:
+		return object.NewError("wrong number of arguments: got=%d, want=%d", n, max)
+	}
+	return nil
+}
+
+// Rest returns an array of the arguments following the first n ones.
+func Rest(args []object.Object, n int) *object.Array {
+	rest := &object.Array{}
+	if len(args) > n {
+		rest.Elements = append(rest.Elements, args[n:]...)
+	}
+	return rest
+}
+
+// Spread returns an array of the values of obj spread in a call or an
+// array literal, or an error.
+func Spread(obj object.Object) object.Object {
+	if _, ok := obj.(object.Iterable); ok {
+		obj = collect(obj)
+		if IsError(obj) {
+			return obj
+		}
+	}
+	if _, ok := obj.(*object.Array); !ok {
+		return object.NewError("cannot spread %s", obj.Type())
+	}
+	return obj
+}
+
+func collect(obj object.Object) object.Object {
+	it, _ := object.Iterate(obj)
+	defer it.Close()
+
+	elems := []object.Object{}
+	for {
+		val, ok := it.Next()
+		if !ok {
+			return &object.Array{Elements: elems}
+		}
+		if IsError(val) {
+			return val
+		}
+		elems = append(elems, val)
+	}
+}
+
+// Iterate returns an iterator over obj for a for statement.
+func Iterate(obj object.Object) (object.Iterator, *object.Error) {
+	it, ok := object.Iterate(obj)
+	if !ok {
+		return nil, object.NewError("cannot iterate over %s", obj.Type())
+	}
+	return it, nil
+}
+
+// Interpolate concatenates parts. A string is embedded as is and the other
+// objects are embedded as their Inspect().
+func Interpolate(parts ...object.Object) object.Object {
+	var out strings.Builder
+	for _, part := range parts {
+		if s, ok := part.(*object.String); ok {
+			out.WriteString(s.Value)
+		} else {
+			out.WriteString(part.Inspect())
+		}
+	}
+	return &object.String{Value: out.String()}
+}
+
+// Key returns key if it can be a key of a hash, or an error.
+func Key(key object.Object) object.Object {
+	if _, ok := key.(object.Hashable); !ok {
+		return object.NewError("unusable as hash key: %s", key.Type())
+	}
+	return key
+}
+
+func Prefix(op string, right object.Object) object.Object {
+	switch op {
+	case "!":
+		return object.BooleanFromNative(!IsTruthy(right))
+	case "-":
+		integer, ok := right.(*object.Integer)
+		if !ok {
+			return object.NewError("unknown operator: -%s", right.Type())
+		}
+		return object.NewInteger(-integer.Value)
+	default:
+		return object.NewError("unknown operator: %s%s", op, right.Type())
+	}
+}
+
+func Infix(op string, left, right object.Object) object.Object {
+	switch {
+	case left.Type() == object.IntegerObjectType && right.Type() == object.IntegerObjectType:
+		return integerInfix(op, left.(*object.Integer).Value, right.(*object.Integer).Value)
+	case op == "==":
+		return object.BooleanFromNative(object.Equal(left, right))
+	case op == "!=":
+		return object.BooleanFromNative(!object.Equal(left, right))
+	case left.Type() == object.StringObjectType && right.Type() == object.StringObjectType && op == "+":
+		return &object.String{Value: left.(*object.String).Value + right.(*object.String).Value}
+	case left.Type() == right.Type() && (op == "<" || op == ">") &&
+		(left.Type() == object.StringObjectType || left.Type() == object.ArrayObjectType):
+		c, ok := object.Compare(left, right)
+		if !ok {
+			return object.NewError("incomparable elements: %s %s %s", left.Type(), op, right.Type())
+		}
+		if op == "<" {
+			return object.BooleanFromNative(c < 0)
+		}
+		return object.BooleanFromNative(c > 0)
+	case left.Type() != right.Type():
+		return object.NewError("type mismatch: %s %s %s", left.Type(), op, right.Type())
+	default:
+		return object.NewError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+	}
+}
+
+func integerInfix(op string, left, right int64) object.Object {
+	switch op {
+	case "+":
+		return object.NewInteger(left + right)
+	case "-":
+		return object.NewInteger(left - right)
+	case "*":
+		return object.NewInteger(left * right)
+	case "/":
+		if right == 0 {
+			return object.NewError("division by zero")
+		}
+		return object.NewInteger(left / right)
+	case "==":
+		return object.BooleanFromNative(left == right)
+	case "!=":
+		return object.BooleanFromNative(left != right)
+	case "<":
+		return object.BooleanFromNative(left < right)
+	case ">":
+		return object.BooleanFromNative(left > right)
+	default:
+		return object.NewError("unknown operator: INTEGER %s INTEGER", op)
+	}
+}
+
+// Index returns left[index]. A string is indexed by byte.
+func Index(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ArrayObjectType && index.Type() == object.IntegerObjectType:
+		elems := left.(*object.Array).Elements
+		i := normalizeIndex(index.(*object.Integer).Value, len(elems))
+		if i < 0 || i >= int64(len(elems)) {
+			return object.Null
+		}
+		return elems[i]
+	case left.Type() == object.StringObjectType && index.Type() == object.IntegerObjectType:
+		s := left.(*object.String).Value
+		i := normalizeIndex(index.(*object.Integer).Value, len(s))
+		if i < 0 || i >= int64(len(s)) {
+			return object.Null
+		}
+		return &object.String{Value: s[i : i+1]}
+	case left.Type() == object.HashObjectType:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return object.NewError("unusable as hash key: %s", index.Type())
+		}
+		if value, ok := left.(*object.Hash).Get(key); ok {
+			return value
+		}
+		return object.Null
+	default:
+		return object.NewError("index operator not supported: %s", left.Type())
+	}
+}
+
+// Slice returns left[low:high], whose bounds are byte offsets if left is a
+// string. Null bounds mean the start and the end respectively.
+func Slice(left, low, high object.Object) object.Object {
+	var length int
+	switch left := left.(type) {
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.String:
+		length = len(left.Value)
+	default:
+		return object.NewError("slice operator not supported: %s", left.Type())
+	}
+
+	bound := func(obj object.Object, def int64) (int64, *object.Error) {
+		if obj == object.Null {
+			return def, nil
+		}
+		i, ok := obj.(*object.Integer)
+		if !ok {
+			return 0, object.NewError("slice index must be INTEGER: got %s", obj.Type())
+		}
+		v := normalizeIndex(i.Value, length)
+		if v < 0 {
+			v = 0
+		}
+		if v > int64(length) {
+			v = int64(length)
+		}
+		return v, nil
+	}
+	l, err := bound(low, 0)
+	if err != nil {
+		return err
+	}
+	h, err := bound(high, int64(length))
+	if err != nil {
+		return err
+	}
+	if l > h {
+		l = h
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		elems := make([]object.Object, h-l)
+		copy(elems, left.Elements[l:h])
+		return &object.Array{Elements: elems}
+	default:
+		return &object.String{Value: left.(*object.String).Value[l:h]}
+	}
+}
+
+func normalizeIndex(i int64, length int) int64 {
+	if i < 0 {
+		return i + int64(length)
+	}
+	return i
+}
+
+// Member returns the member named name of obj.
+func Member(obj object.Object, name string) object.Object {
+	s, ok := obj.(*object.Struct)
+	if !ok {
+		return object.NewError("cannot access member %s of %s", name, obj.Type())
+	}
+	member, ok := s.Member(name)
+	if !ok {
+		return object.NewError("%s has no member %s", s.StructType.Name, name)
+	}
+	return member
+}
+
+// Assign sets the field named name of obj to val and returns val.
+func Assign(obj object.Object, name string, val object.Object) object.Object {
+	s, ok := obj.(*object.Struct)
+	if !ok {
+		return object.NewError("cannot assign to member %s of %s", name, obj.Type())
+	}
+	if !s.SetField(name, val) {
+		return object.NewError("%s has no field %s", s.StructType.Name, name)
+	}
+	return val
+}
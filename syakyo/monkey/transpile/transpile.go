@@ -0,0 +1,722 @@
+// Package transpile translates Monkey programs into Go programs, which use
+// the rt package as their runtime, so that they can be compiled to native
+// code by `go build`.
+package transpile
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	"github.com/daichimukai/x/syakyo/monkey/ast"
+	"github.com/daichimukai/x/syakyo/monkey/eval"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+)
+
+// Program returns the source of a Go main package running program, which
+// is read from filename. The compiled program behaves the same as the one
+// run by `monkey run` with all the capabilities granted, and its Program
+// function returns the same value as the evaluator.
+//
+// An undefined variable, which the evaluator reports before the evaluation,
+// is reported by Program as an error.
+func Program(program *ast.Program, filename string) ([]byte, error) {
+	g := &generator{
+		builtins: map[string]bool{},
+		scope:    &scope{vars: map[string]*variable{}, consts: map[string]bool{}},
+		fn:       &function{},
+	}
+	for _, name := range eval.BuiltinNames() {
+		g.builtins[name] = true
+	}
+
+	g.printf("// Program is compiled from %s.", filename)
+	g.printf("func Program(r *rt.Runtime) object.Object {")
+	g.printf("return %s", g.statements(program.Statements))
+	g.printf("}")
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, header, filename, filename)
+	out.Write(g.decls.Bytes())
+	out.Write(g.body.Bytes())
+	return format.Source(out.Bytes())
+}
+
+const header = `// Code generated by monkey build from %s. DO NOT EDIT.
+
+package main
+
+import (
+	"os"
+
+	"github.com/daichimukai/x/syakyo/monkey/object"
+	"github.com/daichimukai/x/syakyo/monkey/transpile/rt"
+)
+
+func main() {
+	os.Exit(rt.Main(%q, Program))
+}
+
+`
+
+// generator writes the Go code of a program. A Monkey expression is
+// compiled into Go statements computing its value, followed by a Go
+// expression of the value; an error is returned from the enclosing Go
+// function as soon as it occurs, as the evaluator propagates it.
+//
+// A Monkey variable is a Go variable of object.Object declared at the
+// beginning of its scope, which is nil while the variable is not defined
+// yet. The whole program is a Go function and a Monkey function is a
+// closure in it, so that the variables are captured as in the evaluator.
+type generator struct {
+	decls bytes.Buffer // the package-level declarations
+	body  bytes.Buffer // the Program function
+
+	builtins map[string]bool
+	scope    *scope
+	fn       *function
+	temps    int // the number of the temporary Go variables
+	vars     int // the number of the Go variables of Monkey variables
+	patterns int
+	err      error // the first error found
+}
+
+// scope mirrors an environment created during the evaluation.
+type scope struct {
+	outer  *scope
+	vars   map[string]*variable
+	consts map[string]bool // the names bound by const statements so far
+}
+
+type variable struct {
+	name    string // the Go identifier
+	defined bool   // whether the variable is defined whenever it is referred to
+}
+
+// function is a Go function being generated.
+type function struct {
+	generator bool     // whether it runs the body of a generator
+	iterators []string // the iterators of the for statements being run
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.body, format, args...)
+	g.body.WriteByte('\n')
+}
+
+func (g *generator) errorf(node ast.Node, format string, args ...interface{}) {
+	if g.err == nil {
+		g.err = fmt.Errorf("%s: %s", node.Pos(), fmt.Sprintf(format, args...))
+	}
+}
+
+func (g *generator) temp() string {
+	g.temps++
+	return fmt.Sprintf("t%d", g.temps)
+}
+
+func (g *generator) openScope() {
+	g.scope = &scope{
+		outer:  g.scope,
+		vars:   map[string]*variable{},
+		consts: map[string]bool{},
+	}
+}
+
+func (g *generator) closeScope() {
+	g.scope = g.scope.outer
+}
+
+// declare declares the variable named name in the current scope unless it
+// is already declared.
+func (g *generator) declare(name string, defined bool) *variable {
+	if v, ok := g.scope.vars[name]; ok {
+		return v
+	}
+	g.vars++
+	v := &variable{name: fmt.Sprintf("%s_%d", name, g.vars), defined: defined}
+	g.scope.vars[name] = v
+	g.printf("var %s object.Object", v.name)
+	g.printf("_ = %s", v.name)
+	return v
+}
+
+// ret returns value from the current Go function, closing the iterators.
+func (g *generator) ret(value string) {
+	for i := len(g.fn.iterators) - 1; i >= 0; i-- {
+		g.printf("%s.Close()", g.fn.iterators[i])
+	}
+	g.printf("return %s", value)
+}
+
+// raise returns an error with msg.
+func (g *generator) raise(msg string) {
+	g.ret(fmt.Sprintf("&object.Error{Message: %q}", msg))
+}
+
+// call assigns the value of the Go expression formatted by format and
+// args, which may be an error, to a new temporary variable and returns it.
+func (g *generator) call(format string, args ...interface{}) string {
+	t := g.temp()
+	g.printf("%s := "+format, append([]interface{}{t}, args...)...)
+	g.printf("if rt.IsError(%s) {", t)
+	g.ret(t)
+	g.printf("}")
+	return t
+}
+
+// statements compiles stmts in the current scope and returns their value.
+func (g *generator) statements(stmts []ast.Statement) string {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			for _, name := range letNames(stmt) {
+				g.declare(name, false)
+			}
+		case *ast.StructStatement:
+			g.declare(stmt.Name.Value, false)
+		}
+	}
+
+	result := "nil"
+	for i, stmt := range stmts {
+		value := g.statement(stmt)
+		if i == len(stmts)-1 {
+			result = value
+		} else if value != "nil" {
+			g.printf("_ = %s", value)
+		}
+	}
+	return result
+}
+
+// letNames returns the names bound by stmt.
+func letNames(stmt *ast.LetStatement) []string {
+	if stmt.Pattern == nil {
+		return []string{stmt.Name.Value}
+	}
+	var names []string
+	ast.Inspect(stmt.Pattern, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok && ident.Value != "_" {
+			names = append(names, ident.Value)
+		}
+		return true
+	})
+	return names
+}
+
+func (g *generator) statement(stmt ast.Statement) string {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		return g.expr(stmt.Expression)
+	case *ast.LetStatement:
+		g.letStatement(stmt)
+	case *ast.ReturnStatement:
+		g.ret(g.expr(stmt.ReturnValue))
+	case *ast.StructStatement:
+		g.structStatement(stmt)
+	case *ast.ForStatement:
+		g.forStatement(stmt)
+	case *ast.BlockStatement:
+		return g.statements(stmt.Statements)
+	}
+	return "nil"
+}
+
+func (g *generator) letStatement(stmt *ast.LetStatement) {
+	names := letNames(stmt)
+	for _, name := range names {
+		if g.scope.consts[name] {
+			g.raise("cannot reassign constant " + name)
+			return
+		}
+	}
+
+	value := g.expr(stmt.Value)
+	if stmt.Pattern != nil {
+		g.destructure(stmt.Pattern, value)
+	} else {
+		g.printf("%s = %s", g.declare(stmt.Name.Value, false).name, value)
+	}
+
+	if stmt.Const {
+		for _, name := range names {
+			g.scope.consts[name] = true
+		}
+	}
+}
+
+func (g *generator) structStatement(stmt *ast.StructStatement) {
+	name := stmt.Name.Value
+	if g.scope.consts[name] {
+		g.raise("cannot reassign constant " + name)
+		return
+	}
+
+	var fields []string
+	for _, field := range stmt.Fields {
+		fields = append(fields, strconv.Quote(field.Value))
+	}
+	t := g.temp()
+	g.printf("%s := &object.StructType{Name: %q, Fields: []string{%s}, Methods: map[string]*object.Function{}}",
+		t, name, strings.Join(fields, ", "))
+	for _, method := range stmt.Methods {
+		g.printf("%s.Methods[%q] = %s", t, method.Name.Value, g.function(method, true))
+	}
+	g.printf("%s = %s", g.declare(name, false).name, t)
+}
+
+func (g *generator) forStatement(stmt *ast.ForStatement) {
+	iterable := g.expr(stmt.Iterable)
+	it, err := g.temp(), g.temp()
+	g.printf("%s, %s := rt.Iterate(%s)", it, err, iterable)
+	g.printf("if %s != nil {", err)
+	g.ret(err)
+	g.printf("}")
+
+	g.fn.iterators = append(g.fn.iterators, it)
+	val, ok := g.temp(), g.temp()
+	g.printf("for {")
+	g.printf("%s, %s := %s.Next()", val, ok, it)
+	g.printf("if !%s {", ok)
+	g.printf("break")
+	g.printf("}")
+	g.printf("if rt.IsError(%s) {", val)
+	g.ret(val)
+	g.printf("}")
+
+	// Each iteration has its own scope.
+	g.openScope()
+	g.destructure(stmt.Pattern, val)
+	if value := g.statements(stmt.Body.Statements); value != "nil" {
+		g.printf("_ = %s", value)
+	}
+	g.closeScope()
+	g.printf("}")
+	g.fn.iterators = g.fn.iterators[:len(g.fn.iterators)-1]
+	g.printf("%s.Close()", it)
+}
+
+func (g *generator) expr(expr ast.Expression) string {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return literal(expr)
+	case *ast.Null:
+		return "object.Null"
+	case *ast.Identifier:
+		return g.identifier(expr)
+	case *ast.InterpolatedString:
+		var parts []string
+		for _, part := range expr.Parts {
+			parts = append(parts, g.expr(part))
+		}
+		t := g.temp()
+		g.printf("%s := rt.Interpolate(%s)", t, strings.Join(parts, ", "))
+		return t
+	case *ast.PrefixExpression:
+		right := g.expr(expr.Right)
+		return g.call("rt.Prefix(%q, %s)", expr.Operator, right)
+	case *ast.InfixExpression:
+		left := g.expr(expr.Left)
+		right := g.expr(expr.Right)
+		return g.call("rt.Infix(%q, %s, %s)", expr.Operator, left, right)
+	case *ast.IfExpression:
+		return g.ifExpression(expr)
+	case *ast.MatchExpression:
+		return g.matchExpression(expr)
+	case *ast.FunctionLiteral:
+		return g.function(expr, false)
+	case *ast.CallExpression:
+		fn := g.expr(expr.Function)
+		args, spread := g.exprs(expr.Arguments)
+		if spread {
+			return g.call("rt.Call(%s, %s...)", fn, args)
+		}
+		if args == "" {
+			return g.call("rt.Call(%s)", fn)
+		}
+		return g.call("rt.Call(%s, %s)", fn, args)
+	case *ast.ArrayLiteral:
+		elems, spread := g.exprs(expr.Elements)
+		t := g.temp()
+		switch {
+		case spread:
+			g.printf("%s := &object.Array{Elements: %s}", t, elems)
+		case elems == "":
+			g.printf("%s := &object.Array{}", t)
+		default:
+			g.printf("%s := &object.Array{Elements: []object.Object{%s}}", t, elems)
+		}
+		return t
+	case *ast.HashLiteral:
+		t := g.temp()
+		g.printf("%s := object.NewHash()", t)
+		for _, pair := range expr.Pairs {
+			key := g.call("rt.Key(%s)", g.expr(pair.Key))
+			value := g.expr(pair.Value)
+			g.printf("%s.Set(%s.(object.Hashable), %s)", t, key, value)
+		}
+		return t
+	case *ast.IndexExpression:
+		left := g.expr(expr.Left)
+		index := g.expr(expr.Index)
+		return g.call("rt.Index(%s, %s)", left, index)
+	case *ast.SliceExpression:
+		left := g.expr(expr.Left)
+		low, high := "object.Null", "object.Null"
+		if expr.Low != nil {
+			low = g.expr(expr.Low)
+		}
+		if expr.High != nil {
+			high = g.expr(expr.High)
+		}
+		return g.call("rt.Slice(%s, %s, %s)", left, low, high)
+	case *ast.MemberExpression:
+		obj := g.expr(expr.Object)
+		return g.call("rt.Member(%s, %q)", obj, expr.Member.Value)
+	case *ast.AssignExpression:
+		target, ok := expr.Target.(*ast.MemberExpression)
+		if !ok {
+			g.raise("cannot assign to " + expr.Target.String())
+			return "nil"
+		}
+		obj := g.expr(target.Object)
+		value := g.expr(expr.Value)
+		return g.call("rt.Assign(%s, %q, %s)", obj, target.Member.Value, value)
+	case *ast.YieldExpression:
+		value := g.expr(expr.Value)
+		if !g.fn.generator {
+			g.raise("yield outside generator")
+			return "nil"
+		}
+		g.printf("if !yield(%s) {", value)
+		g.ret("rt.ErrGeneratorClosed")
+		g.printf("}")
+		return "object.Null"
+	case *ast.SpreadExpression:
+		g.raise("spread is not allowed here: " + expr.String())
+		return "nil"
+	default:
+		return "nil"
+	}
+}
+
+// literal returns the Go expression of the value of a literal, which is a
+// hashable object, or null in a pattern.
+func literal(expr ast.Expression) string {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return fmt.Sprintf("object.NewInteger(%d)", expr.Value)
+	case *ast.StringLiteral:
+		return fmt.Sprintf("&object.String{Value: %s}", strconv.Quote(expr.Value))
+	case *ast.Boolean:
+		if expr.Value {
+			return "object.True"
+		}
+		return "object.False"
+	case *ast.Null:
+		return "object.Null"
+	case *ast.PrefixExpression:
+		// a negated integer in a pattern
+		return fmt.Sprintf("object.NewInteger(-%d)", expr.Right.(*ast.IntegerLiteral).Value)
+	default:
+		return "nil"
+	}
+}
+
+// exprs compiles exprs, the arguments of a call or the elements of an
+// array, into a comma-separated list of Go expressions, or a Go slice if
+// some of them are spread.
+func (g *generator) exprs(exprs []ast.Expression) (string, bool) {
+	spread := false
+	for _, expr := range exprs {
+		if _, ok := expr.(*ast.SpreadExpression); ok {
+			spread = true
+		}
+	}
+
+	if !spread {
+		var values []string
+		for _, expr := range exprs {
+			values = append(values, g.expr(expr))
+		}
+		return strings.Join(values, ", "), false
+	}
+
+	t := g.temp()
+	g.printf("var %s []object.Object", t)
+	for _, expr := range exprs {
+		if s, ok := expr.(*ast.SpreadExpression); ok {
+			arr := g.call("rt.Spread(%s)", g.expr(s.Value))
+			g.printf("%s = append(%s, %s.(*object.Array).Elements...)", t, t, arr)
+			continue
+		}
+		g.printf("%s = append(%s, %s)", t, t, g.expr(expr))
+	}
+	return t, true
+}
+
+// identifier returns the value of the variable referred to by ident. The
+// variable is the one in the innermost scope which is defined at runtime,
+// or the builtin if none is.
+func (g *generator) identifier(ident *ast.Identifier) string {
+	var vars []string
+	for s := g.scope; s != nil; s = s.outer {
+		v, ok := s.vars[ident.Value]
+		if !ok {
+			continue
+		}
+		if v.defined && len(vars) == 0 {
+			return v.name
+		}
+		vars = append(vars, v.name)
+		if v.defined {
+			break
+		}
+	}
+
+	if len(vars) == 0 {
+		if !g.builtins[ident.Value] {
+			g.errorf(ident, "identifier not found: %s", ident.Value)
+			return "nil"
+		}
+		return fmt.Sprintf("r.Builtin(%q)", ident.Value)
+	}
+	return g.call("r.Lookup(%q, %s)", ident.Value, strings.Join(vars, ", "))
+}
+
+func (g *generator) ifExpression(expr *ast.IfExpression) string {
+	cond := g.expr(expr.Condition)
+	t := g.temp()
+	g.printf("var %s object.Object", t)
+	g.printf("if rt.IsTruthy(%s) {", cond)
+	g.block(expr.Consequence, t)
+	g.printf("} else {")
+	if expr.Alternative != nil {
+		g.block(expr.Alternative, t)
+	} else {
+		g.printf("%s = object.Null", t)
+	}
+	g.printf("}")
+	return t
+}
+
+// block compiles block in a new scope and assigns its value to t, which is
+// null if the block ends with a statement.
+func (g *generator) block(block *ast.BlockStatement, t string) {
+	g.openScope()
+	value := g.statements(block.Statements)
+	if value == "nil" {
+		value = "object.Null"
+	}
+	g.printf("%s = %s", t, value)
+	g.closeScope()
+}
+
+func (g *generator) matchExpression(expr *ast.MatchExpression) string {
+	subject, t, matched := g.temp(), g.temp(), g.temp()
+	g.printf("%s := %s", subject, g.expr(expr.Subject))
+	g.printf("var %s object.Object", t)
+	g.printf("%s := false", matched)
+
+	for _, arm := range expr.Arms {
+		g.printf("if !%s {", matched)
+		g.openScope()
+		err, bind := g.match(arm.Pattern, subject)
+		if err != "" {
+			g.printf("if %s == nil {", err)
+		}
+		bind()
+		if arm.Guard != nil {
+			g.printf("if rt.IsTruthy(%s) {", g.expr(arm.Guard))
+		}
+		g.printf("%s = %s", t, g.expr(arm.Value))
+		g.printf("%s = true", matched)
+		if arm.Guard != nil {
+			g.printf("}")
+		}
+		if err != "" {
+			g.printf("}")
+		}
+		g.closeScope()
+		g.printf("}")
+	}
+
+	g.printf("if !%s {", matched)
+	g.ret(fmt.Sprintf("object.NewError(\"no match arm matches %%s\", %s.Inspect())", subject))
+	g.printf("}")
+	return t
+}
+
+// destructure binds the identifiers in pattern to value, returning an
+// error if value does not match pattern.
+func (g *generator) destructure(pattern ast.Expression, value string) {
+	err, bind := g.match(pattern, value)
+	if err != "" {
+		g.printf("if %s != nil {", err)
+		g.ret(err)
+		g.printf("}")
+	}
+	bind()
+}
+
+// match matches value against pattern, declaring the variables bound by
+// pattern in the current scope. It returns the Go expression of the error,
+// which is nil if value matches, and the function to bind the variables
+// after the match. The error is empty if value always matches.
+func (g *generator) match(pattern ast.Expression, value string) (string, func()) {
+	if ident, ok := pattern.(*ast.Identifier); ok {
+		if ident.Value == "_" {
+			return "", func() {}
+		}
+		v := g.declare(ident.Value, true)
+		return "", func() { g.printf("%s = %s", v.name, value) }
+	}
+
+	var vars []*variable
+	p := g.pattern(pattern, &vars)
+	g.patterns++
+	name := fmt.Sprintf("pattern%d", g.patterns)
+	fmt.Fprintf(&g.decls, "var %s = %s\n\n", name, p)
+
+	bound, err := g.temp(), g.temp()
+	g.printf("var %s [%d]object.Object", bound, len(vars))
+	g.printf("%s := rt.Match(%s, %s, %s[:])", err, name, value, bound)
+	return err, func() {
+		for i, v := range vars {
+			g.printf("%s = %s[%d]", v.name, bound, i)
+		}
+	}
+}
+
+// pattern returns the Go expression of the rt.Pattern of pattern. The
+// variables bound by it are appended to vars.
+func (g *generator) pattern(pattern ast.Expression, vars *[]*variable) string {
+	bind := func(ident *ast.Identifier) int {
+		if ident == nil || ident.Value == "_" {
+			return -1
+		}
+		*vars = append(*vars, g.declare(ident.Value, true))
+		return len(*vars) - 1
+	}
+
+	text := strconv.Quote(pattern.String())
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		return fmt.Sprintf("&rt.Pattern{Kind: rt.PatternBind, Text: %s, Bind: %d}", text, bind(pattern))
+	case *ast.ArrayPattern:
+		var elems []string
+		for _, elem := range pattern.Elements {
+			elems = append(elems, g.pattern(elem, vars))
+		}
+		return fmt.Sprintf("&rt.Pattern{Kind: rt.PatternArray, Text: %s, Elements: []*rt.Pattern{%s}, Rest: %d}",
+			text, strings.Join(elems, ", "), bind(pattern.Rest))
+	case *ast.HashPattern:
+		var keys, values []string
+		for _, pair := range pattern.Pairs {
+			keys = append(keys, literal(pair.Key))
+			values = append(values, g.pattern(pair.Value, vars))
+		}
+		return fmt.Sprintf("&rt.Pattern{Kind: rt.PatternHash, Text: %s, Keys: []object.Hashable{%s}, Values: []*rt.Pattern{%s}, Rest: %d}",
+			text, strings.Join(keys, ", "), strings.Join(values, ", "), bind(pattern.Rest))
+	default:
+		return fmt.Sprintf("&rt.Pattern{Kind: rt.PatternLiteral, Text: %s, Literal: %s}", text, literal(pattern))
+	}
+}
+
+// function compiles lit into a native function, which takes the receiver
+// as the first argument if lit is a method of a struct.
+func (g *generator) function(lit *ast.FunctionLiteral, method bool) string {
+	outerScope, outerFn := g.scope, g.fn
+	defer func() {
+		g.scope, g.fn = outerScope, outerFn
+	}()
+
+	name := ""
+	var named *variable
+	if lit.Name != nil {
+		// A named function can refer to itself from its body.
+		name = lit.Name.Value
+		g.openScope()
+		named = g.declare(name, true)
+	}
+	source := (&object.Function{Parameters: lit.Parameters, Patterns: lit.Patterns, Body: lit.Body}).Inspect()
+
+	fn := g.temp()
+	g.printf("%s := &object.Function{Name: %q, Generator: %t, Source: %q, Native: func(args []object.Object) object.Object {",
+		fn, name, lit.Generator, source)
+	g.fn = &function{}
+	if method {
+		g.openScope()
+		g.printf("%s = args[0]", g.declare("self", false).name)
+		g.printf("args = args[1:]")
+	}
+	g.openScope()
+
+	min := len(lit.Parameters)
+	for i := range lit.Parameters {
+		if i < len(lit.Defaults) && lit.Defaults[i] != nil {
+			min = i
+			break
+		}
+	}
+	g.printf("if err := rt.CheckArity(len(args), %d, %d, %t); err != nil {", min, len(lit.Parameters), lit.Rest != nil)
+	g.printf("return err")
+	g.printf("}")
+
+	for i, param := range lit.Parameters {
+		arg := g.temp()
+		if i < len(lit.Defaults) && lit.Defaults[i] != nil {
+			// Default values are evaluated in the scope of the function
+			// so that they can refer to the preceding parameters.
+			g.printf("var %s object.Object", arg)
+			g.printf("if len(args) > %d {", i)
+			g.printf("%s = args[%d]", arg, i)
+			g.printf("} else {")
+			g.printf("%s = %s", arg, g.expr(lit.Defaults[i]))
+			g.printf("}")
+		} else {
+			g.printf("%s := args[%d]", arg, i)
+		}
+		if param == nil {
+			g.destructure(lit.Patterns[i], arg)
+			continue
+		}
+		g.printf("%s = %s", g.declare(param.Value, true).name, arg)
+	}
+	if lit.Rest != nil {
+		g.printf("%s = rt.Rest(args, %d)", g.declare(lit.Rest.Value, true).name, len(lit.Parameters))
+	}
+
+	if lit.Generator {
+		g.printf("return object.NewGenerator(func(yield func(object.Object) bool) object.Object {")
+		g.fn = &function{generator: true}
+	}
+	value := g.statements(lit.Body.Statements)
+	if value == "nil" && !lit.Generator {
+		// The body ends with a statement.
+		value = "object.Null"
+	}
+	g.printf("return %s", value)
+	if lit.Generator {
+		g.printf("})")
+	}
+	g.printf("}}")
+
+	switch {
+	case named != nil && method:
+		// The method referred to by its name is not bound to a receiver.
+		g.printf("%s = &object.Function{Name: %q, Generator: %t, Source: %q, Native: func(args []object.Object) object.Object {",
+			named.name, name, lit.Generator, source)
+		g.printf("return %s.Native(append([]object.Object{nil}, args...))", fn)
+		g.printf("}}")
+	case named != nil:
+		g.printf("%s = %s", named.name, fn)
+	}
+	return fn
+}
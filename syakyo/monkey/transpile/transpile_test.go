@@ -0,0 +1,166 @@
+package transpile_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/daichimukai/x/syakyo/monkey/eval"
+	"github.com/daichimukai/x/syakyo/monkey/lexer"
+	"github.com/daichimukai/x/syakyo/monkey/object"
+	"github.com/daichimukai/x/syakyo/monkey/parser"
+	"github.com/daichimukai/x/syakyo/monkey/transpile"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProgram compiles the programs to Go and checks that the compiled ones
+// print the same as the evaluator.
+func TestProgram(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building Go programs")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	testcases := []string{
+		// expressions
+		`puts(1 + 2 * 3, 7 / 2 - 10, -5, !true, !0, 1 < 2, 1 > 2, 1 == 1, 1 != 1)`,
+		`puts("foo" + "bar", "x=${1 + 1}, y=${[1, "a"]}", true == true, len("abc"))`,
+		`puts([1, [2]] == [1, [2]], {"a": 1} != {"a": 2}, null == null, 1 == "1", "a" < "b", [1, 2] < [1, 3], [1] > [])`,
+		`let f = fn() {}; puts(f == f, f == fn() {}, len == len); puts([1] < ["a"])`,
+		`puts([1, 2, 3][-1], [1, 2][5], "abc"[1], {"a": 1, 2: true}["a"], {"a": 1}[2])`,
+		`puts("aé"[1:], len("é"[0]), "é"[0] + "é"[1] == "é")`,
+		`let xs = [1, 2, 3, 4]; puts(xs[1:], xs[:-1], xs[5:], "hello"[1:3])`,
+		`puts(if (1 < 2) { "yes" } else { "no" }, if (false) { 1 }, if (0) { "zero" })`,
+		// functions
+		`let add = fn(a, b) { a + b }; puts(add(1, 2), add(...[3, 4]))`,
+		`let f = fn(a, b = a * 2, ...rest) { [a, b, rest] }; puts(f(1), f(1, 5), f(1, 2, 3, 4))`,
+		`let f = fn([a, b], {c}) { a + b + c }; puts(f([1, 2], {"c": 3}))`,
+		`let fact = fn(n) { if (n == 0) { return 1; }; n * fact(n - 1) }; puts(fact(10))`,
+		`let fib = fn f(n) { if (n < 2) { n } else { f(n - 1) + f(n - 2) } }; puts(fib(15))`,
+		`let make = fn(n) { fn(m) { n + m } }; let addTwo = make(2); puts(addTwo(3), make(10)(1))`,
+		`puts(map([1, 2, 3], fn(x) { x * x }), filter([1, 2, 3, 4], fn(x) { x / 2 * 2 == x }))`,
+		`puts(reduce([1, 2, 3], fn(acc, x) { acc + x }, 0), sort_by(["bb", "a", "ccc"], len))`,
+		`puts(map({"a": 1, "b": 2}, fn(k, v) { k + ":" + "${v}" }))`,
+		`let f = fn(x) { x }; puts(f)`,
+		`let f = fn() { let x = 1; }; puts(f(), if (true) {}, [fn() {}()])`,
+		// scopes
+		`let x = 1; let f = fn() { x }; let x = 2; puts(f())`,
+		`let x = 1; let f = fn() { let y = x; let x = 2; [x, y] }; puts(f())`,
+		`let x = 1; if (true) { let x = 2; puts(x) }; puts(x)`,
+		`let len = fn(x) { 42 }; puts(len("a"))`,
+		`let fs = map([1, 2, 3], fn(i) { fn() { i } }); puts(map(fs, fn(f) { f() }))`,
+		`let g = fn() { h() }; let h = fn() { "h" }; puts(g())`,
+		`const c = 1; let f = fn() { let c = 2; c }; puts(f(), c)`,
+		// patterns
+		`let [a, [b, c], ...rest] = [1, [2, 3], 4, 5]; puts(a, b, c, rest)`,
+		`let {name, "age": age, ...rest} = {"name": "x", "age": 3, "k": true}; puts(name, age, rest)`,
+		`let f = fn(x) { match (x) { 0 => "zero", -1 => "minus one", [a, b] => a + b, {k} if k > 1 => "big", {k} => "k", _ => "other" } }; puts(f(0), f(-1), f([1, 2]), f({"k": 2}), f({"k": 1}), f("s"))`,
+		`let f = fn(x) { match (x) { null => "null", [a, null] => a, _ => "other" } }; puts(f(null), f([1, null]), f(0))`,
+		`for ([k, v] in {"a": 1, "b": 2}) { puts(k, v) }`,
+		// structs
+		`struct P { x, y, fn norm() { self.x * self.x + self.y * self.y }, fn move(dx) { self.x = self.x + dx; self } }; let p = P(3, 4); puts(p.norm(), p.move(1), p.x, P)`,
+		`struct Counter { n, fn inc() { self.n = self.n + 1 } }; let c = Counter(0); each(range(3), fn(_) { c.inc() }); puts(c.n)`,
+		// generators and loops
+		`let nat = fn() { let i = 0; for (i in range(1000000)) { yield i } }; puts(collect(take(nat(), 5)))`,
+		`let g = fn(xs) { for (x in xs) { if (x > 2) { return null_; }; yield x * 10 } }; let null_ = 0; puts(collect(g([1, 2, 3, 4])))`,
+		`let f = fn() { for (x in range(10)) { if (x == 3) { return x; } }; -1 }; puts(f())`,
+		`let total = fn(n) { let s = 0; for (i in range(n)) { puts(i) }; s }; total(3)`,
+		// concurrency
+		`let ch = channel(3); let t = spawn(fn() { for (i in range(3)) { send(ch, i) }; close(ch) }); join(t); puts(collect(ch))`,
+		`let ts = map([1, 2, 3], fn(x) { spawn(fn(y) { y * y }, x) }); puts(join(...ts))`,
+		// builtins
+		`puts(json_stringify({"a": [1, true, "s"]}), json_parse("[1, 2]"), replace("a1b22", regex("[0-9]+"), fn(m) { "<" + m + ">" }))`,
+		// errors
+		`puts("before"); 1 + true; puts("after")`,
+		`let f = fn(x) { x }; f(1, 2)`,
+		`let [a, b] = [1];`,
+		`const c = 1; let c = 2;`,
+		`match (3) { 1 => 1 }`,
+		`let f = fn() { "a" - "b" }; puts(map([1], fn(x) { f() }))`,
+		`for (x in 1) { x }`,
+		`let g = fn() { yield 1; 1 + "a" }; puts(collect(g()))`,
+		`yield 1`,
+		`let f = fn() { g() }; f(); let g = fn() { 1 };`,
+		`struct P { x, fn m(n) { if (n == 0) { self } else { m(n - 1) } } }; puts(P(1).m(0)); P(1).m(1)`,
+		`1 + [...2]`,
+		`let z = 0; puts(7 / z)`,
+	}
+
+	dir := buildModule(t)
+	args := []string{"build", "-o", filepath.Join(dir, "bin") + string(filepath.Separator)}
+	for i, input := range testcases {
+		program, err := parser.New(lexer.New(input)).ParseProgram()
+		require.NoError(t, err, input)
+		code, err := transpile.Program(program, "test.mk")
+		require.NoError(t, err, input)
+
+		pkg := filepath.Join(dir, fmt.Sprintf("p%d", i))
+		require.NoError(t, os.Mkdir(pkg, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(pkg, "main.go"), code, 0o644))
+		args = append(args, fmt.Sprintf("./p%d", i))
+	}
+	cmd := exec.Command(gobin, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	for i, input := range testcases {
+		t.Run(input, func(t *testing.T) {
+			var expectOut, expectErr bytes.Buffer
+			program, err := parser.New(lexer.New(input)).ParseProgram()
+			require.NoError(t, err)
+			env := eval.NewEnvironment(eval.WithStdout(&expectOut), eval.WithCapabilities(eval.CapabilityAll))
+			expectCode := 0
+			if errObj, ok := env.Eval(program).(*object.Error); ok {
+				fmt.Fprintf(&expectErr, "test.mk: %s\n", errObj.Inspect())
+				expectCode = 1
+			}
+
+			var stdout, stderr bytes.Buffer
+			cmd := exec.Command(filepath.Join(dir, "bin", fmt.Sprintf("p%d", i)))
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+			code := 0
+			if err := cmd.Run(); err != nil {
+				var exitErr *exec.ExitError
+				require.True(t, errors.As(err, &exitErr), err)
+				code = exitErr.ExitCode()
+			}
+			require.Equal(t, expectOut.String(), stdout.String())
+			require.Equal(t, expectErr.String(), stderr.String())
+			require.Equal(t, expectCode, code)
+		})
+	}
+}
+
+// buildModule returns a temporary directory containing a module which
+// requires this one by a replace directive, in which the compiled programs
+// are built outside of the source tree.
+func buildModule(t *testing.T) string {
+	t.Helper()
+
+	root, err := filepath.Abs("..")
+	require.NoError(t, err)
+	dir := t.TempDir()
+	gomod := fmt.Sprintf("module monkeytest\n\ngo 1.18\n\nrequire %[1]s v0.0.0\n\nreplace %[1]s => %[2]s\n", modulePath, root)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o644))
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.sum"), sum, 0o644))
+	return dir
+}
+
+const modulePath = "github.com/daichimukai/x/syakyo/monkey"
+
+func TestProgramUndefinedVariable(t *testing.T) {
+	program, err := parser.New(lexer.New("let f = fn() {\n  x + 1\n};")).ParseProgram()
+	require.NoError(t, err)
+	_, err = transpile.Program(program, "test.mk")
+	require.EqualError(t, err, "2:3: identifier not found: x")
+}